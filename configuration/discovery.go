@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/hasura/ndc-prometheus/connector/metadata"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"golang.org/x/sync/errgroup"
+)
+
+var (
+	counterMetricSuffixes   = []string{"_total", "_count", "_sum"}
+	histogramMetricSuffixes = []string{"_bucket", "_count", "_sum"}
+)
+
+// MetricTypePattern the compiled setting to type discovered metrics.
+type MetricTypePattern struct {
+	Regex *regexp.Regexp
+	Type  model.MetricType
+}
+
+// discoveredMetric the metric that is discovered from series names or recording rules.
+type discoveredMetric struct {
+	Name        string
+	Type        model.MetricType
+	Description *string
+}
+
+// discoverMetrics introspects metrics that don't have metadata,
+// such as outputs of recording rules and remote-written series.
+func (uc *updateCommand) discoverMetrics(ctx context.Context) error {
+	settings := uc.Config.Generator.Metrics.Discovery
+	if !settings.Series && !settings.RecordingRules {
+		return nil
+	}
+
+	names := []string{}
+
+	if settings.Series {
+		values, err := uc.getLabelValues(ctx, "", model.MetricNameLabel, 0)
+		if err != nil {
+			return fmt.Errorf("failed to fetch metric names: %w", err)
+		}
+
+		names = append(names, values...)
+	}
+
+	rules := map[string]string{}
+
+	if settings.RecordingRules {
+		var err error
+
+		rules, err = uc.getRecordingRules(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to fetch recording rules: %w", err)
+		}
+
+		for name := range rules {
+			names = append(names, name)
+		}
+	}
+
+	var eg errgroup.Group
+
+	eg.SetLimit(uc.coroutines)
+
+	for _, metric := range uc.buildDiscoveredMetrics(names, rules) {
+		eg.Go(func() error {
+			slog.Info(metric.Name, slog.String("type", string(metric.Type)))
+
+			labels, err := uc.getAllLabelsOfMetric(
+				ctx,
+				metric.Name,
+				v1.Metadata{Type: v1.MetricType(metric.Type)},
+			)
+			if err != nil {
+				return fmt.Errorf(
+					"error when fetching labels for metric `%s`: %w",
+					metric.Name,
+					err,
+				)
+			}
+
+			uc.SetMetadataMetric(metric.Name, metadata.MetricInfo{
+				Type:        metric.Type,
+				Description: metric.Description,
+				Labels:      labels,
+			})
+
+			return nil
+		})
+	}
+
+	return eg.Wait()
+}
+
+// getRecordingRules returns names and expressions of recording rules.
+func (uc *updateCommand) getRecordingRules(ctx context.Context) (map[string]string, error) {
+	result, err := uc.Client.Rules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := map[string]string{}
+
+	for _, group := range result.Groups {
+		for _, rule := range group.Rules {
+			recordingRule, ok := rule.(v1.RecordingRule)
+			if !ok {
+				continue
+			}
+
+			// the same metric may be recorded by many rules with different labels.
+			if _, ok := rules[recordingRule.Name]; !ok {
+				rules[recordingRule.Name] = recordingRule.Query
+			}
+		}
+	}
+
+	return rules, nil
+}
+
+// buildDiscoveredMetrics filters metric names that aren't introspected
+// and evaluates their types by settings or naming conventions.
+func (uc *updateCommand) buildDiscoveredMetrics(
+	names []string,
+	rules map[string]string,
+) []discoveredMetric {
+	slices.Sort(names)
+	names = slices.Compact(names)
+
+	nameSet := make(map[string]bool, len(names))
+	for _, name := range names {
+		nameSet[name] = true
+	}
+
+	results := []discoveredMetric{}
+	visited := map[string]bool{}
+
+	for _, name := range names {
+		metricName, metricType := uc.evalDiscoveredMetricType(name, nameSet)
+		if visited[metricName] || uc.MetricExists(metricName) ||
+			(len(uc.Include) > 0 && !validateRegularExpressions(uc.Include, metricName)) ||
+			validateRegularExpressions(uc.Exclude, metricName) {
+			continue
+		}
+
+		visited[metricName] = true
+		metric := discoveredMetric{
+			Name: metricName,
+			Type: metricType,
+		}
+
+		if query, ok := rules[metricName]; ok {
+			metric.Description = &query
+		}
+
+		results = append(results, metric)
+	}
+
+	return results
+}
+
+// evalDiscoveredMetricType returns the name and type of the metric.
+// Bucket, sum and count series are merged into a histogram if the bucket series exists.
+func (uc *updateCommand) evalDiscoveredMetricType(
+	name string,
+	names map[string]bool,
+) (string, model.MetricType) {
+	for _, mt := range uc.DiscoveryTypes {
+		if mt.Regex.MatchString(name) {
+			return name, mt.Type
+		}
+	}
+
+	for _, suffix := range histogramMetricSuffixes {
+		baseName, ok := strings.CutSuffix(name, suffix)
+		if ok && names[baseName+"_bucket"] && names[baseName+"_count"] {
+			return baseName, model.MetricTypeHistogram
+		}
+	}
+
+	switch {
+	case strings.HasSuffix(name, "_info"):
+		return name, model.MetricTypeInfo
+	case slices.ContainsFunc(counterMetricSuffixes, func(suffix string) bool {
+		return strings.HasSuffix(name, suffix)
+	}):
+		return name, model.MetricTypeCounter
+	default:
+		return name, model.MetricTypeGauge
+	}
+}
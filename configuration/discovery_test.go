@@ -0,0 +1,54 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hasura/ndc-sdk-go/utils"
+	"github.com/prometheus/common/model"
+	"gotest.tools/v3/assert"
+)
+
+func TestBuildDiscoveredMetrics(t *testing.T) {
+	uc := &updateCommand{
+		Exclude: []*regexp.Regexp{regexp.MustCompile("^ALERTS")},
+		DiscoveryTypes: []MetricTypePattern{
+			{Regex: regexp.MustCompile("_state$"), Type: model.MetricTypeStateset},
+		},
+		existedMetrics: map[string]any{
+			"go_goroutines": true,
+		},
+	}
+
+	results := uc.buildDiscoveredMetrics(
+		[]string{
+			"go_goroutines",
+			"http_request_duration_seconds_bucket",
+			"http_request_duration_seconds_count",
+			"http_request_duration_seconds_sum",
+			"rpc_calls_total",
+			"rpc_calls_total",
+			"build_info",
+			"ALERTS",
+			"node_systemd_unit_state",
+			"job:http_requests:rate5m",
+		},
+		map[string]string{
+			"job:http_requests:rate5m": "sum by (job) (rate(http_requests_total[5m]))",
+		},
+	)
+
+	expected := []discoveredMetric{
+		{Name: "build_info", Type: model.MetricTypeInfo},
+		{Name: "http_request_duration_seconds", Type: model.MetricTypeHistogram},
+		{
+			Name:        "job:http_requests:rate5m",
+			Type:        model.MetricTypeGauge,
+			Description: utils.ToPtr("sum by (job) (rate(http_requests_total[5m]))"),
+		},
+		{Name: "node_systemd_unit_state", Type: model.MetricTypeStateset},
+		{Name: "rpc_calls_total", Type: model.MetricTypeCounter},
+	}
+
+	assert.DeepEqual(t, expected, results)
+}
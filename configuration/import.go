@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/hasura/ndc-prometheus/connector/metadata"
+)
+
+// importNativeQueries reads the configuration of the directory, imports native queries with the callback
+// and writes the configuration file. The default configuration is used if the file doesn't exist.
+func importNativeQueries(dir string, importFiles func(cmd *updateCommand) error) error {
+	originalConfig, err := metadata.ReadConfiguration(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+
+		originalConfig = &defaultConfiguration
+	}
+
+	if originalConfig.Metadata.NativeOperations.Queries == nil {
+		originalConfig.Metadata.NativeOperations.Queries = map[string]metadata.NativeQuery{}
+	}
+
+	cmd := &updateCommand{
+		Config:    originalConfig,
+		OutputDir: dir,
+	}
+
+	if err := importFiles(cmd); err != nil {
+		return err
+	}
+
+	if err := cmd.writeConfigFile(); err != nil {
+		return fmt.Errorf("failed to write the configuration file: %w", err)
+	}
+
+	return nil
+}
+
+// findImportFiles expands glob patterns of imported files of the kind, e.g. rule or dashboard.
+func findImportFiles(kind string, patterns []string) ([]string, error) {
+	var results []string
+
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s file pattern `%s`: %w", kind, pattern, err)
+		}
+
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no %s file matches `%s`", kind, pattern)
+		}
+
+		for _, match := range matches {
+			if !slices.Contains(results, match) {
+				results = append(results, match)
+			}
+		}
+	}
+
+	return results, nil
+}
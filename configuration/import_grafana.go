@@ -0,0 +1,518 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hasura/ndc-prometheus/connector/metadata"
+	"github.com/hasura/ndc-sdk-go/utils"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	grafanaDatasourcePrometheus = "prometheus"
+	grafanaAllValue             = "$__all"
+)
+
+var (
+	// legacy [[var]] and ${var:format} syntaxes of Grafana template variables.
+	grafanaBracketVariableRegex = regexp.MustCompile(`\[\[(\w+)(?::\w+)?\]\]`)
+	grafanaFormatVariableRegex  = regexp.MustCompile(`\$\{(\w+):\w+\}`)
+	grafanaNameInvalidCharRegex = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// ImportGrafanaArguments represent input arguments of the `import-grafana` command.
+type ImportGrafanaArguments struct {
+	Dir       string   `default:"." env:"HASURA_PLUGIN_CONNECTOR_CONTEXT_PATH" help:"The directory where the configuration.yaml file is present" short:"d"`
+	Files     []string `                                                       help:"Paths or glob patterns of Grafana dashboard JSON files"               arg:""`
+	Overwrite bool     `                                                       help:"Overwrite existing native queries of the same names"`
+}
+
+type grafanaDashboard struct {
+	Title  string         `json:"title"`
+	Panels []grafanaPanel `json:"panels"`
+	// panels of legacy dashboards are grouped in rows.
+	Rows []struct {
+		Panels []grafanaPanel `json:"panels"`
+	} `json:"rows"`
+	Templating struct {
+		List []grafanaVariable `json:"list"`
+	} `json:"templating"`
+}
+
+// AllPanels returns panels of the dashboard, including panels of rows.
+func (gd grafanaDashboard) AllPanels() []grafanaPanel {
+	var results []grafanaPanel
+
+	var walk func(panels []grafanaPanel)
+
+	walk = func(panels []grafanaPanel) {
+		for _, panel := range panels {
+			results = append(results, panel)
+			walk(panel.Panels)
+		}
+	}
+
+	walk(gd.Panels)
+
+	for _, row := range gd.Rows {
+		walk(row.Panels)
+	}
+
+	return results
+}
+
+type grafanaPanel struct {
+	ID           int                `json:"id"`
+	Type         string             `json:"type"`
+	Title        string             `json:"title"`
+	Description  string             `json:"description"`
+	Datasource   *grafanaDatasource `json:"datasource"`
+	Targets      []grafanaTarget    `json:"targets"`
+	LibraryPanel map[string]any     `json:"libraryPanel"`
+	// panels of collapsed rows.
+	Panels []grafanaPanel `json:"panels"`
+}
+
+type grafanaTarget struct {
+	RefID      string             `json:"refId"`
+	Expr       string             `json:"expr"`
+	Hide       bool               `json:"hide"`
+	Datasource *grafanaDatasource `json:"datasource"`
+}
+
+// grafanaDatasource is the datasource reference of panels and targets.
+// Legacy dashboards reference datasources by names whose types are unknown.
+type grafanaDatasource struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (gd *grafanaDatasource) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err == nil {
+		gd.UID = name
+
+		return nil
+	}
+
+	type plain grafanaDatasource
+
+	return json.Unmarshal(b, (*plain)(gd))
+}
+
+type grafanaVariable struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Label       string `json:"label"`
+	Description string `json:"description"`
+	Multi       bool   `json:"multi"`
+	IncludeAll  bool   `json:"includeAll"`
+	// the query string, or the query object of the datasource.
+	Query   any `json:"query"`
+	Current struct {
+		Value any `json:"value"`
+	} `json:"current"`
+	Options []struct {
+		Value any `json:"value"`
+	} `json:"options"`
+}
+
+// GrafanaImportIssue represents a panel target that can't be converted to a native query.
+type GrafanaImportIssue struct {
+	File    string
+	Panel   string
+	PanelID int
+	RefID   string
+	Reason  string
+}
+
+// importGrafana generates native queries from Prometheus targets of Grafana dashboard panels.
+// Dashboards are read offline, neither Grafana nor Prometheus servers are requested.
+func importGrafana(ctx context.Context, args *ImportGrafanaArguments) error {
+	start := time.Now()
+
+	slog.Info(
+		"importing grafana dashboards",
+		slog.String("dir", args.Dir),
+		slog.Any("files", args.Files),
+	)
+
+	filePaths, err := findImportFiles("dashboard", args.Files)
+	if err != nil {
+		return err
+	}
+
+	var issues []GrafanaImportIssue
+
+	var imported int
+
+	err = importNativeQueries(args.Dir, func(cmd *updateCommand) error {
+		for _, filePath := range filePaths {
+			fileIssues, err := cmd.importGrafanaDashboardFile(ctx, filePath, args.Overwrite)
+			if err != nil {
+				return err
+			}
+
+			issues = append(issues, fileIssues...)
+		}
+
+		imported = len(cmd.importedTargets)
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, issue := range issues {
+		slog.Warn(
+			"failed to convert the panel",
+			slog.String("file", issue.File),
+			slog.String("panel", issue.Panel),
+			slog.Int("panel_id", issue.PanelID),
+			slog.String("ref_id", issue.RefID),
+			slog.String("reason", issue.Reason),
+		)
+	}
+
+	slog.Info(
+		"imported grafana dashboards",
+		slog.Int("imported", imported),
+		slog.Int("failed", len(issues)),
+		slog.String("exec_time", time.Since(start).Round(time.Millisecond).String()),
+	)
+
+	return nil
+}
+
+// importGrafanaDashboardFile parses the dashboard file and adds native queries of Prometheus panel targets.
+// Returns targets that can't be converted.
+func (uc *updateCommand) importGrafanaDashboardFile(
+	ctx context.Context,
+	filePath string,
+	overwrite bool,
+) ([]GrafanaImportIssue, error) {
+	rawBytes, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// dashboards that are exported from the HTTP API are wrapped in the dashboard field.
+	var wrapper struct {
+		Dashboard *grafanaDashboard `json:"dashboard"`
+	}
+
+	if err := json.Unmarshal(rawBytes, &wrapper); err != nil {
+		return nil, fmt.Errorf("failed to parse the dashboard file %s: %w", filePath, err)
+	}
+
+	dashboard := wrapper.Dashboard
+	if dashboard == nil {
+		dashboard = &grafanaDashboard{}
+
+		if err := json.Unmarshal(rawBytes, dashboard); err != nil {
+			return nil, fmt.Errorf("failed to parse the dashboard file %s: %w", filePath, err)
+		}
+	}
+
+	if uc.importedTargets == nil {
+		uc.importedTargets = map[string]bool{}
+	}
+
+	variables := make(map[string]grafanaVariable)
+
+	for _, variable := range dashboard.Templating.List {
+		variables[variable.Name] = variable
+	}
+
+	var issues []GrafanaImportIssue
+
+	for _, panel := range dashboard.AllPanels() {
+		newIssue := func(refID string, reason string) GrafanaImportIssue {
+			return GrafanaImportIssue{
+				File:    filePath,
+				Panel:   panel.Title,
+				PanelID: panel.ID,
+				RefID:   refID,
+				Reason:  reason,
+			}
+		}
+
+		if len(panel.Targets) == 0 {
+			if panel.LibraryPanel != nil {
+				issues = append(issues, newIssue("", "library panels aren't supported"))
+			}
+
+			continue
+		}
+
+		for _, target := range panel.Targets {
+			name := createGrafanaCollectionName(panel, target)
+			// panels of the same title are distinguished by their IDs.
+			if uc.importedTargets[name] {
+				name = fmt.Sprintf("%s_%d", name, panel.ID)
+			}
+
+			nativeQuery, reason := createGrafanaNativeQuery(panel, target, variables)
+			if reason != "" {
+				issues = append(issues, newIssue(target.RefID, reason))
+
+				continue
+			}
+
+			if _, ok := uc.Config.Metadata.Metrics[name]; ok {
+				issues = append(
+					issues,
+					newIssue(
+						target.RefID,
+						fmt.Sprintf("the name %s exists in the metrics collection", name),
+					),
+				)
+
+				continue
+			}
+
+			existing, exists := uc.Config.Metadata.NativeOperations.Queries[name]
+			if uc.importedTargets[name] || (exists && !overwrite) {
+				issues = append(
+					issues,
+					newIssue(target.RefID, fmt.Sprintf("the native query name %s exists", name)),
+				)
+
+				continue
+			}
+
+			// labels of the configuration have user-supplied descriptions and types.
+			nativeQuery.Labels = existing.Labels
+
+			nativeQuery, err := uc.validateNativeQuery(ctx, name, nativeQuery)
+			if err != nil {
+				issues = append(issues, newIssue(target.RefID, err.Error()))
+
+				continue
+			}
+
+			uc.Config.Metadata.NativeOperations.Queries[name] = nativeQuery
+			uc.importedTargets[name] = true
+		}
+	}
+
+	return issues, nil
+}
+
+// createGrafanaNativeQuery creates the native query of the panel target.
+// Returns the reason if the target can't be converted.
+func createGrafanaNativeQuery(
+	panel grafanaPanel,
+	target grafanaTarget,
+	variables map[string]grafanaVariable,
+) (metadata.NativeQuery, string) {
+	datasource := target.Datasource
+	if datasource == nil || datasource.Type == "" {
+		datasource = panel.Datasource
+	}
+
+	if datasource != nil && datasource.Type != "" &&
+		datasource.Type != grafanaDatasourcePrometheus {
+		return metadata.NativeQuery{}, fmt.Sprintf(
+			"the datasource type %s isn't Prometheus",
+			datasource.Type,
+		)
+	}
+
+	if target.Hide {
+		return metadata.NativeQuery{}, "the target is hidden"
+	}
+
+	query := strings.TrimSpace(target.Expr)
+	if query == "" {
+		return metadata.NativeQuery{}, "the target doesn't have a PromQL expression"
+	}
+
+	query = grafanaBracketVariableRegex.ReplaceAllString(query, "$${$1}")
+	query = grafanaFormatVariableRegex.ReplaceAllString(query, "$${$1}")
+
+	nativeQuery := metadata.NativeQuery{
+		Query:     query,
+		Arguments: map[string]metadata.NativeQueryArgumentInfo{},
+	}
+
+	for _, match := range nativeQueryVariableRegex.FindAllStringSubmatch(query, -1) {
+		name := match[1]
+		if metadata.IsBuiltinVariable(name) {
+			continue
+		}
+
+		if strings.HasPrefix(name, "__") {
+			return metadata.NativeQuery{}, fmt.Sprintf(
+				"the built-in variable $%s isn't supported",
+				name,
+			)
+		}
+
+		variable, ok := variables[name]
+		if !ok {
+			continue
+		}
+
+		argument, ok := createGrafanaArgument(variable)
+		if !ok {
+			return metadata.NativeQuery{}, fmt.Sprintf(
+				"the %s variable $%s isn't supported",
+				variable.Type,
+				name,
+			)
+		}
+
+		nativeQuery.Arguments[name] = argument
+	}
+
+	description := strings.TrimSpace(panel.Title)
+	if panelDescription := strings.TrimSpace(panel.Description); panelDescription != "" {
+		description = strings.TrimSpace(description + "\n" + panelDescription)
+	}
+
+	if description != "" {
+		nativeQuery.Description = utils.ToPtr(description)
+	}
+
+	return nativeQuery, ""
+}
+
+// createGrafanaArgument creates the native query argument of the Grafana template variable.
+// Types are inferred from variable types and values. Returns false if the variable type isn't supported.
+func createGrafanaArgument(variable grafanaVariable) (metadata.NativeQueryArgumentInfo, bool) {
+	argument := metadata.NativeQueryArgumentInfo{
+		Type: string(metadata.ScalarString),
+	}
+
+	if variable.Description != "" {
+		argument.Description = utils.ToPtr(variable.Description)
+	} else if variable.Label != "" {
+		argument.Description = utils.ToPtr(variable.Label)
+	}
+
+	switch variable.Type {
+	case "interval":
+		argument.Type = string(metadata.ScalarDuration)
+
+		if value, ok := variable.Current.Value.(string); ok {
+			if _, err := model.ParseDuration(value); err == nil {
+				argument.Default = value
+			}
+		}
+	case "custom", "query":
+		argument.Array = variable.Multi || variable.IncludeAll
+
+		if variable.Type == "custom" {
+			for _, option := range variable.Options {
+				if value, ok := option.Value.(string); ok && value != grafanaAllValue &&
+					!slices.Contains(argument.Enum, value) {
+					argument.Enum = append(argument.Enum, value)
+				}
+			}
+		}
+
+		argument.Default = getGrafanaVariableDefault(variable, argument.Enum)
+	case "constant", "textbox":
+		value, ok := variable.Query.(string)
+		if !ok || value == "" {
+			value, _ = variable.Current.Value.(string)
+		}
+
+		if value != "" {
+			argument.Type, argument.Default = inferGrafanaValueType(value)
+		}
+	default:
+		return argument, false
+	}
+
+	return argument, true
+}
+
+// getGrafanaVariableDefault gets the current value of the variable as the default value of the argument.
+// The "All" value and values that aren't in the enum are ignored.
+func getGrafanaVariableDefault(variable grafanaVariable, enum []string) any {
+	isValid := func(value string) bool {
+		return value != "" && value != grafanaAllValue &&
+			(len(enum) == 0 || slices.Contains(enum, value))
+	}
+
+	switch value := variable.Current.Value.(type) {
+	case string:
+		if !isValid(value) {
+			return nil
+		}
+
+		if variable.Multi || variable.IncludeAll {
+			return []any{value}
+		}
+
+		return value
+	case []any:
+		var results []any
+
+		for _, item := range value {
+			str, ok := item.(string)
+			if !ok || !isValid(str) {
+				return nil
+			}
+
+			results = append(results, str)
+		}
+
+		if len(results) == 0 {
+			return nil
+		}
+
+		return results
+	default:
+		return nil
+	}
+}
+
+// inferGrafanaValueType infers the argument type of the constant value.
+func inferGrafanaValueType(value string) (string, any) {
+	if number, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return string(metadata.ScalarInt64), number
+	}
+
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		return string(metadata.ScalarFloat64), number
+	}
+
+	if _, err := model.ParseDuration(value); err == nil {
+		return string(metadata.ScalarDuration), value
+	}
+
+	return string(metadata.ScalarString), value
+}
+
+// createGrafanaCollectionName creates the collection name from the panel title.
+// The reference ID of the target is appended if the panel has many targets.
+func createGrafanaCollectionName(panel grafanaPanel, target grafanaTarget) string {
+	name := strings.Trim(
+		grafanaNameInvalidCharRegex.ReplaceAllString(strings.ToLower(panel.Title), "_"),
+		"_",
+	)
+
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = strings.TrimSuffix(fmt.Sprintf("panel_%d_%s", panel.ID, name), "_")
+	}
+
+	if len(panel.Targets) > 1 {
+		name = fmt.Sprintf("%s_%s", name, strings.ToLower(target.RefID))
+	}
+
+	return name
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hasura/ndc-prometheus/connector/metadata"
+	"github.com/hasura/ndc-sdk-go/utils"
+	"gotest.tools/v3/assert"
+)
+
+const testGrafanaDashboard = `{
+  "dashboard": {
+    "title": "Services",
+    "templating": {
+      "list": [
+        { "name": "datasource", "type": "datasource", "query": "prometheus" },
+        {
+          "name": "job",
+          "label": "Job",
+          "type": "query",
+          "multi": true,
+          "includeAll": true,
+          "current": { "value": ["$__all"] }
+        },
+        {
+          "name": "code",
+          "type": "custom",
+          "options": [{ "value": "2.." }, { "value": "5.." }],
+          "current": { "value": "5.." }
+        },
+        { "name": "window", "type": "interval", "current": { "value": "5m" } },
+        { "name": "threshold", "type": "constant", "query": "0.5" }
+      ]
+    },
+    "panels": [
+      {
+        "id": 1,
+        "type": "timeseries",
+        "title": "Request Rate",
+        "description": "Requests per second by job",
+        "datasource": { "type": "prometheus", "uid": "${datasource}" },
+        "targets": [
+          {
+            "refId": "A",
+            "expr": "sum by (job) (rate(http_requests_total{job=~\"$job\", code=~\"${code:regex}\"}[$__rate_interval]))"
+          }
+        ]
+      },
+      {
+        "id": 2,
+        "type": "row",
+        "title": "Details",
+        "collapsed": true,
+        "panels": [
+          {
+            "id": 3,
+            "type": "stat",
+            "title": "Error Ratio",
+            "targets": [
+              {
+                "refId": "A",
+                "expr": "sum(rate(http_requests_total{code=~\"5..\"}[[[window]]])) / sum(rate(http_requests_total[[[window]]])) > $threshold"
+              },
+              { "refId": "B", "expr": "up == 0", "hide": true }
+            ]
+          },
+          {
+            "id": 4,
+            "type": "logs",
+            "title": "Logs",
+            "datasource": { "type": "loki", "uid": "loki" },
+            "targets": [{ "refId": "A", "expr": "{job=\"api\"}" }]
+          }
+        ]
+      },
+      {
+        "id": 5,
+        "type": "timeseries",
+        "title": "Request Rate",
+        "targets": [{ "refId": "A", "expr": "sum(rate(http_requests_total[$__interval_ms]))" }]
+      },
+      {
+        "id": 6,
+        "type": "timeseries",
+        "title": "Request Rate",
+        "targets": [{ "refId": "A", "expr": "sum(rate(http_requests_total[5m]))" }]
+      },
+      { "id": 7, "title": "Shared", "libraryPanel": { "uid": "shared" } }
+    ]
+  }
+}`
+
+func TestImportGrafanaDashboard(t *testing.T) {
+	dir := t.TempDir()
+	dashboardFile := filepath.Join(dir, "dashboard.json")
+	assert.NilError(t, os.WriteFile(dashboardFile, []byte(testGrafanaDashboard), 0o644))
+
+	uc := &updateCommand{
+		Config: &metadata.Configuration{
+			Metadata: metadata.Metadata{
+				NativeOperations: metadata.NativeOperations{
+					Queries: map[string]metadata.NativeQuery{},
+				},
+			},
+		},
+	}
+
+	issues, err := uc.importGrafanaDashboardFile(context.TODO(), dashboardFile, false)
+	assert.NilError(t, err)
+
+	queries := uc.Config.Metadata.NativeOperations.Queries
+	assert.DeepEqual(
+		t,
+		[]string{"error_ratio_a", "request_rate", "request_rate_6"},
+		utils.GetSortedKeys(queries),
+	)
+
+	requestRate := queries["request_rate"]
+	assert.Equal(
+		t,
+		`sum by (job) (rate(http_requests_total{job=~"${job}", code=~"${code}"}[$__rate_interval]))`,
+		requestRate.Query,
+	)
+	assert.Equal(t, "Request Rate\nRequests per second by job", *requestRate.Description)
+	assert.DeepEqual(t, metadata.NativeQueryArgumentInfo{
+		Description: utils.ToPtr("Job"),
+		Type:        string(metadata.ScalarString),
+		Array:       true,
+	}, requestRate.Arguments["job"])
+	assert.DeepEqual(t, metadata.NativeQueryArgumentInfo{
+		Type:    string(metadata.ScalarString),
+		Enum:    []string{"2..", "5.."},
+		Default: "5..",
+	}, requestRate.Arguments["code"])
+	assert.DeepEqual(t, []string{"job"}, utils.GetSortedKeys(requestRate.Labels))
+
+	errorRatio := queries["error_ratio_a"]
+	assert.Equal(
+		t,
+		`sum(rate(http_requests_total{code=~"5.."}[${window}])) / sum(rate(http_requests_total[${window}])) > ${threshold}`,
+		errorRatio.Query,
+	)
+	assert.DeepEqual(t, metadata.NativeQueryArgumentInfo{
+		Type:    string(metadata.ScalarDuration),
+		Default: "5m",
+	}, errorRatio.Arguments["window"])
+	assert.DeepEqual(t, metadata.NativeQueryArgumentInfo{
+		Type:    string(metadata.ScalarFloat64),
+		Default: 0.5,
+	}, errorRatio.Arguments["threshold"])
+
+	reasons := map[int]string{}
+	for _, issue := range issues {
+		reasons[issue.PanelID] = issue.Reason
+	}
+
+	assert.DeepEqual(t, map[int]string{
+		3: "the target is hidden",
+		4: "the datasource type loki isn't Prometheus",
+		5: "the built-in variable $__interval_ms isn't supported",
+		7: "library panels aren't supported",
+	}, reasons)
+}
+
+func TestCreateGrafanaCollectionName(t *testing.T) {
+	testCases := []struct {
+		Panel    grafanaPanel
+		Expected string
+	}{
+		{
+			Panel:    grafanaPanel{ID: 1, Title: "CPU Usage (%)"},
+			Expected: "cpu_usage",
+		},
+		{
+			Panel:    grafanaPanel{ID: 2, Title: "5xx errors"},
+			Expected: "panel_2_5xx_errors",
+		},
+		{
+			Panel:    grafanaPanel{ID: 3},
+			Expected: "panel_3",
+		},
+		{
+			Panel: grafanaPanel{
+				ID:      4,
+				Title:   "Latency",
+				Targets: []grafanaTarget{{RefID: "A"}, {RefID: "B"}},
+			},
+			Expected: "latency_a",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Expected, func(t *testing.T) {
+			assert.Equal(
+				t,
+				tc.Expected,
+				createGrafanaCollectionName(tc.Panel, grafanaTarget{RefID: "A"}),
+			)
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hasura/ndc-prometheus/connector/metadata"
+	"github.com/hasura/ndc-sdk-go/utils"
+	"github.com/prometheus/prometheus/model/rulefmt"
+)
+
+var (
+	ruleTemplateActionRegex = regexp.MustCompile(`\{\{-?\s*(.*?)\s*-?\}\}`)
+	ruleTemplateLabelRegex  = regexp.MustCompile(`\$labels\.([a-zA-Z_]\w*)`)
+	ruleTemplateValueRegex  = regexp.MustCompile(`\$value\b`)
+)
+
+// ImportRulesArguments represent input arguments of the `import-rules` command.
+type ImportRulesArguments struct {
+	Dir       string   `default:"." env:"HASURA_PLUGIN_CONNECTOR_CONTEXT_PATH" help:"The directory where the configuration.yaml file is present" short:"d"`
+	Files     []string `                                                       help:"Paths or glob patterns of Prometheus rule files"                      arg:""`
+	Overwrite bool     `                                                       help:"Overwrite existing native queries of the same names"`
+}
+
+// importRules generates native queries from recording and alerting rules of Prometheus rule files.
+// Rule files are read offline, the Prometheus server isn't requested.
+func importRules(ctx context.Context, args *ImportRulesArguments) error {
+	start := time.Now()
+
+	slog.Info("importing rules", slog.String("dir", args.Dir), slog.Any("files", args.Files))
+
+	filePaths, err := findRuleFiles(args.Files)
+	if err != nil {
+		return err
+	}
+
+	err = importNativeQueries(args.Dir, func(cmd *updateCommand) error {
+		for _, filePath := range filePaths {
+			if err := cmd.importRuleFile(ctx, filePath, args.Overwrite); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.Info(
+		"imported rules successfully",
+		slog.String("exec_time", time.Since(start).Round(time.Millisecond).String()),
+	)
+
+	return nil
+}
+
+// findRuleFiles expands glob patterns of rule files.
+func findRuleFiles(patterns []string) ([]string, error) {
+	return findImportFiles("rule", patterns)
+}
+
+// importRuleFile parses the rule file and adds native queries of its rules.
+func (uc *updateCommand) importRuleFile(
+	ctx context.Context,
+	filePath string,
+	overwrite bool,
+) error {
+	groups, errs := rulefmt.ParseFile(filePath, false)
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to parse the rule file %s: %w", filePath, errors.Join(errs...))
+	}
+
+	uc.importRuleGroups(ctx, filePath, groups.Groups, overwrite)
+
+	return nil
+}
+
+// importRuleGroups validates and adds native queries of rules. Rules are skipped with warnings
+// if their expressions are invalid, or their names are used by metrics, existing native queries or previous rules.
+func (uc *updateCommand) importRuleGroups(
+	ctx context.Context,
+	filePath string,
+	groups []rulefmt.RuleGroup,
+	overwrite bool,
+) {
+	queries := uc.Config.Metadata.NativeOperations.Queries
+
+	if uc.importedRules == nil {
+		uc.importedRules = map[string]bool{}
+	}
+
+	for _, group := range groups {
+		for _, rule := range group.Rules {
+			name := rule.Record
+			if name == "" {
+				name = rule.Alert
+			}
+
+			logger := slog.With(
+				slog.String("file", filePath),
+				slog.String("group", group.Name),
+				slog.String("rule", name),
+			)
+
+			if _, ok := uc.Config.Metadata.Metrics[name]; ok {
+				logger.Warn("skipped the rule, the name exists in the metrics collection")
+
+				continue
+			}
+
+			existing, exists := queries[name]
+			if uc.importedRules[name] || (exists && !overwrite) {
+				logger.Warn("skipped the rule, the native query name exists")
+
+				continue
+			}
+
+			nativeQuery, err := createRuleNativeQuery(group.Name, rule)
+			if err != nil {
+				logger.Warn("skipped the invalid rule", slog.String("error", err.Error()))
+
+				continue
+			}
+
+			// labels of the configuration have user-supplied descriptions and types.
+			for key, label := range existing.Labels {
+				nativeQuery.Labels[key] = label
+			}
+
+			nativeQuery, err = uc.validateNativeQuery(ctx, name, nativeQuery)
+			if err != nil {
+				logger.Warn("skipped the invalid rule", slog.String("error", err.Error()))
+
+				continue
+			}
+
+			queries[name] = nativeQuery
+			uc.importedRules[name] = true
+		}
+	}
+}
+
+// createRuleNativeQuery creates the native query of a recording or alerting rule.
+// Labels that are referenced by $labels templates of annotations are also labels of the result.
+func createRuleNativeQuery(groupName string, rule rulefmt.Rule) (metadata.NativeQuery, error) {
+	nativeQuery := metadata.NativeQuery{
+		Query:     strings.TrimSpace(rule.Expr),
+		Labels:    map[string]metadata.LabelInfo{},
+		Arguments: map[string]metadata.NativeQueryArgumentInfo{},
+	}
+
+	if err := nativeQuery.Validate(); err != nil {
+		return nativeQuery, err
+	}
+
+	description := fmt.Sprintf("The expression of the recording rule in the group %s", groupName)
+
+	if rule.Alert != "" {
+		description = fmt.Sprintf("The expression of the alerting rule in the group %s", groupName)
+
+		var annotations []string
+
+		for _, key := range []string{"summary", "description"} {
+			annotation := strings.TrimSpace(rule.Annotations[key])
+			if annotation == "" {
+				continue
+			}
+
+			text, labelNames := renderRuleAnnotation(annotation)
+			annotations = append(annotations, text)
+
+			for _, labelName := range labelNames {
+				nativeQuery.Labels[labelName] = metadata.LabelInfo{}
+			}
+		}
+
+		if len(annotations) > 0 {
+			description = strings.Join(annotations, "\n")
+		}
+	}
+
+	nativeQuery.Description = utils.ToPtr(description)
+
+	return nativeQuery, nil
+}
+
+// renderRuleAnnotation replaces template actions of the annotation with placeholders,
+// e.g. {{ $labels.instance }} with <instance> and {{ $value }} with <value>.
+// Returns the text and names of referenced labels.
+func renderRuleAnnotation(annotation string) (string, []string) {
+	var labelNames []string
+
+	text := ruleTemplateActionRegex.ReplaceAllStringFunc(annotation, func(action string) string {
+		if match := ruleTemplateLabelRegex.FindStringSubmatch(action); len(match) > 1 {
+			if !slices.Contains(labelNames, match[1]) {
+				labelNames = append(labelNames, match[1])
+			}
+
+			return "<" + match[1] + ">"
+		}
+
+		if ruleTemplateValueRegex.MatchString(action) {
+			return "<value>"
+		}
+
+		return action
+	})
+
+	return text, labelNames
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hasura/ndc-prometheus/connector/metadata"
+	"github.com/hasura/ndc-sdk-go/utils"
+	"gotest.tools/v3/assert"
+)
+
+const testRuleFile = `groups:
+  - name: api
+    rules:
+      - record: job:http_requests:rate5m
+        expr: sum by (job) (rate(http_requests_total[5m]))
+      - alert: HighErrorRate
+        expr: sum by (job, instance) (rate(http_requests_total{code=~"5.."}[5m])) > 1
+        for: 10m
+        labels:
+          severity: page
+        annotations:
+          summary: "High error rate on {{ $labels.instance }}"
+          description: "{{ $labels.job }} returns {{ $value | humanize }} errors per second"
+      - alert: TargetDown
+        expr: up == 0
+      - alert: HighErrorRate
+        expr: sum by (job) (rate(http_requests_total{code=~"5.."}[5m])) > 10
+  - name: node
+    rules:
+      - record: up
+        expr: up
+`
+
+func TestImportRules(t *testing.T) {
+	dir := t.TempDir()
+	ruleFile := filepath.Join(dir, "rules.yaml")
+	assert.NilError(t, os.WriteFile(ruleFile, []byte(testRuleFile), 0o644))
+
+	uc := &updateCommand{
+		OutputDir: dir,
+		Config: &metadata.Configuration{
+			Metadata: metadata.Metadata{
+				Metrics: map[string]metadata.MetricInfo{
+					"up": {},
+				},
+				NativeOperations: metadata.NativeOperations{
+					Queries: map[string]metadata.NativeQuery{
+						"TargetDown": {
+							Query: "up == 0",
+							Labels: map[string]metadata.LabelInfo{
+								"job": {Description: utils.ToPtr("The job name")},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	filePaths, err := findRuleFiles([]string{filepath.Join(dir, "*.yaml")})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{ruleFile}, filePaths)
+
+	_, err = findRuleFiles([]string{filepath.Join(dir, "*.yml")})
+	assert.ErrorContains(t, err, "no rule file matches")
+
+	assert.NilError(t, uc.importRuleFile(context.TODO(), ruleFile, true))
+
+	queries := uc.Config.Metadata.NativeOperations.Queries
+	assert.Equal(t, 3, len(queries))
+
+	recording := queries["job:http_requests:rate5m"]
+	assert.Equal(t, "sum by (job) (rate(http_requests_total[5m]))", recording.Query)
+	assert.Equal(t, "The expression of the recording rule in the group api", *recording.Description)
+	assert.DeepEqual(t, []string{"job"}, utils.GetSortedKeys(recording.Labels))
+
+	alert := queries["HighErrorRate"]
+	assert.Equal(
+		t,
+		`sum by (job, instance) (rate(http_requests_total{code=~"5.."}[5m])) > 1`,
+		alert.Query,
+	)
+	assert.Equal(
+		t,
+		"High error rate on <instance>\n<job> returns <value> errors per second",
+		*alert.Description,
+	)
+	assert.DeepEqual(t, []string{"instance", "job"}, utils.GetSortedKeys(alert.Labels))
+
+	targetDown := queries["TargetDown"]
+	assert.Equal(t, "The expression of the alerting rule in the group api", *targetDown.Description)
+	assert.Equal(t, "The job name", *targetDown.Labels["job"].Description)
+}
+
+func TestImportRulesWithoutOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	ruleFile := filepath.Join(dir, "rules.yaml")
+	assert.NilError(t, os.WriteFile(ruleFile, []byte(testRuleFile), 0o644))
+
+	uc := &updateCommand{
+		Config: &metadata.Configuration{
+			Metadata: metadata.Metadata{
+				NativeOperations: metadata.NativeOperations{
+					Queries: map[string]metadata.NativeQuery{
+						"TargetDown": {Query: "up == 0"},
+					},
+				},
+			},
+		},
+	}
+
+	assert.NilError(t, uc.importRuleFile(context.TODO(), ruleFile, false))
+
+	queries := uc.Config.Metadata.NativeOperations.Queries
+	assert.Equal(t, 4, len(queries))
+	assert.Assert(t, queries["TargetDown"].Description == nil)
+	assert.Equal(t, "up", queries["up"].Query)
+
+	assert.ErrorContains(
+		t,
+		uc.importRuleFile(context.TODO(), filepath.Join(dir, "not_found.yaml"), false),
+		"failed to parse the rule file",
+	)
+}
@@ -14,9 +14,11 @@ import (
 )
 
 var cli struct {
-	LogLevel string          `default:"info" enum:"debug,info,warn,error,DEBUG,INFO,WARN,ERROR"          env:"HASURA_PLUGIN_LOG_LEVEL" help:"Log level."`
-	Update   UpdateArguments `cmd:""         help:"Introspect metric metadata and update configuration."`
-	Version  struct{}        `cmd:""         help:"Print the CLI version."`
+	LogLevel      string                 `default:"info" enum:"debug,info,warn,error,DEBUG,INFO,WARN,ERROR"          env:"HASURA_PLUGIN_LOG_LEVEL" help:"Log level."`
+	Update        UpdateArguments        `cmd:"" help:"Introspect metric metadata and update configuration."`
+	ImportRules   ImportRulesArguments   `cmd:"" help:"Generate native queries from Prometheus recording and alerting rule files."`
+	ImportGrafana ImportGrafanaArguments `cmd:"" help:"Generate native queries from Prometheus targets of Grafana dashboard files."`
+	Version       struct{}               `cmd:"" help:"Print the CLI version."`
 }
 
 func main() {
@@ -38,6 +40,20 @@ func main() {
 			logger.Error(fmt.Sprintf("failed to update configuration: %s", err))
 			stop()
 
+			os.Exit(1)
+		}
+	case "import-rules <files>":
+		if err := importRules(ctx, &cli.ImportRules); err != nil {
+			logger.Error(fmt.Sprintf("failed to import rules: %s", err))
+			stop()
+
+			os.Exit(1)
+		}
+	case "import-grafana <files>":
+		if err := importGrafana(ctx, &cli.ImportGrafana); err != nil {
+			logger.Error(fmt.Sprintf("failed to import grafana dashboards: %s", err))
+			stop()
+
 			os.Exit(1)
 		}
 	case "version":
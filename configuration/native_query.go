@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/hasura/ndc-prometheus/connector/metadata"
+	"github.com/prometheus/common/model"
+)
+
+// validateNativeJoinQuery finds arguments of all expressions of the native query.
+// Labels of joined results are join labels.
+func (uc *updateCommand) validateNativeJoinQuery(
+	nq *metadata.NativeQuery,
+	fragments map[string]metadata.NativeQueryFragment,
+) error {
+	if err := nq.ExpandFragments(fragments); err != nil {
+		return err
+	}
+
+	arguments := map[string]metadata.NativeQueryArgumentInfo{}
+	expressionQueries := nq.ExpressionQueries()
+
+	for name, expression := range expressionQueries {
+		args, err := uc.findNativeQueryVariables(expression)
+		if err != nil {
+			return fmt.Errorf(
+				"expression `%s`: %w; query: %s",
+				name,
+				err,
+				expression.ExpandedQuery(),
+			)
+		}
+
+		maps.Copy(arguments, args)
+	}
+
+	expressions := make(map[string]string, len(nq.Expressions))
+
+	for name, expression := range nq.Expressions {
+		// format and replace $<name> to ${<name>}
+		query, err := uc.formatNativeQueryVariables(expression, arguments)
+		if err != nil {
+			return err
+		}
+
+		expressions[name] = query
+	}
+
+	nq.Arguments = arguments
+	nq.Expressions = expressions
+
+	if err := nq.ExpandFragments(fragments); err != nil {
+		return err
+	}
+
+	if err := nq.Validate(); err != nil {
+		return err
+	}
+
+	labels := make(map[string]metadata.LabelInfo, len(nq.JoinLabels))
+
+	for _, key := range nq.JoinLabels {
+		labels[key] = nq.Labels[key]
+	}
+
+	nq.Labels = labels
+
+	return nil
+}
+
+// discoverNativeQueryLabels runs the native query with example arguments and merges label names of the result
+// with labels that are inferred from the PromQL expression. The query isn't run if inferred labels are exact.
+// Existing labels of the configuration are kept.
+func (uc *updateCommand) discoverNativeQueryLabels(
+	ctx context.Context,
+	name string,
+	nq metadata.NativeQuery,
+) map[string]metadata.LabelInfo {
+	labelSet, err := nq.InferLabels()
+	if err != nil {
+		slog.Warn(
+			"failed to infer labels of the native query",
+			slog.String("name", name),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	labelNames := labelSet.Labels
+
+	if uc.Client != nil && !labelSet.Exact {
+		resultLabels, err := uc.queryNativeQueryLabels(ctx, nq)
+		if err != nil {
+			slog.Warn(
+				"failed to discover labels of the native query",
+				slog.String("name", name),
+				slog.String("error", err.Error()),
+			)
+		}
+
+		labelNames = append(labelNames, resultLabels...)
+	}
+
+	result := make(map[string]metadata.LabelInfo)
+
+	for _, labelName := range labelNames {
+		if !slices.Contains(bannedLabels, labelName) &&
+			!slices.Contains(labelSet.Removed, labelName) {
+			result[labelName] = metadata.LabelInfo{}
+		}
+	}
+
+	// labels of the configuration have user-supplied descriptions and types.
+	for key, label := range nq.Labels {
+		result[key] = label
+	}
+
+	return result
+}
+
+// queryNativeQueryLabels runs the native query with example arguments and returns label names of the result.
+func (uc *updateCommand) queryNativeQueryLabels(
+	ctx context.Context,
+	nq metadata.NativeQuery,
+) ([]string, error) {
+	query, ok, err := renderNativeQueryExample(nq)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	slog.Debug("discovering labels of the native query", slog.String("query", query))
+
+	vector, _, err := uc.Client.Query(ctx, query, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []string
+
+	for _, sample := range vector {
+		for labelName := range sample.Metric {
+			if labelName != model.MetricNameLabel && !slices.Contains(results, string(labelName)) {
+				results = append(results, string(labelName))
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// renderNativeQueryExample renders the native query with example or default values of arguments.
+// Regex label matchers, durations and numbers have sample values if the argument has neither of them.
+// Returns false if other required arguments don't have example values.
+func renderNativeQueryExample(nq metadata.NativeQuery) (string, bool, error) {
+	template, err := nq.Template()
+	if err != nil {
+		return "", false, err
+	}
+
+	var missingExample bool
+
+	query, err := template.Render(
+		func(variable metadata.NativeQueryVariable) (string, bool, error) {
+			arg := nq.Arguments[variable.Name]
+
+			value := arg.Example
+			if value == nil {
+				value = arg.Default
+			}
+
+			if value != nil {
+				return formatNativeQueryExample(variable, value)
+			}
+
+			switch {
+			case arg.Nullable:
+				return "", false, nil
+			case variable.Context == metadata.VariableContextDuration:
+				return "5m", true, nil
+			case variable.Context == metadata.VariableContextNumber:
+				return "1", true, nil
+			case variable.Context == metadata.VariableContextLabelValue && variable.IsRegex():
+				return ".*", true, nil
+			default:
+				missingExample = true
+
+				return "", true, nil
+			}
+		},
+	)
+	if err != nil {
+		return "", false, err
+	}
+
+	return query, !missingExample, nil
+}
+
+// formatNativeQueryExample formats the example value in the context of the variable.
+func formatNativeQueryExample(
+	variable metadata.NativeQueryVariable,
+	value any,
+) (string, bool, error) {
+	values, isArray := value.([]any)
+	if !isArray {
+		values = []any{value}
+	}
+
+	if len(values) == 0 {
+		return "", false, nil
+	}
+
+	items := make([]string, len(values))
+
+	for i, item := range values {
+		items[i] = fmt.Sprint(item)
+
+		if isArray && variable.IsRegex() {
+			items[i] = regexp.QuoteMeta(items[i])
+		}
+	}
+
+	switch variable.Context {
+	case metadata.VariableContextLabelList:
+		return strings.Join(items, ", "), true, nil
+	case metadata.VariableContextString, metadata.VariableContextLabelValue:
+		result, err := metadata.EscapePromQLString(strings.Join(items, "|"), variable.Quote)
+
+		return result, err == nil, err
+	default:
+		return items[0], true, nil
+	}
+}
@@ -32,6 +32,10 @@ var (
 		model.MetricTypeGauge,
 		model.MetricTypeHistogram,
 		model.MetricTypeGaugeHistogram,
+		model.MetricTypeSummary,
+		model.MetricTypeInfo,
+		model.MetricTypeStateset,
+		model.MetricTypeUnknown,
 	}
 )
 
@@ -53,10 +57,16 @@ type updateCommand struct {
 	Include       []*regexp.Regexp
 	Exclude       []*regexp.Regexp
 	ExcludeLabels []ExcludeLabels
+	// Types of discovered metrics by regular expression matching of metric names
+	DiscoveryTypes []MetricTypePattern
 
 	coroutines      int
-	apiFormatExists bool
 	existedMetrics  map[string]any
+	previousMetrics map[string]metadata.MetricInfo
+	// names of native queries that are imported from rule files.
+	importedRules map[string]bool
+	// names of native queries that are imported from targets of Grafana panels.
+	importedTargets map[string]bool
 	lock            sync.Mutex
 }
 
@@ -166,6 +176,18 @@ func (uc *updateCommand) introspectMetrics(
 		})
 	}
 
+	for _, mt := range originalConfig.Generator.Metrics.Discovery.Types {
+		rg, err := regexp.Compile(mt.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid discovery type pattern `%s`: %w", mt.Pattern, err)
+		}
+
+		uc.DiscoveryTypes = append(uc.DiscoveryTypes, MetricTypePattern{
+			Regex: rg,
+			Type:  mt.Type,
+		})
+	}
+
 	return uc.updateMetricsMetadata(ctx)
 }
 
@@ -192,6 +214,7 @@ func (uc *updateCommand) updateMetricsMetadata(ctx context.Context) error {
 		}
 	}
 
+	uc.previousMetrics = uc.Config.Metadata.Metrics
 	uc.Config.Metadata.Metrics = make(map[string]metadata.MetricInfo)
 
 	var eg errgroup.Group
@@ -214,6 +237,10 @@ func (uc *updateCommand) updateMetricsMetadata(ctx context.Context) error {
 		return err
 	}
 
+	if err := uc.discoverMetrics(ctx); err != nil {
+		return err
+	}
+
 	// merge existing metrics
 	for key, metric := range existingMetrics {
 		if _, ok := uc.existedMetrics[key]; ok {
@@ -223,9 +250,92 @@ func (uc *updateCommand) updateMetricsMetadata(ctx context.Context) error {
 		uc.Config.Metadata.Metrics[key] = metric
 	}
 
+	uc.updateMetricScrapeIntervals(ctx)
+
 	return nil
 }
 
+// updateMetricScrapeIntervals discovers scrape intervals of targets that expose metrics.
+// The interval is set if it's different from the runtime scrape interval.
+// Intervals that are set in the configuration are kept. Discovered intervals are refreshed,
+// or kept if the discovery fails or targets aren't found.
+func (uc *updateCommand) updateMetricScrapeIntervals(ctx context.Context) {
+	metricIntervals, err := uc.discoverMetricScrapeIntervals(ctx)
+	if err != nil {
+		slog.Warn(
+			"failed to discover scrape intervals of metrics",
+			slog.String("error", err.Error()),
+		)
+	}
+
+	runtimeInterval := uc.Config.Runtime.GetScrapeInterval()
+
+	for key, metric := range uc.Config.Metadata.Metrics {
+		if previous, ok := uc.previousMetrics[key]; ok && metric.ScrapeInterval == nil {
+			metric.ScrapeInterval = previous.ScrapeInterval
+			metric.ScrapeIntervalDiscovered = previous.ScrapeIntervalDiscovered
+		}
+
+		interval, ok := metricIntervals[key]
+
+		switch {
+		case !ok || (metric.ScrapeInterval != nil && !metric.ScrapeIntervalDiscovered):
+		case interval == runtimeInterval:
+			metric.ScrapeInterval = nil
+			metric.ScrapeIntervalDiscovered = false
+		default:
+			metric.ScrapeInterval = utils.ToPtr(model.Duration(interval))
+			metric.ScrapeIntervalDiscovered = true
+		}
+
+		uc.Config.Metadata.Metrics[key] = metric
+	}
+}
+
+// discoverMetricScrapeIntervals gets the maximum scrape interval of targets that expose each metric.
+func (uc *updateCommand) discoverMetricScrapeIntervals(
+	ctx context.Context,
+) (map[string]time.Duration, error) {
+	targets, err := uc.Client.TargetScrapeIntervals(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scrape intervals of targets: %w", err)
+	}
+
+	targetsMetadata, err := uc.Client.TargetsMetadata(ctx, "", "", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata of targets: %w", err)
+	}
+
+	// targets of metadata are identified by job and instance labels.
+	targetIntervals := make(map[string]time.Duration, len(targets))
+
+	for _, target := range targets {
+		key := createTargetKey(
+			string(target.Labels[model.JobLabel]),
+			string(target.Labels[model.InstanceLabel]),
+		)
+		targetIntervals[key] = target.ScrapeInterval
+	}
+
+	metricIntervals := map[string]time.Duration{}
+
+	for _, meta := range targetsMetadata {
+		interval, ok := targetIntervals[createTargetKey(
+			meta.Target[model.JobLabel],
+			meta.Target[model.InstanceLabel],
+		)]
+		if ok {
+			metricIntervals[meta.Metric] = max(metricIntervals[meta.Metric], interval)
+		}
+	}
+
+	return metricIntervals, nil
+}
+
+func createTargetKey(job string, instance string) string {
+	return job + string(model.SeparatorByte) + instance
+}
+
 func (uc *updateCommand) introspectMetric(
 	ctx context.Context,
 	key string,
@@ -242,8 +352,14 @@ func (uc *updateCommand) introspectMetric(
 			continue
 		}
 
-		if uc.MetricExists(key) {
-			slog.Warn(fmt.Sprintf("metric %s exists", key))
+		name := key
+		// OpenMetrics info series are exposed with the _info suffix.
+		if metricType == model.MetricTypeInfo && !strings.HasSuffix(name, "_info") {
+			name += "_info"
+		}
+
+		if uc.MetricExists(name) {
+			slog.Warn(fmt.Sprintf("metric %s exists", name))
 		}
 
 		switch metricType {
@@ -251,17 +367,21 @@ func (uc *updateCommand) introspectMetric(
 			for _, suffix := range []string{"sum", "bucket", "count"} {
 				uc.SetMetricExists(fmt.Sprintf("%s_%s", key, suffix))
 			}
+		case model.MetricTypeSummary:
+			for _, suffix := range []string{"sum", "count"} {
+				uc.SetMetricExists(fmt.Sprintf("%s_%s", key, suffix))
+			}
 		default:
 		}
 
-		slog.Info(key, slog.String("type", string(info.Type)))
+		slog.Info(name, slog.String("type", string(info.Type)))
 
-		labels, err := uc.getAllLabelsOfMetric(ctx, key, info)
+		labels, err := uc.getAllLabelsOfMetric(ctx, name, info)
 		if err != nil {
-			return fmt.Errorf("error when fetching labels for metric `%s`: %w", key, err)
+			return fmt.Errorf("error when fetching labels for metric `%s`: %w", name, err)
 		}
 
-		uc.SetMetadataMetric(key, metadata.MetricInfo{
+		uc.SetMetadataMetric(name, metadata.MetricInfo{
 			Type:        model.MetricType(info.Type),
 			Description: &info.Help,
 			Labels:      labels,
@@ -280,8 +400,10 @@ func (uc *updateCommand) getAllLabelsOfMetric(
 ) (map[string]metadata.LabelInfo, error) {
 	metricName := name
 
-	if metric.Type == v1.MetricTypeHistogram || metric.Type == v1.MetricTypeGaugeHistogram {
+	switch metric.Type {
+	case v1.MetricTypeHistogram, v1.MetricTypeGaugeHistogram, v1.MetricTypeSummary:
 		metricName += "_count"
+	default:
 	}
 
 	labels, warnings, err := uc.Client.LabelNames(
@@ -316,24 +438,172 @@ func (uc *updateCommand) getAllLabelsOfMetric(
 		}
 	}
 
+	// keep label settings of the previous configuration, e.g. types of labels
+	previousLabels := uc.previousMetrics[name].Labels
+
 	for _, key := range labels {
 		if slices.Contains(excludedLabels, key) {
 			continue
 		}
 
-		results[key] = metadata.LabelInfo{}
+		labelInfo := previousLabels[key]
+
+		// the state of a stateset series is the label of the same name as the metric.
+		if metric.Type == v1.MetricTypeStateset && key == name {
+			labelInfo, err = uc.introspectStateLabel(ctx, metricName, key, labelInfo)
+		} else {
+			labelInfo, err = uc.introspectLabelValues(ctx, metricName, key, labelInfo)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error when fetching values of label `%s`: %w", key, err)
+		}
+
+		results[key] = labelInfo
 	}
 
 	return results, nil
 }
 
+// introspectLabelValues fetches values of the label and records them as an enum
+// if the number of values doesn't exceed the threshold.
+func (uc *updateCommand) introspectLabelValues(
+	ctx context.Context,
+	metricName string,
+	name string,
+	label metadata.LabelInfo,
+) (metadata.LabelInfo, error) {
+	maxValues := uc.Config.Generator.Metrics.EnumLabelMaxValues
+	if maxValues <= 0 || (label.Type != "" && label.Type != metadata.LabelTypeEnum) {
+		return label, nil
+	}
+
+	values, err := uc.getLabelValues(ctx, metricName, name, uint64(maxValues)+1)
+	if err != nil {
+		return label, err
+	}
+
+	result, exceeded := buildEnumLabelInfo(label, values, maxValues)
+	if exceeded && label.Type == metadata.LabelTypeEnum && !label.Introspected {
+		slog.Warn(
+			"the number of values of the enum label exceeds the maximum, the configured values are kept",
+			slog.String("metric", metricName),
+			slog.String("label", name),
+			slog.Int("enum_label_max_values", maxValues),
+		)
+	}
+
+	return result, nil
+}
+
+// introspectStateLabel records all states of the stateset metric as an enum.
+func (uc *updateCommand) introspectStateLabel(
+	ctx context.Context,
+	metricName string,
+	name string,
+	label metadata.LabelInfo,
+) (metadata.LabelInfo, error) {
+	if label.Type != "" && label.Type != metadata.LabelTypeEnum {
+		return label, nil
+	}
+
+	values, err := uc.getLabelValues(ctx, metricName, name, 0)
+	if err != nil {
+		return label, err
+	}
+
+	result, _ := buildEnumLabelInfo(label, values, math.MaxInt)
+
+	return result, nil
+}
+
+func (uc *updateCommand) getLabelValues(
+	ctx context.Context,
+	metricName string,
+	name string,
+	limit uint64,
+) ([]string, error) {
+	var matches []string
+	if metricName != "" {
+		matches = []string{metricName}
+	}
+
+	values, warnings, err := uc.Client.LabelValues(
+		ctx,
+		name,
+		matches,
+		uc.Config.Generator.Metrics.StartAt,
+		time.Now(),
+		v1.WithLimit(limit),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(warnings) > 0 {
+		slog.Debug(
+			fmt.Sprintf("warning when fetching values of label `%s`", name),
+			slog.Any("warnings", warnings),
+		)
+	}
+
+	strValues := make([]string, len(values))
+
+	for i, value := range values {
+		strValues[i] = string(value)
+	}
+
+	return strValues, nil
+}
+
+// buildEnumLabelInfo builds the enum label from introspected values. Values of introspected enums are replaced,
+// and values of enums of the configuration are merged. If the number of values exceeds the maximum,
+// enums of the configuration are kept as they are and other labels become string labels.
+// Returns true if the maximum is exceeded.
+func buildEnumLabelInfo(
+	label metadata.LabelInfo,
+	values []string,
+	maxValues int,
+) (metadata.LabelInfo, bool) {
+	configured := label.Type == metadata.LabelTypeEnum && !label.Introspected
+
+	var enumValues []string
+	if configured {
+		enumValues = slices.Clone(label.Values)
+	}
+
+	for _, value := range values {
+		if value != "" && !slices.Contains(enumValues, value) {
+			enumValues = append(enumValues, value)
+		}
+	}
+
+	if configured && len(enumValues) > maxValues {
+		return label, true
+	}
+
+	if len(enumValues) == 0 || len(enumValues) > maxValues {
+		label.Type = ""
+		label.Values = nil
+		label.Introspected = false
+
+		return label, len(enumValues) > maxValues
+	}
+
+	slices.Sort(enumValues)
+
+	label.Type = metadata.LabelTypeEnum
+	label.Values = enumValues
+	label.Introspected = !configured
+
+	return label, false
+}
+
 func (uc *updateCommand) validateNativeQueries(ctx context.Context) error {
 	if len(uc.Config.Metadata.NativeOperations.Queries) == 0 {
 		return nil
 	}
 
-	uc.checkAPIFormatQueryExist(ctx)
-
 	newNativeQueries := make(map[string]metadata.NativeQuery)
 
 	for key, nativeQuery := range uc.Config.Metadata.NativeOperations.Queries {
@@ -344,44 +614,11 @@ func (uc *updateCommand) validateNativeQueries(ctx context.Context) error {
 			)
 		}
 
-		slog.Debug(
-			key,
-			slog.String("type", "native_query"),
-			slog.String("query", nativeQuery.Query),
-		)
-
-		args, err := uc.findNativeQueryVariables(nativeQuery)
-		if err != nil {
-			return fmt.Errorf("%w; query: %s", err, nativeQuery.Query)
-		}
-
-		nativeQuery.Arguments = args
-		query := nativeQuery.Query
-
-		// validate arguments and promQL syntaxes
-		for k, v := range nativeQuery.Arguments {
-			switch v.Type {
-			case string(metadata.ScalarInt64), string(metadata.ScalarFloat64):
-				query = strings.ReplaceAll(query, fmt.Sprintf("${%s}", k), "1")
-			case string(metadata.ScalarString), string(metadata.ScalarDuration), "":
-				query = strings.ReplaceAll(query, fmt.Sprintf("${%s}", k), "1m")
-			default:
-				return fmt.Errorf("invalid argument type `%s` in the native query `%s`", k, key)
-			}
-		}
-
-		err = uc.validateQuery(ctx, query)
-		if err != nil {
-			return fmt.Errorf("invalid native query %s: %w", key, err)
-		}
-
-		// format and replace $<name> to ${<name>}
-		query, err = uc.formatNativeQueryVariables(nativeQuery.Query, nativeQuery.Arguments)
+		nativeQuery, err := uc.validateNativeQuery(ctx, key, nativeQuery)
 		if err != nil {
 			return err
 		}
 
-		nativeQuery.Query = query
 		newNativeQueries[key] = nativeQuery
 	}
 
@@ -390,32 +627,117 @@ func (uc *updateCommand) validateNativeQueries(ctx context.Context) error {
 	return nil
 }
 
-func (uc *updateCommand) checkAPIFormatQueryExist(ctx context.Context) {
-	_, err := uc.Client.FormatQuery(ctx, "up")
+// validateNativeQuery validates and normalizes the native query.
+// New arguments are inferred from variables and labels are discovered.
+func (uc *updateCommand) validateNativeQuery(
+	ctx context.Context,
+	key string,
+	nativeQuery metadata.NativeQuery,
+) (metadata.NativeQuery, error) {
+	fragments := uc.Config.Metadata.NativeOperations.Fragments
+
+	slog.Debug(
+		key,
+		slog.String("type", "native_query"),
+		slog.String("query", nativeQuery.Query),
+	)
+
+	if nativeQuery.IsJoinQuery() {
+		if err := uc.validateNativeJoinQuery(&nativeQuery, fragments); err != nil {
+			return nativeQuery, fmt.Errorf("invalid native query %s: %w", key, err)
+		}
+
+		return nativeQuery, uc.validateExperimentalFunctions(key, nativeQuery)
+	}
 
-	uc.apiFormatExists = err == nil
+	// variables of fragments are also arguments of the query.
+	if err := nativeQuery.ExpandFragments(fragments); err != nil {
+		return nativeQuery, fmt.Errorf("invalid native query %s: %w", key, err)
+	}
+
+	args, err := uc.findNativeQueryVariables(nativeQuery)
 	if err != nil {
-		slog.Debug(
-			"failed to request /api/v1/format_query endpoint",
-			slog.String("error", err.Error()),
-		)
+		return nativeQuery, fmt.Errorf("%w; query: %s", err, nativeQuery.ExpandedQuery())
 	}
-}
 
-func (uc *updateCommand) validateQuery(ctx context.Context, query string) error {
-	if uc.apiFormatExists {
-		_, err := uc.Client.FormatQuery(ctx, query)
+	nativeQuery.Arguments = args
 
-		return err
+	// format and replace $<name> to ${<name>}
+	nativeQuery.Query, err = uc.formatNativeQueryVariables(
+		nativeQuery.Query,
+		nativeQuery.Arguments,
+	)
+	if err != nil {
+		return nativeQuery, err
 	}
 
-	now := time.Now()
-	_, _, err := uc.Client.Query(ctx, query, &now, 30*time.Second)
+	if err := nativeQuery.ExpandFragments(fragments); err != nil {
+		return nativeQuery, fmt.Errorf("invalid native query %s: %w", key, err)
+	}
 
-	return err
+	// validate arguments and promQL syntaxes
+	if err := nativeQuery.Validate(); err != nil {
+		return nativeQuery, fmt.Errorf("invalid native query %s: %w", key, err)
+	}
+
+	if err := uc.validateExperimentalFunctions(key, nativeQuery); err != nil {
+		return nativeQuery, err
+	}
+
+	// the default result type is vector.
+	if nativeQuery.ResultType == "" {
+		resultType, err := nativeQuery.InferResultType()
+		if err != nil {
+			return nativeQuery, fmt.Errorf("invalid native query %s: %w", key, err)
+		}
+
+		if resultType != metadata.NativeQueryResultVector {
+			nativeQuery.ResultType = resultType
+		}
+	}
+
+	// scalar and string results don't have labels.
+	if nativeQuery.GetResultType().IsSeries() {
+		nativeQuery.Labels = uc.discoverNativeQueryLabels(ctx, key, nativeQuery)
+	}
+
+	return nativeQuery, nil
+}
+
+// validateExperimentalFunctions rejects experimental PromQL functions of the native query
+// if they aren't enabled by the runtime settings.
+func (uc *updateCommand) validateExperimentalFunctions(
+	key string,
+	nativeQuery metadata.NativeQuery,
+) error {
+	if uc.Config.Runtime.IsPromQLExperimentalFunctionsEnabled() {
+		return nil
+	}
+
+	if err := nativeQuery.ValidateExperimentalFunctions(); err != nil {
+		return fmt.Errorf("invalid native query %s: %w", key, err)
+	}
+
+	return nil
 }
 
 func (uc *updateCommand) writeConfigFile() error {
+	config := *uc.Config
+	config.Metadata.NativeOperations.Queries = make(map[string]metadata.NativeQuery)
+
+	// native queries of .promql files are written back to their files.
+	for key, nativeQuery := range uc.Config.Metadata.NativeOperations.Queries {
+		if nativeQuery.File() == nil {
+			config.Metadata.NativeOperations.Queries[key] = nativeQuery
+
+			continue
+		}
+
+		if err := metadata.WriteNativeQueryFile(nativeQuery); err != nil {
+			return fmt.Errorf("failed to write the native query %s: %w", key, err)
+		}
+	}
+
 	var buf bytes.Buffer
 
 	writer := bufio.NewWriter(&buf)
@@ -426,7 +748,7 @@ func (uc *updateCommand) writeConfigFile() error {
 	encoder := yaml.NewEncoder(writer)
 	encoder.SetIndent(2)
 
-	if err := encoder.Encode(uc.Config); err != nil {
+	if err := encoder.Encode(config); err != nil {
 		return fmt.Errorf("failed to encode the configuration file: %w", err)
 	}
 
@@ -439,13 +761,14 @@ func (uc *updateCommand) findNativeQueryVariables(
 	nq metadata.NativeQuery,
 ) (map[string]metadata.NativeQueryArgumentInfo, error) {
 	result := map[string]metadata.NativeQueryArgumentInfo{}
+	query := nq.ExpandedQuery()
 
-	matches := nativeQueryVariableRegex.FindAllStringSubmatchIndex(nq.Query, -1)
+	matches := nativeQueryVariableRegex.FindAllStringSubmatchIndex(query, -1)
 	if len(matches) == 0 {
 		return result, nil
 	}
 
-	queryLength := len(nq.Query)
+	queryLength := len(query)
 
 	for _, match := range matches {
 		if len(match) < 4 {
@@ -457,10 +780,59 @@ func (uc *updateCommand) findNativeQueryVariables(
 			return nil, err
 		}
 
+		// built-in variables are evaluated from the time range of requests.
+		if metadata.IsBuiltinVariable(name) {
+			continue
+		}
+
 		result[name] = *argumentInfo
 	}
 
-	return result, nil
+	return uc.evalNativeQueryVariableContexts(nq, result)
+}
+
+// evalNativeQueryVariableContexts completes new arguments by syntactic contexts of their variables.
+// Arguments in optional blocks [[ ]] are nullable, arguments in label lists are arrays.
+func (uc *updateCommand) evalNativeQueryVariableContexts(
+	nq metadata.NativeQuery,
+	arguments map[string]metadata.NativeQueryArgumentInfo,
+) (map[string]metadata.NativeQueryArgumentInfo, error) {
+	query, err := uc.formatNativeQueryVariables(nq.ExpandedQuery(), arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := metadata.ScanNativeQueryTemplate(query)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, variable := range template.Variables {
+		if _, ok := nq.Arguments[variable.Name]; ok {
+			continue
+		}
+
+		argumentInfo, ok := arguments[variable.Name]
+		if !ok {
+			continue
+		}
+
+		switch variable.Context {
+		case metadata.VariableContextDuration:
+			argumentInfo.Type = string(metadata.ScalarDuration)
+		case metadata.VariableContextLabelList:
+			argumentInfo.Array = true
+		default:
+		}
+
+		if variable.Block >= 0 {
+			argumentInfo.Nullable = true
+		}
+
+		arguments[variable.Name] = argumentInfo
+	}
+
+	return arguments, nil
 }
 
 func (uc *updateCommand) evalMatchedNativeQuery(
@@ -468,19 +840,20 @@ func (uc *updateCommand) evalMatchedNativeQuery(
 	match []int,
 	queryLength int,
 ) (string, *metadata.NativeQueryArgumentInfo, error) {
-	name := nq.Query[match[2]:match[3]]
+	query := nq.ExpandedQuery()
+	name := query[match[2]:match[3]]
 	argumentInfo := metadata.NativeQueryArgumentInfo{}
 
-	if match[0] > 0 && nq.Query[match[0]-1] == '[' {
+	if match[0] > 0 && query[match[0]-1] == '[' {
 		// duration variables should be bounded by square brackets
-		if match[1] >= queryLength || nq.Query[match[1]] != ']' {
+		if match[1] >= queryLength || query[match[1]] != ']' {
 			return "", nil, errors.New("invalid promQL range syntax")
 		}
 
 		argumentInfo.Type = string(metadata.ScalarDuration)
-	} else if match[0] > 0 && nq.Query[match[0]-1] == '"' {
+	} else if match[0] > 0 && query[match[0]-1] == '"' {
 		// duration variables should be bounded by double quotes
-		if match[1] >= queryLength || nq.Query[match[1]] != '"' {
+		if match[1] >= queryLength || query[match[1]] != '"' {
 			return "", nil, errors.New("invalid promQL string syntax")
 		}
 
@@ -492,6 +865,11 @@ func (uc *updateCommand) evalMatchedNativeQuery(
 		arg, ok := nq.Arguments[name]
 		if ok {
 			argumentInfo.Description = arg.Description
+			argumentInfo.Array = arg.Array
+			argumentInfo.Enum = arg.Enum
+			argumentInfo.Default = arg.Default
+			argumentInfo.Nullable = arg.Nullable
+			argumentInfo.Example = arg.Example
 
 			if argumentInfo.Type == "" && arg.Type != "" {
 				argumentInfo.Type = arg.Type
@@ -1,9 +1,19 @@
 package main
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/hasura/ndc-prometheus/connector/client"
 	"github.com/hasura/ndc-prometheus/connector/metadata"
+	"github.com/hasura/ndc-sdk-go/utils"
+	"github.com/prometheus/common/model"
 	"gotest.tools/v3/assert"
 )
 
@@ -53,6 +63,17 @@ func TestNativeQueryVariables(t *testing.T) {
 			},
 			ExpectedQuery: `rate(up{job="${job}", instance="${instance}"}[${range}])`,
 		},
+		{
+			Input: metadata.NativeQuery{
+				Query: `rate(up{job="$job"}[$__rate_interval]) / ${__range_s}`,
+			},
+			ExpectedArguments: map[string]metadata.NativeQueryArgumentInfo{
+				"job": {
+					Type: string(metadata.ScalarString),
+				},
+			},
+			ExpectedQuery: `rate(up{job="${job}"}[$__rate_interval]) / ${__range_s}`,
+		},
 		{
 			Input: metadata.NativeQuery{
 				Query: `up{job="${job}"} > $value`,
@@ -89,6 +110,35 @@ func TestNativeQueryVariables(t *testing.T) {
 			},
 			ExpectedQuery: `up{job="${job}"} > ${value}`,
 		},
+		{
+			Input: metadata.NativeQuery{
+				Query: `sum by ($labels) (up{job="${job}"[[, namespace="$namespace"]]} offset $offset)`,
+				Arguments: map[string]metadata.NativeQueryArgumentInfo{
+					"job": {
+						Type: string(metadata.ScalarString),
+						Enum: []string{"node"},
+					},
+				},
+			},
+			ExpectedArguments: map[string]metadata.NativeQueryArgumentInfo{
+				"labels": {
+					Type:  string(metadata.ScalarString),
+					Array: true,
+				},
+				"job": {
+					Type: string(metadata.ScalarString),
+					Enum: []string{"node"},
+				},
+				"namespace": {
+					Type:     string(metadata.ScalarString),
+					Nullable: true,
+				},
+				"offset": {
+					Type: string(metadata.ScalarDuration),
+				},
+			},
+			ExpectedQuery: `sum by (${labels}) (up{job="${job}"[[, namespace="${namespace}"]]} offset ${offset})`,
+		},
 		{
 			Input: metadata.NativeQuery{
 				Query: "up[$range",
@@ -120,3 +170,461 @@ func TestNativeQueryVariables(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildEnumLabelInfo(t *testing.T) {
+	description := "The HTTP method"
+
+	testCases := []struct {
+		Name     string
+		Label    metadata.LabelInfo
+		Values   []string
+		Expected metadata.LabelInfo
+		Exceeded bool
+	}{
+		{
+			Name:   "enum",
+			Label:  metadata.LabelInfo{Description: &description},
+			Values: []string{"POST", "GET", ""},
+			Expected: metadata.LabelInfo{
+				Description:  &description,
+				Type:         metadata.LabelTypeEnum,
+				Values:       []string{"GET", "POST"},
+				Introspected: true,
+			},
+		},
+		{
+			Name: "refresh",
+			Label: metadata.LabelInfo{
+				Type:         metadata.LabelTypeEnum,
+				Values:       []string{"GET", "PUT"},
+				Introspected: true,
+			},
+			Values: []string{"POST", "GET"},
+			Expected: metadata.LabelInfo{
+				Type:         metadata.LabelTypeEnum,
+				Values:       []string{"GET", "POST"},
+				Introspected: true,
+			},
+		},
+		{
+			Name:   "merge",
+			Label:  metadata.LabelInfo{Type: metadata.LabelTypeEnum, Values: []string{"PUT"}},
+			Values: []string{"GET"},
+			Expected: metadata.LabelInfo{
+				Type:   metadata.LabelTypeEnum,
+				Values: []string{"GET", "PUT"},
+			},
+		},
+		{
+			Name:     "exceeded",
+			Values:   []string{"PUT", "GET", "POST", "DELETE"},
+			Expected: metadata.LabelInfo{},
+			Exceeded: true,
+		},
+		{
+			Name: "exceeded_introspected_enum",
+			Label: metadata.LabelInfo{
+				Type:         metadata.LabelTypeEnum,
+				Values:       []string{"PUT", "GET"},
+				Introspected: true,
+			},
+			Values:   []string{"PUT", "GET", "POST", "DELETE"},
+			Expected: metadata.LabelInfo{},
+			Exceeded: true,
+		},
+		{
+			Name:   "exceeded_configured_enum",
+			Label:  metadata.LabelInfo{Type: metadata.LabelTypeEnum, Values: []string{"PUT", "GET"}},
+			Values: []string{"POST", "DELETE"},
+			Expected: metadata.LabelInfo{
+				Type:   metadata.LabelTypeEnum,
+				Values: []string{"PUT", "GET"},
+			},
+			Exceeded: true,
+		},
+		{
+			Name:     "empty",
+			Values:   []string{},
+			Expected: metadata.LabelInfo{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			result, exceeded := buildEnumLabelInfo(tc.Label, tc.Values, 3)
+			assert.DeepEqual(t, tc.Expected, result)
+			assert.Equal(t, tc.Exceeded, exceeded)
+		})
+	}
+}
+
+func TestRenderNativeQueryExample(t *testing.T) {
+	testCases := []struct {
+		Name      string
+		Query     string
+		Arguments map[string]metadata.NativeQueryArgumentInfo
+		Expected  string
+		Missing   bool
+	}{
+		{
+			Name:  "samples",
+			Query: `rate(up{job=~"${job}"}[${range}]) > ${value}`,
+			Arguments: map[string]metadata.NativeQueryArgumentInfo{
+				"job":   {Type: string(metadata.ScalarString)},
+				"range": {Type: string(metadata.ScalarDuration)},
+				"value": {Type: string(metadata.ScalarFloat64)},
+			},
+			Expected: `rate(up{job=~".*"}[5m]) > 1`,
+		},
+		{
+			Name:  "examples",
+			Query: `sum by (${labels}) (up{job=~"${jobs}", env="${env}"[[, namespace="${namespace}"]]})`,
+			Arguments: map[string]metadata.NativeQueryArgumentInfo{
+				"labels":    {Array: true, Example: []any{"job", "env"}},
+				"jobs":      {Array: true, Example: []any{"node", "a.b"}},
+				"env":       {Default: "prod", Example: `"dev"`},
+				"namespace": {Nullable: true},
+			},
+			Expected: `sum by (job, env) (up{job=~"node|a\\.b", env="\"dev\""})`,
+		},
+		{
+			Name:  "missing_example",
+			Query: `up{job="${job}"}`,
+			Arguments: map[string]metadata.NativeQueryArgumentInfo{
+				"job": {Type: string(metadata.ScalarString)},
+			},
+			Missing: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			nq := metadata.NativeQuery{
+				Query:     tc.Query,
+				Arguments: tc.Arguments,
+			}
+			assert.NilError(t, nq.Validate())
+
+			query, ok, err := renderNativeQueryExample(nq)
+			assert.NilError(t, err)
+			assert.Equal(t, !tc.Missing, ok)
+
+			if !tc.Missing {
+				assert.Equal(t, tc.Expected, query)
+			}
+		})
+	}
+}
+
+func TestDiscoverNativeQueryLabels(t *testing.T) {
+	var queries []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NilError(t, r.ParseForm())
+		queries = append(queries, r.Form.Get("query"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[
+			{"metric":{"__name__":"up","job":"node","instance":"node:9100","env":"prod"},"value":[1700000000,"1"]}
+		]}}`))
+	}))
+	defer server.Close()
+
+	apiClient, err := client.NewClient(context.TODO(), client.ClientSettings{
+		URL: utils.NewEnvStringValue(server.URL),
+	})
+	assert.NilError(t, err)
+
+	uc := &updateCommand{Client: apiClient}
+
+	// labels of `by` aggregations are exact, so the query isn't run.
+	nq := metadata.NativeQuery{
+		Query: `label_replace(sum by (job, instance) (up), "service", "$1", "job", "(.*)")`,
+		Labels: map[string]metadata.LabelInfo{
+			"job": {Description: utils.ToPtr("The job name")},
+		},
+	}
+	assert.NilError(t, nq.Validate())
+
+	assert.DeepEqual(t, map[string]metadata.LabelInfo{
+		"job":      {Description: utils.ToPtr("The job name")},
+		"instance": {},
+		"service":  {},
+	}, uc.discoverNativeQueryLabels(context.Background(), "test", nq))
+	assert.Equal(t, 0, len(queries))
+
+	nq = metadata.NativeQuery{Query: `up`}
+	assert.NilError(t, nq.Validate())
+
+	assert.DeepEqual(t, map[string]metadata.LabelInfo{
+		"job":      {},
+		"instance": {},
+		"env":      {},
+	}, uc.discoverNativeQueryLabels(context.Background(), "test", nq))
+	assert.DeepEqual(t, []string{"up"}, queries)
+}
+
+func TestUpdateNativeQueryFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "configuration.yaml"), []byte(`metadata:
+  metrics: {}
+  native_operations:
+    directory: .
+    queries:
+      inline_up:
+        query: up
+        labels: {}
+        arguments: {}
+`), 0o644))
+	assert.NilError(t, os.WriteFile(
+		filepath.Join(dir, "service_up.promql"),
+		[]byte(`max by (job) (up{instance="$instance"})`),
+		0o644,
+	))
+
+	config, err := metadata.ReadConfiguration(dir)
+	assert.NilError(t, err)
+
+	uc := &updateCommand{
+		Config:    config,
+		OutputDir: dir,
+	}
+	assert.NilError(t, uc.validateNativeQueries(context.Background()))
+	assert.NilError(t, uc.writeConfigFile())
+
+	config, err = metadata.ReadConfiguration(dir)
+	assert.NilError(t, err)
+
+	queries := config.Metadata.NativeOperations.Queries
+	assert.Assert(t, queries["inline_up"].File() == nil)
+	assert.Equal(t, `max by (job) (up{instance="${instance}"})`, queries["service_up"].Query)
+	assert.DeepEqual(t, map[string]metadata.LabelInfo{"job": {}}, queries["service_up"].Labels)
+	assert.Equal(t, string(metadata.ScalarString), queries["service_up"].Arguments["instance"].Type)
+
+	configBytes, err := os.ReadFile(filepath.Join(dir, "configuration.yaml"))
+	assert.NilError(t, err)
+	assert.Assert(t, !strings.Contains(string(configBytes), "service_up"))
+}
+
+func TestValidateNativeQueryFragments(t *testing.T) {
+	uc := &updateCommand{
+		Config: &metadata.Configuration{
+			Metadata: metadata.Metadata{
+				NativeOperations: metadata.NativeOperations{
+					Fragments: map[string]metadata.NativeQueryFragment{
+						"rate_by_job": {
+							Query:      `sum by (job) (rate({{ metric }}[${range}]))`,
+							Parameters: []string{"metric"},
+						},
+					},
+					Queries: map[string]metadata.NativeQuery{
+						"request_rate": {
+							Query: `{{ rate_by_job(http_requests_total) }} > $value`,
+						},
+					},
+				},
+			},
+		},
+	}
+	assert.NilError(t, uc.validateNativeQueries(context.Background()))
+
+	nq := uc.Config.Metadata.NativeOperations.Queries["request_rate"]
+	assert.Equal(t, `{{ rate_by_job(http_requests_total) }} > ${value}`, nq.Query)
+	assert.DeepEqual(t, map[string]metadata.NativeQueryArgumentInfo{
+		"range": {Type: string(metadata.ScalarDuration)},
+		"value": {Type: string(metadata.ScalarString)},
+	}, nq.Arguments)
+	assert.DeepEqual(t, map[string]metadata.LabelInfo{"job": {}}, nq.Labels)
+
+	uc.Config.Metadata.NativeOperations.Queries["cycle"] = metadata.NativeQuery{
+		Query: `{{ cycle }}`,
+	}
+	uc.Config.Metadata.NativeOperations.Fragments["cycle"] = metadata.NativeQueryFragment{
+		Query: `{{ cycle }}`,
+	}
+	assert.ErrorContains(
+		t,
+		uc.validateNativeQueries(context.Background()),
+		"invalid native query cycle: cyclic fragment references: cycle -> cycle",
+	)
+}
+
+func TestValidateNativeQueryResultType(t *testing.T) {
+	uc := &updateCommand{
+		Config: &metadata.Configuration{
+			Metadata: metadata.Metadata{
+				NativeOperations: metadata.NativeOperations{
+					Queries: map[string]metadata.NativeQuery{
+						"up_count": {
+							Query: `scalar(count(up{job="${job}"}))`,
+						},
+						"up": {
+							Query: `up{job="${job}"}`,
+						},
+					},
+				},
+			},
+		},
+	}
+	assert.NilError(t, uc.validateNativeQueries(context.Background()))
+
+	queries := uc.Config.Metadata.NativeOperations.Queries
+	assert.Equal(t, metadata.NativeQueryResultScalar, queries["up_count"].ResultType)
+	assert.Equal(t, 0, len(queries["up_count"].Labels))
+	assert.Equal(t, metadata.NativeQueryResultType(""), queries["up"].ResultType)
+}
+
+func TestValidateNativeJoinQuery(t *testing.T) {
+	uc := &updateCommand{
+		Config: &metadata.Configuration{
+			Metadata: metadata.Metadata{
+				NativeOperations: metadata.NativeOperations{
+					Queries: map[string]metadata.NativeQuery{
+						"service_health": {
+							Expressions: map[string]string{
+								"requests": `sum by (job) (rate(http_requests_total{job=~"$job"}[$range]))`,
+								"errors":   `sum by (job) (rate(http_requests_total{job=~"$job",code=~"5.."}[$range]))`,
+							},
+							JoinLabels: []string{"job"},
+							Labels: map[string]metadata.LabelInfo{
+								"code": {},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	assert.NilError(t, uc.validateNativeQueries(context.Background()))
+
+	nq := uc.Config.Metadata.NativeOperations.Queries["service_health"]
+	assert.DeepEqual(t, map[string]string{
+		"requests": `sum by (job) (rate(http_requests_total{job=~"${job}"}[${range}]))`,
+		"errors":   `sum by (job) (rate(http_requests_total{job=~"${job}",code=~"5.."}[${range}]))`,
+	}, nq.Expressions)
+	assert.DeepEqual(t, map[string]metadata.NativeQueryArgumentInfo{
+		"job":   {Type: string(metadata.ScalarString)},
+		"range": {Type: string(metadata.ScalarDuration)},
+	}, nq.Arguments)
+	assert.DeepEqual(t, map[string]metadata.LabelInfo{"job": {}}, nq.Labels)
+}
+
+func TestUpdateMetricScrapeIntervals(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data string
+
+		switch r.URL.Path {
+		case "/api/v1/targets":
+			data = `{"activeTargets":[
+				{"labels":{"job":"node","instance":"node:9100"},"scrapeInterval":"30s"},
+				{"labels":{"job":"api","instance":"api:8080"},"scrapeInterval":"15s"},
+				{"labels":{"job":"api","instance":"api:8081"},"scrapeInterval":"1m"}
+			]}`
+		case "/api/v1/targets/metadata":
+			data = `[
+				{"target":{"job":"node","instance":"node:9100"},"metric":"node_cpu_seconds_total","type":"counter"},
+				{"target":{"job":"api","instance":"api:8080"},"metric":"http_requests_total","type":"counter"},
+				{"target":{"job":"api","instance":"api:8081"},"metric":"http_requests_total","type":"counter"},
+				{"target":{"job":"api","instance":"api:8080"},"metric":"process_cpu_seconds_total","type":"counter"},
+				{"target":{"job":"api","instance":"api:8080"},"metric":"go_goroutines","type":"gauge"}
+			]`
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":` + data + `}`))
+	}))
+	defer server.Close()
+
+	apiClient, err := client.NewClient(context.TODO(), client.ClientSettings{
+		URL: utils.NewEnvStringValue(server.URL),
+	})
+	assert.NilError(t, err)
+
+	uc := &updateCommand{
+		Client: apiClient,
+		Config: &metadata.Configuration{
+			Metadata: metadata.Metadata{
+				Metrics: map[string]metadata.MetricInfo{
+					"node_cpu_seconds_total":    {Type: model.MetricTypeCounter},
+					"http_requests_total":       {Type: model.MetricTypeCounter},
+					"process_cpu_seconds_total": {Type: model.MetricTypeCounter},
+					"go_goroutines":             {Type: model.MetricTypeGauge},
+					"custom_metric":             {Type: model.MetricTypeGauge},
+				},
+			},
+		},
+		previousMetrics: map[string]metadata.MetricInfo{
+			"custom_metric": {
+				Type:           model.MetricTypeGauge,
+				ScrapeInterval: utils.ToPtr(model.Duration(10 * time.Second)),
+			},
+			"node_cpu_seconds_total": {
+				Type:                     model.MetricTypeCounter,
+				ScrapeInterval:           utils.ToPtr(model.Duration(10 * time.Second)),
+				ScrapeIntervalDiscovered: true,
+			},
+			"process_cpu_seconds_total": {
+				Type:                     model.MetricTypeCounter,
+				ScrapeInterval:           utils.ToPtr(model.Duration(time.Minute)),
+				ScrapeIntervalDiscovered: true,
+			},
+			"go_goroutines": {
+				Type:           model.MetricTypeGauge,
+				ScrapeInterval: utils.ToPtr(model.Duration(5 * time.Second)),
+			},
+		},
+	}
+
+	uc.updateMetricScrapeIntervals(context.TODO())
+
+	metrics := uc.Config.Metadata.Metrics
+	assert.DeepEqual(t, utils.ToPtr(model.Duration(30*time.Second)), metrics["node_cpu_seconds_total"].ScrapeInterval)
+	assert.Assert(t, metrics["node_cpu_seconds_total"].ScrapeIntervalDiscovered)
+	assert.DeepEqual(t, utils.ToPtr(model.Duration(time.Minute)), metrics["http_requests_total"].ScrapeInterval)
+	assert.Assert(t, metrics["http_requests_total"].ScrapeIntervalDiscovered)
+	assert.Assert(t, metrics["process_cpu_seconds_total"].ScrapeInterval == nil)
+	assert.Assert(t, !metrics["process_cpu_seconds_total"].ScrapeIntervalDiscovered)
+	assert.DeepEqual(t, utils.ToPtr(model.Duration(5*time.Second)), metrics["go_goroutines"].ScrapeInterval)
+	assert.Assert(t, !metrics["go_goroutines"].ScrapeIntervalDiscovered)
+	assert.DeepEqual(t, utils.ToPtr(model.Duration(10*time.Second)), metrics["custom_metric"].ScrapeInterval)
+}
+
+func TestUpdateMetricScrapeIntervalsFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	apiClient, err := client.NewClient(context.TODO(), client.ClientSettings{
+		URL: utils.NewEnvStringValue(server.URL),
+	})
+	assert.NilError(t, err)
+
+	uc := &updateCommand{
+		Client: apiClient,
+		Config: &metadata.Configuration{
+			Metadata: metadata.Metadata{
+				Metrics: map[string]metadata.MetricInfo{
+					"node_cpu_seconds_total": {Type: model.MetricTypeCounter},
+				},
+			},
+		},
+		previousMetrics: map[string]metadata.MetricInfo{
+			"node_cpu_seconds_total": {
+				Type:           model.MetricTypeCounter,
+				ScrapeInterval: utils.ToPtr(model.Duration(30 * time.Second)),
+			},
+		},
+	}
+
+	uc.updateMetricScrapeIntervals(context.TODO())
+
+	assert.DeepEqual(
+		t,
+		utils.ToPtr(model.Duration(30*time.Second)),
+		uc.Config.Metadata.Metrics["node_cpu_seconds_total"].ScrapeInterval,
+	)
+}
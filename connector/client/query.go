@@ -15,7 +15,7 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
-// Query evaluates an [instant query] at a single point in time
+// Query evaluates an [instant query] at a single point in time and expects an instant vector result.
 //
 // [instant query]: https://prometheus.io/docs/prometheus/latest/querying/api/#instant-queries
 func (c *Client) Query(
@@ -24,6 +24,28 @@ func (c *Client) Query(
 	ts *time.Time,
 	timeout time.Duration,
 ) (model.Vector, v1.Warnings, error) {
+	r, warnings, err := c.QueryValue(ctx, queryString, ts, timeout)
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	if result, ok := r.(model.Vector); ok {
+		return result, warnings, nil
+	}
+
+	return nil, warnings, errors.New("did not receive an instant vector result")
+}
+
+// QueryValue evaluates an [instant query] at a single point in time.
+// The result can be a vector, matrix, scalar or string.
+//
+// [instant query]: https://prometheus.io/docs/prometheus/latest/querying/api/#instant-queries
+func (c *Client) QueryValue(
+	ctx context.Context,
+	queryString string,
+	ts *time.Time,
+	timeout time.Duration,
+) (model.Value, v1.Warnings, error) {
 	ctx, span := clientTracer.Start(ctx, "PrometheusQuery")
 	defer span.End()
 
@@ -43,13 +65,74 @@ func (c *Client) Query(
 		return nil, nil, err
 	}
 
+	r, warnings, err := c.API.Query(ctx, queryString, *ts, opts...)
+
+	if len(warnings) > 0 {
+		span.SetAttributes(attribute.StringSlice("warnings", warnings))
+	}
+
+	if err != nil {
+		span.SetStatus(codes.Error, "error querying prometheus")
+		span.RecordError(err)
+
+		return nil, warnings, fmt.Errorf("error querying prometheus: %w", err)
+	}
+
+	span.SetAttributes(attribute.String("result_type", r.Type().String()))
+
+	return r, warnings, nil
+}
+
+type queryStringResult struct {
+	ResultType model.ValueType `json:"resultType"`
+	Result     model.String    `json:"result"`
+}
+
+// QueryString evaluates an [instant query] that returns a string result, e.g. a string literal.
+// The base API library doesn't decode string results.
+//
+// [instant query]: https://prometheus.io/docs/prometheus/latest/querying/api/#instant-queries
+func (c *Client) QueryString(
+	ctx context.Context,
+	queryString string,
+	ts *time.Time,
+	timeout time.Duration,
+) (*model.String, v1.Warnings, error) {
+	ctx, span := clientTracer.Start(ctx, "PrometheusQuery")
+	defer span.End()
+
 	if ts == nil {
-		now := time.Now()
-		ts = &now
+		t := time.Now()
+		ts = &t
 	}
 
-	r, warnings, err := c.API.Query(ctx, queryString, *ts, opts...)
+	c.setQuerySpanAttributes(span, queryString)
+	span.SetAttributes(
+		attribute.String("timestamp", ts.String()),
+		attribute.String("timeout", timeout.String()),
+	)
 
+	if timeout == 0 && c.timeout != nil {
+		timeout = time.Duration(*c.timeout)
+	}
+
+	u := c.client.URL("/api/v1/query", nil)
+	q := u.Query()
+	q.Set("query", queryString)
+	q.Set("time", formatTime(*ts))
+
+	if timeout > 0 {
+		q.Set("timeout", model.Duration(timeout).String())
+	}
+
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	body, warnings, err := c.do(ctx, req)
 	if len(warnings) > 0 {
 		span.SetAttributes(attribute.StringSlice("warnings", warnings))
 	}
@@ -61,14 +144,20 @@ func (c *Client) Query(
 		return nil, warnings, fmt.Errorf("error querying prometheus: %w", err)
 	}
 
-	if result, ok := r.(model.Vector); ok {
-		return result, warnings, nil
+	var result queryStringResult
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, warnings, err
 	}
 
-	err = errors.New("did not receive an instant vector result")
-	span.SetStatus(codes.Error, err.Error())
+	if result.ResultType != model.ValString {
+		return nil, warnings, fmt.Errorf(
+			"did not receive a string result, got %s",
+			result.ResultType,
+		)
+	}
 
-	return nil, warnings, err
+	return &result.Result, warnings, nil
 }
 
 // QueryRange evaluates a [range query] that performs query over a range of time
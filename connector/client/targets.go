@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// TargetScrapeInterval represents the scrape interval of an active target.
+type TargetScrapeInterval struct {
+	Labels         model.LabelSet
+	ScrapeInterval time.Duration
+}
+
+type activeTargetsResult struct {
+	ActiveTargets []struct {
+		Labels         model.LabelSet `json:"labels"`
+		ScrapeInterval string         `json:"scrapeInterval"`
+	} `json:"activeTargets"`
+}
+
+// TargetScrapeIntervals returns scrape intervals of active [targets].
+// The base API library doesn't decode scrape intervals of targets.
+// Targets without the scrape interval, e.g. of old Prometheus versions, are skipped.
+//
+// [targets]: https://prometheus.io/docs/prometheus/latest/querying/api/#targets
+func (c *Client) TargetScrapeIntervals(ctx context.Context) ([]TargetScrapeInterval, error) {
+	u := c.client.URL("/api/v1/targets", nil)
+	q := u.Query()
+	q.Set("state", "active")
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	body, _, err := c.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result activeTargetsResult
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	targets := make([]TargetScrapeInterval, 0, len(result.ActiveTargets))
+
+	for _, target := range result.ActiveTargets {
+		if target.ScrapeInterval == "" {
+			continue
+		}
+
+		interval, err := model.ParseDuration(target.ScrapeInterval)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"invalid scrape interval of the target %s: %w",
+				target.Labels,
+				err,
+			)
+		}
+
+		targets = append(targets, TargetScrapeInterval{
+			Labels:         target.Labels,
+			ScrapeInterval: time.Duration(interval),
+		})
+	}
+
+	return targets, nil
+}
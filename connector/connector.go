@@ -71,6 +71,24 @@ func (c *PrometheusConnector) ParseConfiguration(
 		return nil, fmt.Errorf("invalid runtime configuration: %w", err)
 	}
 
+	for name, nativeQuery := range config.Metadata.NativeOperations.Queries {
+		if err := nativeQuery.ExpandFragments(config.Metadata.NativeOperations.Fragments); err != nil {
+			return nil, fmt.Errorf("invalid native query %s: %w", name, err)
+		}
+
+		if err := nativeQuery.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid native query %s: %w", name, err)
+		}
+
+		if !config.Runtime.IsPromQLExperimentalFunctionsEnabled() {
+			if err := nativeQuery.ValidateExperimentalFunctions(); err != nil {
+				return nil, fmt.Errorf("invalid native query %s: %w", name, err)
+			}
+		}
+
+		config.Metadata.NativeOperations.Queries[name] = nativeQuery
+	}
+
 	c.metadata = &config.Metadata
 	c.runtime = &config.Runtime
 
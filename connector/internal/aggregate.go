@@ -2,6 +2,7 @@ package internal
 
 import (
 	"context"
+	"slices"
 	"sync"
 	"time"
 
@@ -95,7 +96,12 @@ func (qce *QueryCollectionExecutor) queryGroupAggregates(
 		return nil, nil
 	}
 
-	results := NewGroupResults(qce.Runtime)
+	results := NewGroupResults(
+		qce.Runtime,
+		qce.Metric.Labels,
+		explainResult.Request.Location,
+		explainResult.Request.TimestampBucket,
+	)
 	aggregateLength := len(explainResult.Groups.AggregateQueries)
 
 	if aggregateLength == 1 {
@@ -145,6 +151,7 @@ func (qce *QueryCollectionExecutor) queryGroupAggregate(
 	results.MergeMatrixToAggregateGroups(
 		rawMatrix,
 		key,
+		getAggregateFunctionName(explainResult.Request.Groups.Aggregates[key]),
 		explainResult.Groups.Dimensions,
 	)
 
@@ -174,16 +181,29 @@ func (ar *AggregateResults) SetResult(key string, value any) {
 
 // GroupResults store the aggregate group results with mutex lock.
 type GroupResults struct {
-	groups  []schema.Group
-	runtime *metadata.RuntimeSettings
-	lock    sync.Mutex
+	groups   []schema.Group
+	runtime  *metadata.RuntimeSettings
+	labels   map[string]metadata.LabelInfo
+	location *time.Location
+	bucket   *TimestampBucket
+	// accumulators of timestamp buckets by group index and aggregate key.
+	accumulators []map[string]*bucketAccumulator
+	lock         sync.Mutex
 }
 
 // NewGroupResults create a new GroupResults.
-func NewGroupResults(runtime *metadata.RuntimeSettings) *GroupResults {
+func NewGroupResults(
+	runtime *metadata.RuntimeSettings,
+	labels map[string]metadata.LabelInfo,
+	location *time.Location,
+	bucket *TimestampBucket,
+) *GroupResults {
 	return &GroupResults{
-		groups:  []schema.Group{},
-		runtime: runtime,
+		groups:   []schema.Group{},
+		runtime:  runtime,
+		labels:   labels,
+		location: location,
+		bucket:   bucket,
 	}
 }
 
@@ -191,18 +211,84 @@ func NewGroupResults(runtime *metadata.RuntimeSettings) *GroupResults {
 func (gr *GroupResults) MergeMatrixToAggregateGroups(
 	rawMatrix model.Matrix,
 	key string,
+	function string,
 	dimensions []string,
 ) []schema.Group {
 	gr.lock.Lock()
 	defer gr.lock.Unlock()
 
 	for _, sample := range rawMatrix {
+		if gr.bucket != nil && slices.Contains(dimensions, metadata.TimestampKey) {
+			gr.mergeSampleToBucketGroup(sample, key, function, dimensions)
+
+			continue
+		}
+
 		gr.groups = gr.mergeSampleToAggregateGroup(gr.groups, sample, key, dimensions)
 	}
 
 	return gr.groups
 }
 
+// mergeSampleToBucketGroup combines sample values into groups of calendar buckets
+// with the aggregate function.
+func (gr *GroupResults) mergeSampleToBucketGroup(
+	samples *model.SampleStream,
+	key string,
+	function string,
+	dimensions []string,
+) {
+L:
+	for _, item := range samples.Values {
+		dimensionValues := make([]any, len(dimensions))
+
+		for i, dim := range dimensions {
+			if dim == metadata.TimestampKey {
+				bucketStart := gr.bucket.Truncate(item.Timestamp.Time())
+				dimensionValues[i] = formatTimestamp(
+					model.TimeFromUnixNano(bucketStart.UnixNano()),
+					gr.runtime.Format.Timestamp,
+					gr.bucket.Location,
+				)
+
+				continue
+			}
+
+			dimensionValues[i] = gr.formatLabelDimension(samples.Metric, dim)
+		}
+
+		for i, g := range gr.groups {
+			if !equalSlice(g.Dimensions, dimensionValues) {
+				continue
+			}
+
+			acc, ok := gr.accumulators[i][key]
+			if !ok {
+				acc = &bucketAccumulator{function: metadata.PromQLFunctionName(function)}
+				gr.accumulators[i][key] = acc
+			}
+
+			acc.Add(item.Value)
+			g.Aggregates[key] = formatValue(acc.Value(), gr.runtime.Format)
+
+			continue L
+		}
+
+		acc := &bucketAccumulator{function: metadata.PromQLFunctionName(function)}
+		acc.Add(item.Value)
+
+		gr.groups = append(gr.groups, schema.Group{
+			Dimensions: dimensionValues,
+			Aggregates: schema.GroupAggregates{
+				key: formatValue(acc.Value(), gr.runtime.Format),
+			},
+		})
+		gr.accumulators = append(gr.accumulators, map[string]*bucketAccumulator{
+			key: acc,
+		})
+	}
+}
+
 func (gr *GroupResults) mergeSampleToAggregateGroup(
 	source []schema.Group,
 	samples *model.SampleStream,
@@ -218,12 +304,16 @@ L:
 
 		for i, dim := range dimensions {
 			if dim == metadata.TimestampKey {
-				group.Dimensions[i] = formatTimestamp(item.Timestamp, gr.runtime.Format.Timestamp)
+				group.Dimensions[i] = formatTimestamp(
+					item.Timestamp,
+					gr.runtime.Format.Timestamp,
+					gr.location,
+				)
 
 				continue
 			}
 
-			group.Dimensions[i] = samples.Metric[model.LabelName(dim)]
+			group.Dimensions[i] = gr.formatLabelDimension(samples.Metric, dim)
 		}
 
 		for i, g := range source {
@@ -240,3 +330,7 @@ L:
 
 	return source
 }
+
+func (gr *GroupResults) formatLabelDimension(metric model.Metric, name string) any {
+	return formatLabelValue(metric[model.LabelName(name)], gr.labels[name], gr.runtime.Format)
+}
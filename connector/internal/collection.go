@@ -3,22 +3,50 @@ package internal
 import (
 	"context"
 	"fmt"
+	"slices"
 
 	"github.com/hasura/ndc-prometheus/connector/client"
 	"github.com/hasura/ndc-prometheus/connector/metadata"
 	"github.com/hasura/ndc-sdk-go/schema"
 	"github.com/hasura/ndc-sdk-go/utils"
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql/parser"
 	"go.opentelemetry.io/otel/trace"
 )
 
-var valueBinaryOperators = map[string]string{
-	metadata.Equal:          "==",
-	metadata.NotEqual:       "!=",
-	metadata.Least:          "<",
-	metadata.LeastOrEqual:   "<=",
-	metadata.Greater:        ">",
-	metadata.GreaterOrEqual: ">=",
+var valueBinaryOperators = map[string]parser.ItemType{
+	metadata.Equal:          parser.EQLC,
+	metadata.NotEqual:       parser.NEQ,
+	metadata.Least:          parser.LSS,
+	metadata.LeastOrEqual:   parser.LTE,
+	metadata.Greater:        parser.GTR,
+	metadata.GreaterOrEqual: parser.GTE,
+}
+
+// functions that aggregate series or rewrite labels,
+// so series can't be filtered by original labels after the query.
+var labelPostFilterUnsupportedFunctions = []metadata.PromQLFunctionName{
+	metadata.Sum,
+	metadata.Min,
+	metadata.Max,
+	metadata.Avg,
+	metadata.Count,
+	metadata.CountValues,
+	metadata.Stddev,
+	metadata.Stdvar,
+	metadata.TopK,
+	metadata.BottomK,
+	metadata.Quantile,
+	metadata.LimitK,
+	metadata.LimitRatio,
+	metadata.Group,
+	metadata.Absent,
+	metadata.AbsentOverTime,
+	metadata.HistogramFraction,
+	metadata.HistogramQuantile,
+	metadata.LabelJoin,
+	metadata.LabelReplace,
+	metadata.Scalar,
 }
 
 type QueryCollectionExecutor struct {
@@ -30,6 +58,9 @@ type QueryCollectionExecutor struct {
 	Metric     metadata.MetricInfo
 	Variables  map[string]any
 	Arguments  map[string]any
+	// The PromQL expression that is queried instead of the metric selector, e.g. the native query.
+	// Label matchers can't be applied to the expression, so labels are filtered after the query.
+	Subquery parser.Expr
 }
 
 // Execute executes the query request.
@@ -113,9 +144,20 @@ func (qce *QueryCollectionExecutor) queryInstant(
 		return nil, schema.UnprocessableContentError(err.Error(), nil)
 	}
 
+	vector, err = qce.filterVectorByLabels(vector, predicate)
+	if err != nil {
+		return nil, schema.UnprocessableContentError(err.Error(), nil)
+	}
+
 	sortVector(vector, predicate.OrderBy)
 	vector = paginateVector(vector, qce.Request.Query)
-	results := createQueryResultsFromVector(vector, qce.Metric.Labels, qce.Runtime, flat)
+	results := createQueryResultsFromVector(
+		vector,
+		qce.Metric.Labels,
+		qce.Runtime,
+		predicate.Location,
+		flat,
+	)
 
 	return results, nil
 }
@@ -131,8 +173,22 @@ func (qce *QueryCollectionExecutor) queryRange(
 		return nil, err
 	}
 
-	sortMatrix(matrix, predicate.OrderBy)
-	results := createQueryResultsFromMatrix(matrix, qce.Metric.Labels, qce.Runtime, flat)
+	// summaries are calculated from original values of series before downsampling.
+	summaries := evalMatrixSummaries(
+		matrix,
+		predicate.OrderBy,
+		!flat && isSummarySelected(qce.Request.Query.Fields),
+	)
+	sortMatrix(matrix, predicate.OrderBy, summaries)
+	matrix = downsampleMatrix(matrix, predicate.MaxPoints)
+	results := createQueryResultsFromMatrix(
+		matrix,
+		summaries,
+		qce.Metric.Labels,
+		qce.Runtime,
+		predicate.Location,
+		flat,
+	)
 
 	return paginateQueryResults(results, qce.Request.Query), nil
 }
@@ -147,5 +203,136 @@ func (qce *QueryCollectionExecutor) queryRangeMatrix(
 		return nil, schema.UnprocessableContentError(err.Error(), nil)
 	}
 
+	matrix, err = qce.filterMatrixByLabels(matrix, predicate)
+	if err != nil {
+		return nil, schema.UnprocessableContentError(err.Error(), nil)
+	}
+
 	return matrix, nil
 }
+
+// getLabelPostFilters returns expressions of labels that can't be compared in label matchers.
+// Series are filtered by those labels after the query is executed.
+func (qce *QueryCollectionExecutor) getLabelPostFilters(
+	predicate *CollectionRequest,
+) []*LabelExpression {
+	var results []*LabelExpression
+
+	for _, key := range utils.GetSortedKeys(predicate.LabelExpressions) {
+		if qce.Subquery != nil || qce.Metric.Labels[key].GetType() == metadata.LabelTypeFloat64 {
+			results = append(results, predicate.LabelExpressions[key])
+		}
+	}
+
+	return results
+}
+
+// validateLabelPostFilters checks if the query result keeps labels to be filtered.
+func (qce *QueryCollectionExecutor) validateLabelPostFilters(predicate *CollectionRequest) error {
+	postFilters := qce.getLabelPostFilters(predicate)
+	if len(postFilters) == 0 {
+		return nil
+	}
+
+	labelKind := "float64 label"
+	if qce.Subquery != nil {
+		labelKind = "label"
+	}
+
+	if len(predicate.Aggregates) > 0 || predicate.Groups != nil {
+		return fmt.Errorf(
+			"the %s %s can't be filtered in aggregate queries",
+			labelKind,
+			postFilters[0].Name,
+		)
+	}
+
+	for _, fn := range predicate.Functions {
+		if slices.Contains(
+			labelPostFilterUnsupportedFunctions,
+			metadata.PromQLFunctionName(fn.Key),
+		) {
+			return fmt.Errorf(
+				"the %s %s can't be filtered with the %s function",
+				labelKind,
+				postFilters[0].Name,
+				fn.Key,
+			)
+		}
+	}
+
+	return nil
+}
+
+func (qce *QueryCollectionExecutor) filterVectorByLabels(
+	vector model.Vector,
+	predicate *CollectionRequest,
+) (model.Vector, error) {
+	postFilters := qce.getLabelPostFilters(predicate)
+	if len(postFilters) == 0 {
+		return vector, nil
+	}
+
+	results := model.Vector{}
+
+	for _, item := range vector {
+		matched, err := qce.matchLabelPostFilters(item.Metric, postFilters)
+		if err != nil {
+			return nil, err
+		}
+
+		if matched {
+			results = append(results, item)
+		}
+	}
+
+	return results, nil
+}
+
+func (qce *QueryCollectionExecutor) filterMatrixByLabels(
+	matrix model.Matrix,
+	predicate *CollectionRequest,
+) (model.Matrix, error) {
+	postFilters := qce.getLabelPostFilters(predicate)
+	if len(postFilters) == 0 {
+		return matrix, nil
+	}
+
+	results := model.Matrix{}
+
+	for _, item := range matrix {
+		matched, err := qce.matchLabelPostFilters(item.Metric, postFilters)
+		if err != nil {
+			return nil, err
+		}
+
+		if matched {
+			results = append(results, item)
+		}
+	}
+
+	return results, nil
+}
+
+func (qce *QueryCollectionExecutor) matchLabelPostFilters(
+	metric model.Metric,
+	postFilters []*LabelExpression,
+) (bool, error) {
+	for _, labelExpr := range postFilters {
+		label := qce.Metric.Labels[labelExpr.Name]
+		labelValue := metric[model.LabelName(labelExpr.Name)]
+
+		for _, expr := range labelExpr.Expressions {
+			matched, err := matchTypedLabelValue(label, labelValue, expr, qce.Variables)
+			if err != nil {
+				return false, fmt.Errorf("%s: %w", labelExpr.Name, err)
+			}
+
+			if !matched {
+				return false, nil
+			}
+		}
+	}
+
+	return true, nil
+}
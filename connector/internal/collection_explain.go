@@ -3,12 +3,16 @@ package internal
 import (
 	"errors"
 	"fmt"
-	"strings"
+	"slices"
+	"time"
 
 	"github.com/go-viper/mapstructure/v2"
 	"github.com/hasura/ndc-prometheus/connector/metadata"
 	"github.com/hasura/ndc-sdk-go/schema"
 	"github.com/hasura/ndc-sdk-go/utils"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
 )
 
 // QueryCollectionExplainResult holds the result of collection group planning.
@@ -41,11 +45,24 @@ func (qcer QueryCollectionExplainResult) ToExplainResponse() (*schema.ExplainRes
 		result.Details[aggKey] = agg
 	}
 
+	if qcer.Request != nil && qcer.Request.AutoRange != nil {
+		result.Details["auto_range"] = qcer.Request.AutoRange.String()
+	}
+
 	if qcer.Groups != nil {
 		for key, agg := range qcer.Groups.AggregateQueries {
 			aggKey := fmt.Sprintf("group_aggregates[%s]", key)
 			result.Details[aggKey] = agg
 		}
+
+		if qcer.Request != nil && qcer.Request.TimestampBucket != nil &&
+			slices.Contains(qcer.Groups.Dimensions, metadata.TimestampKey) {
+			result.Details["timestamp_bucket"] = fmt.Sprintf(
+				"%s (%s)",
+				qcer.Request.TimestampBucket.Granularity,
+				qcer.Request.TimestampBucket.Location,
+			)
+		}
 	}
 
 	return result, nil
@@ -55,12 +72,22 @@ func (qcer QueryCollectionExplainResult) ToExplainResponse() (*schema.ExplainRes
 func (qce *QueryCollectionExecutor) Explain(
 	expressions *CollectionRequest,
 ) (*QueryCollectionExplainResult, error) {
-	collectionQuery := qce.MetricName
+	collectionQuery := qce.newCollectionSelector(nil)
+
 	result := &QueryCollectionExplainResult{
 		OK:      false,
 		Request: expressions,
 	}
 
+	// info series are label-only dimensions, so the latest labels are selected with an instant query.
+	if qce.Metric.Type == model.MetricTypeInfo && expressions != nil && expressions.Range != nil {
+		if !expressions.Range.End.IsZero() {
+			expressions.Timestamp = &expressions.Range.End
+		}
+
+		expressions.Range = nil
+	}
+
 	if expressions != nil {
 		query, ok, err := qce.buildCollectionPredicateQuery(expressions)
 		if err != nil {
@@ -76,6 +103,12 @@ func (qce *QueryCollectionExecutor) Explain(
 			return result, nil
 		}
 
+		if err := qce.validateLabelPostFilters(expressions); err != nil {
+			return nil, schema.UnprocessableContentError(err.Error(), map[string]any{
+				"collection": qce.Request.Collection,
+			})
+		}
+
 		collectionQuery = query
 	}
 
@@ -101,7 +134,7 @@ func (qce *QueryCollectionExecutor) Explain(
 		)
 	}
 
-	result.Groups, err = qce.explainGrouping(expressions.Groups, collectionQuery)
+	groupQueries, err := qce.explainGrouping(expressions.Groups, collectionQuery)
 	if err != nil {
 		return nil, schema.UnprocessableContentError(
 			"failed to evaluate grouping: "+err.Error(),
@@ -111,8 +144,10 @@ func (qce *QueryCollectionExecutor) Explain(
 		)
 	}
 
+	result.Groups = newGroupingExplainResult(expressions.Groups, groupQueries)
+
 	if result.Groups == nil && len(result.Aggregates) == 0 {
-		result.QueryString = collectionQuery
+		result.QueryString = collectionQuery.String()
 	}
 
 	return result, nil
@@ -186,7 +221,7 @@ func (qce *QueryCollectionExecutor) ExplainHistogramQuantile(
 		// histogram_quantile(
 		//     $scalar,
 		//     rate(hasura_graphql_execution_time_seconds_bucket{...}[$step])))
-		result.QueryString, err = qce.buildQueryStringByFunction(
+		query, err := qce.buildQueryStringByFunction(
 			expressions,
 			collectionQuery,
 			histogramQuantileFunc,
@@ -200,6 +235,8 @@ func (qce *QueryCollectionExecutor) ExplainHistogramQuantile(
 			)
 		}
 
+		result.QueryString = query.String()
+
 		return result, nil
 	}
 
@@ -214,15 +251,17 @@ func (qce *QueryCollectionExecutor) ExplainHistogramQuantile(
 func (qce *QueryCollectionExecutor) explainHistogramQuantileGrouping(
 	expressions *CollectionRequest,
 	result *QueryCollectionExplainResult,
-	collectionQuery string,
+	collectionQuery parser.Expr,
 	histogramQuantileFunc KeyValue,
 ) (*QueryCollectionExplainResult, error) {
-	var err error
 	// generate aggregate queries to groups,
 	// add the le bucket to grouping
-	expressions.Groups.Dimensions = append(expressions.Groups.Dimensions, "le")
+	expressions.Groups.Dimensions = append(
+		expressions.Groups.Dimensions,
+		metadata.HistogramBucketLabel,
+	)
 
-	result.Groups, err = qce.explainGrouping(expressions.Groups, collectionQuery)
+	groupQueries, err := qce.explainGrouping(expressions.Groups, collectionQuery)
 	if err != nil {
 		return nil, schema.UnprocessableContentError(
 			"failed to evaluate grouping: "+err.Error(),
@@ -233,7 +272,7 @@ func (qce *QueryCollectionExecutor) explainHistogramQuantileGrouping(
 	}
 
 	// Finally wrap aggregate queries with histogram_quantile
-	for groupKey, groupQuery := range result.Groups.AggregateQueries {
+	for groupKey, groupQuery := range groupQueries {
 		aggQuery, err := qce.buildQueryStringByFunction(
 			expressions,
 			groupQuery,
@@ -248,73 +287,85 @@ func (qce *QueryCollectionExecutor) explainHistogramQuantileGrouping(
 			)
 		}
 
-		result.Groups.AggregateQueries[groupKey] = aggQuery
+		groupQueries[groupKey] = aggQuery
 	}
 
+	result.Groups = newGroupingExplainResult(expressions.Groups, groupQueries)
+
 	return result, nil
 }
 
 func (qce *QueryCollectionExecutor) buildCollectionPredicateQuery(
 	predicate *CollectionRequest,
-) (string, bool, error) {
-	conditions := []string{}
+) (parser.Expr, bool, error) {
+	matchers := []*labels.Matcher{}
 
-	if len(predicate.LabelExpressions) > 0 {
+	// labels of the subquery are filtered after the query.
+	if len(predicate.LabelExpressions) > 0 && qce.Subquery == nil {
 		keys := utils.GetSortedKeys(predicate.LabelExpressions)
 
 		for _, key := range keys {
 			expr := predicate.LabelExpressions[key]
+			label := qce.Metric.Labels[key]
+
+			// float64 labels can't be compared in label matchers, so series are filtered after the query.
+			if label.GetType() == metadata.LabelTypeFloat64 {
+				continue
+			}
 
-			condition, ok, err := (&LabelExpressionBuilder{
+			labelMatchers, ok, err := (&LabelExpressionBuilder{
 				LabelExpression: *expr,
+				Label:           label,
 			}).Evaluate(qce.Variables)
 			if err != nil || !ok {
-				return "", ok, err
+				return nil, ok, err
 			}
 
-			conditions = append(conditions, condition)
+			matchers = append(matchers, labelMatchers...)
 		}
 	}
 
-	query := qce.MetricName
+	query := qce.newCollectionSelector(matchers)
 
-	if len(conditions) > 0 {
-		query = fmt.Sprintf("%s{%s}", qce.MetricName, strings.Join(conditions, ","))
+	if vs, ok := query.(*parser.VectorSelector); ok && predicate.Offset > 0 &&
+		!predicate.HasRangeVectorFunction() {
+		vs.OriginalOffset = predicate.Offset
 	}
 
-	if predicate.Offset > 0 && !predicate.HasRangeVectorFunction() {
-		query = fmt.Sprintf("%s offset %s", query, predicate.Offset.String())
+	return query, true, nil
+}
+
+// newCollectionSelector creates the base expression of the collection query.
+// The subquery is wrapped in parentheses, so it is evaluated before functions and operators.
+func (qce *QueryCollectionExecutor) newCollectionSelector(matchers []*labels.Matcher) parser.Expr {
+	if qce.Subquery != nil {
+		return &parser.ParenExpr{Expr: qce.Subquery}
 	}
 
-	return query, true, nil
+	return newMetricSelector(qce.MetricName, matchers)
 }
 
 func (qce *QueryCollectionExecutor) buildQueryString(
 	predicate *CollectionRequest,
-	query string,
-) (string, error) {
-	valueCondition, err := qce.evalValueComparisonCondition(predicate.Value)
-	if err != nil {
-		return "", err
-	}
+	query parser.Expr,
+) (parser.Expr, error) {
+	var err error
 
 	for _, fn := range predicate.Functions {
 		query, err = qce.buildQueryStringByFunction(predicate, query, fn)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 	}
 
-	query = fmt.Sprintf("%s%s", query, valueCondition)
-
-	return query, nil
+	return qce.evalValueComparisonCondition(query, predicate.Value)
 }
 
 func (qce *QueryCollectionExecutor) buildQueryStringByFunction( //nolint:gocognit,gocyclo,cyclop,funlen,maintidx
 	predicate *CollectionRequest,
-	query string,
+	query parser.Expr,
 	fn KeyValue,
-) (string, error) {
+) (parser.Expr, error) {
 	switch metadata.PromQLFunctionName(fn.Key) {
 	case metadata.Absolute,
 		metadata.Absent,
@@ -351,14 +402,37 @@ func (qce *QueryCollectionExecutor) buildQueryStringByFunction( //nolint:gocogni
 		metadata.Rad:
 		value, err := utils.DecodeNullableBoolean(fn.Value)
 		if err != nil {
-			return "", fmt.Errorf("%s: invalid value %v", fn.Key, fn.Value)
+			return nil, fmt.Errorf("%s: invalid value %v", fn.Key, fn.Value)
 		}
 
-		if value != nil && *value {
-			return fmt.Sprintf("%s(%s)", fn.Key, query), nil
+		if value == nil || !*value || isRedundantFunctionCall(fn.Key, query) {
+			return query, nil
 		}
 
-		return query, nil
+		return newFunctionCall(fn.Key, query), nil
+	case metadata.Minute,
+		metadata.Hour,
+		metadata.DayOfMonth,
+		metadata.DayOfWeek,
+		metadata.DayOfYear,
+		metadata.DaysInMonth,
+		metadata.Month,
+		metadata.Year:
+		value, err := utils.DecodeNullableBoolean(fn.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid value %v", fn.Key, fn.Value)
+		}
+
+		if value == nil || !*value {
+			return query, nil
+		}
+
+		start, end := predicate.getEvaluationRange()
+
+		return newFunctionCall(
+			fn.Key,
+			buildTimezoneShiftQuery(query, predicate.Location, start, end),
+		), nil
 	case metadata.Sum,
 		metadata.Avg,
 		metadata.Min,
@@ -369,49 +443,45 @@ func (qce *QueryCollectionExecutor) buildQueryStringByFunction( //nolint:gocogni
 		metadata.Group:
 		labels, err := utils.DecodeNullableStringSlice(fn.Value)
 		if err != nil {
-			return "", fmt.Errorf("%s: invalid value %v", fn.Key, fn.Value)
+			return nil, fmt.Errorf("%s: invalid value %v", fn.Key, fn.Value)
 		}
 
 		if labels == nil {
 			return query, nil
 		}
 
-		if len(*labels) == 0 {
-			return fmt.Sprintf("%s(%s)", fn.Key, query), nil
-		}
-
-		return fmt.Sprintf("%s by (%s) (%s)", fn.Key, strings.Join(*labels, ","), query), nil
+		return newAggregateExpr(fn.Key, query, nil, *labels)
 	case metadata.SortByLabel, metadata.SortByLabelDesc:
 		labels, err := utils.DecodeNullableStringSlice(fn.Value)
 		if err != nil {
-			return "", fmt.Errorf("%s: invalid value %v", fn.Key, fn.Value)
+			return nil, fmt.Errorf("%s: invalid value %v", fn.Key, fn.Value)
 		}
 
-		if labels != nil {
-			return fmt.Sprintf(
-				"%s(%s%s)",
-				fn.Key,
-				query,
-				buildPromQLParametersFromStringSlice(*labels),
-			), nil
+		if labels == nil {
+			return query, nil
 		}
 
-		return query, nil
+		args := []parser.Expr{query}
+		for _, label := range *labels {
+			args = append(args, &parser.StringLiteral{Val: label})
+		}
+
+		return newFunctionCall(fn.Key, args...), nil
 	case metadata.BottomK, metadata.TopK, metadata.LimitK:
 		k, err := utils.DecodeNullableInt[int64](fn.Value)
 		if err != nil {
-			return "", fmt.Errorf("%s: invalid int64 value %v", fn.Key, fn.Value)
+			return nil, fmt.Errorf("%s: invalid int64 value %v", fn.Key, fn.Value)
 		}
 
-		if k != nil {
-			return fmt.Sprintf("%s(%d, %s)", fn.Key, *k, query), nil
+		if k == nil {
+			return query, nil
 		}
 
-		return query, nil
+		return newAggregateExpr(fn.Key, query, &parser.NumberLiteral{Val: float64(*k)}, nil)
 	case metadata.Quantile, metadata.LimitRatio, metadata.HistogramQuantile:
 		n, err := utils.DecodeNullableFloat[float64](fn.Value)
 		if err != nil {
-			return "", fmt.Errorf("%s: invalid float64 value %v", fn.Key, fn.Value)
+			return nil, fmt.Errorf("%s: invalid float64 value %v", fn.Key, fn.Value)
 		}
 
 		if n == nil {
@@ -419,25 +489,31 @@ func (qce *QueryCollectionExecutor) buildQueryStringByFunction( //nolint:gocogni
 		}
 
 		if *n < 0 || *n > 1 {
-			return "", fmt.Errorf(
+			return nil, fmt.Errorf(
 				"%s: value should be between 0 and 1, got %v",
 				fn.Key,
 				fn.Value,
 			)
 		}
 
-		return fmt.Sprintf("%s(%f, %s)", fn.Key, *n, query), nil
-	case metadata.Round:
+		param := &parser.NumberLiteral{Val: *n}
+
+		if fn.Key == string(metadata.HistogramQuantile) {
+			return newFunctionCall(fn.Key, param, query), nil
+		}
+
+		return newAggregateExpr(fn.Key, query, param, nil)
+	case metadata.Round, metadata.ClampMax, metadata.ClampMin:
 		n, err := utils.DecodeNullableFloat[float64](fn.Value)
 		if err != nil {
-			return "", fmt.Errorf("%s: invalid float64 value %v", fn.Key, fn.Value)
+			return nil, fmt.Errorf("%s: invalid float64 value %v", fn.Key, fn.Value)
 		}
 
-		if n != nil {
-			return fmt.Sprintf("%s(%s, %f)", fn.Key, query, *n), nil
+		if n == nil {
+			return query, nil
 		}
 
-		return query, nil
+		return newFunctionCall(fn.Key, query, &parser.NumberLiteral{Val: *n}), nil
 	case metadata.Clamp:
 		if utils.IsNil(fn.Value) {
 			return query, nil
@@ -446,15 +522,14 @@ func (qce *QueryCollectionExecutor) buildQueryStringByFunction( //nolint:gocogni
 		var boundaryInput ValueBoundaryInput
 
 		if err := mapstructure.Decode(fn.Value, &boundaryInput); err != nil {
-			return "", fmt.Errorf("%s: invalid clamp input %w", fn.Key, err)
+			return nil, fmt.Errorf("%s: invalid clamp input %w", fn.Key, err)
 		}
 
-		return fmt.Sprintf(
-			"%s(%s, %f, %f)",
+		return newFunctionCall(
 			fn.Key,
 			query,
-			boundaryInput.Min,
-			boundaryInput.Max,
+			&parser.NumberLiteral{Val: boundaryInput.Min},
+			&parser.NumberLiteral{Val: boundaryInput.Max},
 		), nil
 	case metadata.HistogramFraction:
 		if utils.IsNil(fn.Value) {
@@ -464,14 +539,19 @@ func (qce *QueryCollectionExecutor) buildQueryStringByFunction( //nolint:gocogni
 		var boundary ValueBoundaryInput
 
 		if err := mapstructure.Decode(fn.Value, &boundary); err != nil {
-			return "", fmt.Errorf(
+			return nil, fmt.Errorf(
 				"%s: invalid histogram_fraction input %w",
 				fn.Key,
 				err,
 			)
 		}
 
-		return fmt.Sprintf("%s(%f, %f, %s)", fn.Key, boundary.Min, boundary.Max, query), nil
+		return newFunctionCall(
+			fn.Key,
+			&parser.NumberLiteral{Val: boundary.Min},
+			&parser.NumberLiteral{Val: boundary.Max},
+			query,
+		), nil
 	case metadata.HoltWinters:
 		if utils.IsNil(fn.Value) {
 			return query, nil
@@ -480,16 +560,16 @@ func (qce *QueryCollectionExecutor) buildQueryStringByFunction( //nolint:gocogni
 		var hw HoltWintersInput
 
 		if err := hw.FromValue(fn.Value, qce.Runtime.UnixTimeUnit); err != nil {
-			return "", fmt.Errorf("%s: %w", fn.Key, err)
+			return nil, fmt.Errorf("%s: %w", fn.Key, err)
 		}
 
-		return fmt.Sprintf(
-			"%s(%s[%s], %f, %f)",
+		qce.resolveAutoRange(predicate, &hw.Range)
+
+		return newFunctionCall(
 			fn.Key,
-			query,
-			hw.Range.String(),
-			hw.Sf,
-			hw.Tf,
+			newRangeVectorExpr(query, hw.Range, 0),
+			&parser.NumberLiteral{Val: hw.Sf},
+			&parser.NumberLiteral{Val: hw.Tf},
 		), nil
 	case metadata.PredictLinear:
 		if utils.IsNil(fn.Value) {
@@ -499,10 +579,16 @@ func (qce *QueryCollectionExecutor) buildQueryStringByFunction( //nolint:gocogni
 		var pli PredictLinearInput
 
 		if err := pli.FromValue(fn.Value, qce.Runtime.UnixTimeUnit); err != nil {
-			return "", fmt.Errorf("%s: %w", fn.Key, err)
+			return nil, fmt.Errorf("%s: %w", fn.Key, err)
 		}
 
-		return fmt.Sprintf("%s(%s[%s], %f)", fn.Key, query, pli.Range.String(), pli.T), nil
+		qce.resolveAutoRange(predicate, &pli.Range)
+
+		return newFunctionCall(
+			fn.Key,
+			newRangeVectorExpr(query, pli.Range, 0),
+			&parser.NumberLiteral{Val: pli.T},
+		), nil
 	case metadata.QuantileOverTime:
 		if utils.IsNil(fn.Value) {
 			return query, nil
@@ -511,10 +597,16 @@ func (qce *QueryCollectionExecutor) buildQueryStringByFunction( //nolint:gocogni
 		var q QuantileOverTimeInput
 
 		if err := q.FromValue(fn.Value, qce.Runtime.UnixTimeUnit); err != nil {
-			return "", fmt.Errorf("%s: %w", fn.Key, err)
+			return nil, fmt.Errorf("%s: %w", fn.Key, err)
 		}
 
-		return fmt.Sprintf("%s(%f, %s[%s])", fn.Key, q.Quantile, query, q.Range.String()), nil
+		qce.resolveAutoRange(predicate, &q.Range)
+
+		return newFunctionCall(
+			fn.Key,
+			&parser.NumberLiteral{Val: q.Quantile},
+			newRangeVectorExpr(query, q.Range, 0),
+		), nil
 	case metadata.LabelJoin:
 		if utils.IsNil(fn.Value) {
 			return query, nil
@@ -523,21 +615,31 @@ func (qce *QueryCollectionExecutor) buildQueryStringByFunction( //nolint:gocogni
 		var input LabelJoinInput
 
 		if err := mapstructure.Decode(fn.Value, &input); err != nil {
-			return "", fmt.Errorf("%s: invalid label_join input %w", fn.Key, err)
+			return nil, fmt.Errorf("%s: invalid label_join input %w", fn.Key, err)
 		}
 
 		if input.DestLabel == "" {
-			return "", fmt.Errorf("%s: the dest_label must not be empty", fn.Key)
+			return nil, fmt.Errorf("%s: the dest_label must not be empty", fn.Key)
 		}
 
 		if len(input.SourceLabels) == 0 {
-			return "", fmt.Errorf(
+			return nil, fmt.Errorf(
 				"%s: the source_labels array must have at least 1 item",
 				fn.Key,
 			)
 		}
 
-		return fmt.Sprintf(`%s(%s, %s)`, fn.Key, query, input.String()), nil
+		args := []parser.Expr{
+			query,
+			&parser.StringLiteral{Val: input.DestLabel},
+			&parser.StringLiteral{Val: input.Separator},
+		}
+
+		for _, label := range input.SourceLabels {
+			args = append(args, &parser.StringLiteral{Val: label})
+		}
+
+		return newFunctionCall(fn.Key, args...), nil
 	case metadata.LabelReplace:
 		if utils.IsNil(fn.Value) {
 			return query, nil
@@ -546,36 +648,32 @@ func (qce *QueryCollectionExecutor) buildQueryStringByFunction( //nolint:gocogni
 		var input LabelReplaceInput
 
 		if err := mapstructure.Decode(fn.Value, &input); err != nil {
-			return "", fmt.Errorf("%s: invalid label_join input %w", fn.Key, err)
+			return nil, fmt.Errorf("%s: invalid label_join input %w", fn.Key, err)
 		}
 
 		if input.DestLabel == "" {
-			return "", fmt.Errorf("%s: the dest_label must not be empty", fn.Key)
+			return nil, fmt.Errorf("%s: the dest_label must not be empty", fn.Key)
 		}
 
-		return fmt.Sprintf(`%s(%s, %s)`, fn.Key, query, input.String()), nil
-	case metadata.ClampMax, metadata.ClampMin:
-		n, err := utils.DecodeNullableFloat[float64](fn.Value)
-		if err != nil {
-			return "", fmt.Errorf("%s: invalid float64 value %v", fn.Key, fn.Value)
-		}
-
-		if n != nil {
-			return fmt.Sprintf("%s(%s, %f)", fn.Key, query, *n), nil
-		}
-
-		return query, nil
+		return newFunctionCall(
+			fn.Key,
+			query,
+			&parser.StringLiteral{Val: input.DestLabel},
+			&parser.StringLiteral{Val: input.Replacement},
+			&parser.StringLiteral{Val: input.SourceLabel},
+			&parser.StringLiteral{Val: input.Regex},
+		), nil
 	case metadata.CountValues:
 		label, err := utils.DecodeNullableString(fn.Value)
 		if err != nil {
-			return "", fmt.Errorf("%s: invalid value %v", fn.Key, fn.Value)
+			return nil, fmt.Errorf("%s: invalid value %v", fn.Key, fn.Value)
 		}
 
-		if label != nil {
-			return fmt.Sprintf(`%s("%s", %s)`, fn.Key, *label, query), nil
+		if label == nil {
+			return query, nil
 		}
 
-		return query, nil
+		return newAggregateExpr(fn.Key, query, &parser.StringLiteral{Val: *label}, nil)
 	case metadata.AbsentOverTime,
 		metadata.Changes,
 		metadata.Derivative,
@@ -597,64 +695,85 @@ func (qce *QueryCollectionExecutor) buildQueryStringByFunction( //nolint:gocogni
 		metadata.PresentOverTime:
 		rng, err := qce.Runtime.ParseRangeResolution(fn.Value)
 		if err != nil {
-			return "", fmt.Errorf("%s: %w", fn.Key, err)
+			return nil, fmt.Errorf("%s: %w", fn.Key, err)
 		}
 
-		if rng != nil {
-			var sb strings.Builder
-
-			_, _ = sb.WriteString(fn.Key)
-			_, _ = sb.WriteRune('(')
-			_, _ = sb.WriteString(query)
-			_, _ = sb.WriteRune('[')
-			_, _ = sb.WriteString(rng.String())
-			_, _ = sb.WriteRune(']')
-
-			if predicate.Offset > 0 && !predicate.OffsetUsed {
-				predicate.OffsetUsed = true
+		if rng == nil {
+			return query, nil
+		}
 
-				_, _ = sb.WriteString(" offset ")
-				_, _ = sb.WriteString(predicate.Offset.String())
-			}
+		qce.resolveAutoRange(predicate, rng)
 
-			_, _ = sb.WriteRune(')')
+		var offset time.Duration
 
-			return sb.String(), nil
+		if predicate.Offset > 0 && !predicate.OffsetUsed {
+			predicate.OffsetUsed = true
+			offset = predicate.Offset
 		}
 
-		return query, nil
+		return newFunctionCall(fn.Key, newRangeVectorExpr(query, *rng, offset)), nil
 	default:
-		return "", fmt.Errorf("unsupported promQL function name `%s`", fn.Key)
+		return nil, fmt.Errorf("unsupported promQL function name `%s`", fn.Key)
 	}
 }
 
+// resolveAutoRange resolves the auto range of range functions from the step of the request
+// and the scrape interval of the metric, or the runtime setting if the metric doesn't have it.
+func (qce *QueryCollectionExecutor) resolveAutoRange(
+	predicate *CollectionRequest,
+	rng *metadata.RangeResolution,
+) {
+	if !rng.Auto {
+		return
+	}
+
+	scrapeInterval := qce.Runtime.GetScrapeInterval()
+	if qce.Metric.ScrapeInterval != nil && *qce.Metric.ScrapeInterval > 0 {
+		scrapeInterval = time.Duration(*qce.Metric.ScrapeInterval)
+	}
+
+	var step time.Duration
+
+	if predicate.Range != nil {
+		step = predicate.Range.Step
+	}
+
+	predicate.AutoRange = &AutoRange{
+		Range:          metadata.EvalRateInterval(step, scrapeInterval),
+		Step:           step,
+		ScrapeInterval: scrapeInterval,
+	}
+	rng.Range = model.Duration(predicate.AutoRange.Range)
+}
+
 func (qce *QueryCollectionExecutor) evalValueComparisonCondition(
+	query parser.Expr,
 	operator *schema.ExpressionBinaryComparisonOperator,
-) (string, error) {
+) (parser.Expr, error) {
 	if operator == nil {
-		return "", nil
+		return query, nil
 	}
 
 	v, err := getComparisonValueFloat64(operator.Value, qce.Variables)
 	if err != nil {
-		return "", fmt.Errorf("invalid value expression: %w", err)
+		return nil, fmt.Errorf("invalid value expression: %w", err)
 	}
 
 	if v == nil {
-		return "", nil
+		return query, nil
 	}
 
 	op, ok := valueBinaryOperators[operator.Operator]
 	if !ok {
-		return "", fmt.Errorf("value: unsupported comparison operator `%s`", operator)
+		return nil, fmt.Errorf("value: unsupported comparison operator `%s`", operator)
 	}
 
-	return fmt.Sprintf(" %s %f", op, *v), nil
+	return newValueComparisonExpr(op, query, *v), nil
 }
 
 func (qce *QueryCollectionExecutor) explainAggregates(
 	aggregates schema.QueryAggregates,
-	query string,
+	query parser.Expr,
 ) (map[string]string, error) {
 	if len(aggregates) == 0 {
 		return map[string]string{}, nil
@@ -663,12 +782,12 @@ func (qce *QueryCollectionExecutor) explainAggregates(
 	result := map[string]string{}
 
 	for key, aggregate := range aggregates {
-		aggQuery, err := qce.explainGroupingAggregateQuery(query, []string{}, aggregate)
+		aggQuery, err := qce.explainGroupingAggregateQuery(query, nil, aggregate)
 		if err != nil {
 			return nil, fmt.Errorf("%s: %w", key, err)
 		}
 
-		result[key] = aggQuery
+		result[key] = aggQuery.String()
 	}
 
 	return result, nil
@@ -676,16 +795,13 @@ func (qce *QueryCollectionExecutor) explainAggregates(
 
 func (qce *QueryCollectionExecutor) explainGrouping(
 	groups *Grouping,
-	query string,
-) (*QueryCollectionGroupingExplainResult, error) {
+	query parser.Expr,
+) (map[string]parser.Expr, error) {
 	if groups == nil {
 		return nil, nil
 	}
 
-	result := &QueryCollectionGroupingExplainResult{
-		Dimensions:       groups.Dimensions,
-		AggregateQueries: make(map[string]string),
-	}
+	result := make(map[string]parser.Expr)
 
 	for key, aggregate := range groups.Aggregates {
 		aggQuery, err := qce.explainGroupingAggregateQuery(query, groups.Dimensions, aggregate)
@@ -693,71 +809,64 @@ func (qce *QueryCollectionExecutor) explainGrouping(
 			return nil, fmt.Errorf("%s: %w", key, err)
 		}
 
-		result.AggregateQueries[key] = aggQuery
+		result[key] = aggQuery
 	}
 
 	return result, nil
 }
 
 func (qce *QueryCollectionExecutor) explainGroupingAggregateQuery(
-	query string,
+	query parser.Expr,
 	dimensions []string,
 	aggregate schema.Aggregate,
-) (string, error) {
+) (parser.Expr, error) {
 	aggregateT, err := aggregate.InterfaceT()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	switch agg := aggregateT.(type) {
 	case *schema.AggregateStarCount:
-		var sb strings.Builder
-
-		sb.WriteString("count")
-
-		if len(dimensions) > 0 {
-			sb.WriteString(" by (")
-			sb.WriteString(strings.Join(dimensions, ", "))
-			sb.WriteString(") ")
-		}
-
-		sb.WriteRune('(')
-		sb.WriteString(query)
-		sb.WriteRune(')')
-
-		return sb.String(), nil
+		return newAggregateExpr(string(metadata.Count), query, nil, dimensions)
 	case *schema.AggregateColumnCount:
 		if agg.Column == metadata.ValueKey {
-			return fmt.Sprintf("count(%s)", query), nil
+			return newAggregateExpr(string(metadata.Count), query, nil, nil)
 		}
 
-		return fmt.Sprintf("count by (%s) (%s)", agg.Column, query), nil
+		return newAggregateExpr(string(metadata.Count), query, nil, []string{agg.Column})
 	case *schema.AggregateSingleColumn:
 		switch agg.Function {
 		case string(metadata.Sum), string(metadata.Min), string(metadata.Max), string(metadata.Avg), string(metadata.Stddev), string(metadata.Stdvar):
 			if agg.Column != metadata.ValueKey {
-				return "", errors.New("support aggregation for the `value` column only")
-			}
-
-			var sb strings.Builder
-
-			sb.WriteString(agg.Function)
-
-			if len(dimensions) > 0 {
-				sb.WriteString(" by (")
-				sb.WriteString(strings.Join(dimensions, ", "))
-				sb.WriteString(") ")
+				return nil, errors.New("support aggregation for the `value` column only")
 			}
 
-			sb.WriteRune('(')
-			sb.WriteString(query)
-			sb.WriteRune(')')
-
-			return sb.String(), nil
+			return newAggregateExpr(agg.Function, query, nil, dimensions)
 		default:
-			return "", fmt.Errorf("unsupported aggregate function: %s", agg.Function)
+			return nil, fmt.Errorf("unsupported aggregate function: %s", agg.Function)
 		}
 	default:
-		return "", fmt.Errorf("unsupported aggregate type: %s", agg.Type())
+		return nil, fmt.Errorf("unsupported aggregate type: %s", agg.Type())
+	}
+}
+
+// newGroupingExplainResult prints aggregate queries of the grouping.
+func newGroupingExplainResult(
+	groups *Grouping,
+	queries map[string]parser.Expr,
+) *QueryCollectionGroupingExplainResult {
+	if groups == nil {
+		return nil
+	}
+
+	result := &QueryCollectionGroupingExplainResult{
+		Dimensions:       groups.Dimensions,
+		AggregateQueries: make(map[string]string, len(queries)),
+	}
+
+	for key, query := range queries {
+		result.AggregateQueries[key] = query.String()
 	}
+
+	return result
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/hasura/ndc-sdk-go/schema"
 	"github.com/hasura/ndc-sdk-go/utils"
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
 	"gotest.tools/v3/assert"
 )
 
@@ -22,6 +23,8 @@ var testCases = []struct {
 	Groups      *QueryCollectionGroupingExplainResult
 	Aggregates  map[string]string
 	Functions   []KeyValue
+	Labels      map[string]metadata.LabelInfo
+	MetricType  model.MetricType
 }{
 	{
 		Name: "nested_expressions",
@@ -90,7 +93,151 @@ var testCases = []struct {
 				{Key: "limitk", Value: 2},
 			},
 		},
-		QueryString: `limitk(2, sort_by_label_desc(abs(max(sum by (job) (go_gc_duration_seconds{instance=~"localhost:9090|node-exporter:9100",job="node"} offset 5m0s))), "job")) >= 0.000000`,
+		QueryString: `limitk(2, sort_by_label_desc(abs(max(sum by (job) (go_gc_duration_seconds{instance=~"localhost:9090|node-exporter:9100",job="node"} offset 5m))), "job")) >= 0`,
+		Aggregates:  map[string]string{},
+	},
+	{
+		Name: "time_functions_timezone",
+		Request: schema.QueryRequest{
+			Collection: "go_gc_duration_seconds",
+			Arguments: schema.QueryRequestArguments{
+				"step":     schema.NewArgumentLiteral("1h").Encode(),
+				"timezone": schema.NewArgumentLiteral("Europe/Berlin").Encode(),
+				"fn": schema.NewArgumentLiteral([]map[string]any{
+					{"timestamp": true},
+					{"hour": true},
+				}).Encode(),
+			},
+			Query: schema.Query{
+				Predicate: schema.NewExpressionAnd(
+					schema.NewExpressionBinaryComparisonOperator(*schema.NewComparisonTargetColumn("timestamp"), "_lt", schema.NewComparisonValueScalar("2024-10-28T00:00:00Z")),
+					schema.NewExpressionBinaryComparisonOperator(*schema.NewComparisonTargetColumn("timestamp"), "_gt", schema.NewComparisonValueScalar("2024-10-26T00:00:00Z")),
+				).Encode(),
+			},
+		},
+		Predicate: CollectionRequest{
+			CollectionValidatedArguments: CollectionValidatedArguments{
+				Range: &v1.Range{
+					Start: time.Date(2024, 10, 26, 0, 0, 0, 0, time.UTC),
+					End:   time.Date(2024, 10, 28, 0, 0, 0, 0, time.UTC),
+					Step:  time.Hour,
+				},
+			},
+		},
+		QueryString: `hour(timestamp(go_gc_duration_seconds) + (7200 + (-3600) * (timestamp(go_gc_duration_seconds) >= bool 1729990800)))`,
+		Aggregates:  map[string]string{},
+	},
+	{
+		Name: "label_set_expressions",
+		Request: schema.QueryRequest{
+			Collection: "go_gc_duration_seconds",
+			Arguments: schema.QueryRequestArguments{
+				"step": schema.NewArgumentLiteral("1h").Encode(),
+			},
+			Query: schema.Query{
+				Predicate: schema.NewExpressionAnd(
+					schema.NewExpressionBinaryComparisonOperator(*schema.NewComparisonTargetColumn("timestamp"), "_lt", schema.NewComparisonValueScalar("2024-09-11T00:00:00Z")),
+					schema.NewExpressionBinaryComparisonOperator(*schema.NewComparisonTargetColumn("timestamp"), "_gt", schema.NewComparisonValueScalar("2024-09-10T00:00:00Z")),
+					schema.NewExpressionBinaryComparisonOperator(*schema.NewComparisonTargetColumn("labels"), "_contains_key", schema.NewComparisonValueScalar("env")),
+					schema.NewExpressionBinaryComparisonOperator(*schema.NewComparisonTargetColumn("labels"), "_eq", schema.NewComparisonValueScalar(map[string]any{"job": "node"})),
+					schema.NewExpressionBinaryComparisonOperator(*schema.NewComparisonTargetColumn("labels"), "_regex", schema.NewComparisonValueScalar(map[string]any{"instance": "node.*"})),
+					schema.NewExpressionBinaryComparisonOperator(*schema.NewComparisonTargetColumn("labels"), "_nregex", schema.NewComparisonValueScalar(map[string]any{"pod": "test.*"})),
+					schema.NewExpressionBinaryComparisonOperator(*schema.NewComparisonTargetColumn("job"), "_in", schema.NewComparisonValueScalar([]string{"node", "prometheus"})),
+				).Encode(),
+			},
+		},
+		Predicate: CollectionRequest{
+			CollectionValidatedArguments: CollectionValidatedArguments{
+				Range: &v1.Range{
+					Start: time.Date(2024, 9, 10, 0, 0, 0, 0, time.UTC),
+					End:   time.Date(2024, 9, 11, 0, 0, 0, 0, time.UTC),
+					Step:  time.Hour,
+				},
+			},
+		},
+		QueryString: `go_gc_duration_seconds{env!="",instance=~"node.*",job="node",pod!~"test.*"}`,
+		Aggregates:  map[string]string{},
+	},
+	{
+		Name: "typed_labels",
+		Request: schema.QueryRequest{
+			Collection: "http_requests_total",
+			Query: schema.Query{
+				Predicate: schema.NewExpressionAnd(
+					schema.NewExpressionBinaryComparisonOperator(*schema.NewComparisonTargetColumn("code"), "_gte", schema.NewComparisonValueScalar(500)),
+					schema.NewExpressionBinaryComparisonOperator(*schema.NewComparisonTargetColumn("code"), "_lt", schema.NewComparisonValueScalar(600)),
+					schema.NewExpressionBinaryComparisonOperator(*schema.NewComparisonTargetColumn("code"), "_neq", schema.NewComparisonValueScalar(503)),
+					schema.NewExpressionBinaryComparisonOperator(*schema.NewComparisonTargetColumn("method"), "_in", schema.NewComparisonValueScalar([]string{"GET", "POST"})),
+					schema.NewExpressionBinaryComparisonOperator(*schema.NewComparisonTargetColumn("replica"), "_eq", schema.NewComparisonValueScalar(true)),
+					schema.NewExpressionBinaryComparisonOperator(*schema.NewComparisonTargetColumn("le"), "_gte", schema.NewComparisonValueScalar(0.5)),
+				).Encode(),
+			},
+		},
+		Labels: map[string]metadata.LabelInfo{
+			"code":    {Type: metadata.LabelTypeInt64},
+			"method":  {Type: metadata.LabelTypeEnum, Values: []string{"GET", "POST", "PUT"}},
+			"replica": {Type: metadata.LabelTypeBoolean},
+			"le":      {Type: metadata.LabelTypeFloat64},
+		},
+		QueryString: `http_requests_total{code!="503",code=~"(5[0-9]{2})",method=~"GET|POST",replica="true"}`,
+		Aggregates:  map[string]string{},
+	},
+	{
+		Name: "typed_labels_invalid_enum",
+		Request: schema.QueryRequest{
+			Collection: "http_requests_total",
+			Query: schema.Query{
+				Predicate: schema.NewExpressionBinaryComparisonOperator(*schema.NewComparisonTargetColumn("method"), "_eq", schema.NewComparisonValueScalar("DELETE")).Encode(),
+			},
+		},
+		Labels: map[string]metadata.LabelInfo{
+			"code":    {Type: metadata.LabelTypeInt64},
+			"method":  {Type: metadata.LabelTypeEnum, Values: []string{"GET", "POST", "PUT"}},
+			"replica": {Type: metadata.LabelTypeBoolean},
+			"le":      {Type: metadata.LabelTypeFloat64},
+		},
+		ErrorMsg: "invalid enum value",
+	},
+	{
+		Name: "typed_labels_float_aggregate",
+		Request: schema.QueryRequest{
+			Collection: "http_requests_total",
+			Arguments: schema.QueryRequestArguments{
+				"fn": schema.NewArgumentLiteral([]map[string]any{
+					{"sum": []string{"code"}},
+				}).Encode(),
+			},
+			Query: schema.Query{
+				Predicate: schema.NewExpressionBinaryComparisonOperator(*schema.NewComparisonTargetColumn("le"), "_lte", schema.NewComparisonValueScalar(1)).Encode(),
+			},
+		},
+		Labels: map[string]metadata.LabelInfo{
+			"code":    {Type: metadata.LabelTypeInt64},
+			"method":  {Type: metadata.LabelTypeEnum, Values: []string{"GET", "POST", "PUT"}},
+			"replica": {Type: metadata.LabelTypeBoolean},
+			"le":      {Type: metadata.LabelTypeFloat64},
+		},
+		ErrorMsg: "the float64 label le can't be filtered with the sum function",
+	},
+	{
+		Name:       "info_instant",
+		MetricType: model.MetricTypeInfo,
+		Request: schema.QueryRequest{
+			Collection: "build_info",
+			Query: schema.Query{
+				Predicate: schema.NewExpressionAnd(
+					schema.NewExpressionBinaryComparisonOperator(*schema.NewComparisonTargetColumn("timestamp"), "_lt", schema.NewComparisonValueScalar("2024-09-11T00:00:00Z")),
+					schema.NewExpressionBinaryComparisonOperator(*schema.NewComparisonTargetColumn("timestamp"), "_gt", schema.NewComparisonValueScalar("2024-09-10T00:00:00Z")),
+					schema.NewExpressionBinaryComparisonOperator(*schema.NewComparisonTargetColumn("version"), "_eq", schema.NewComparisonValueScalar("3.0.0")),
+				).Encode(),
+			},
+		},
+		Predicate: CollectionRequest{
+			CollectionValidatedArguments: CollectionValidatedArguments{
+				Timestamp: utils.ToPtr(time.Date(2024, 9, 11, 0, 0, 0, 0, time.UTC)),
+			},
+		},
+		QueryString: `build_info{version="3.0.0"}`,
 		Aggregates:  map[string]string{},
 	},
 	{
@@ -550,7 +697,7 @@ var testCases = []struct {
 				}},
 			},
 		},
-		QueryString: `histogram_fraction(0.100000, 0.200000, clamp(round(quantile(0.100000, go_gc_duration_seconds offset 5m0s), 0.200000), 1.000000, 2.000000))`,
+		QueryString: `histogram_fraction(0.1, 0.2, clamp(round(quantile(0.1, go_gc_duration_seconds offset 5m), 0.2), 1, 2))`,
 		Aggregates:  map[string]string{},
 	},
 	{
@@ -580,7 +727,7 @@ var testCases = []struct {
 				}},
 			},
 		},
-		QueryString: `holt_winters(go_gc_duration_seconds[1m], 0.100000, 0.200000)`,
+		QueryString: `holt_winters(go_gc_duration_seconds[1m], 0.1, 0.2)`,
 		Aggregates:  map[string]string{},
 	},
 	{
@@ -608,7 +755,7 @@ var testCases = []struct {
 				}},
 			},
 		},
-		QueryString: `predict_linear(go_gc_duration_seconds[1m], 0.100000)`,
+		QueryString: `predict_linear(go_gc_duration_seconds[1m], 0.1)`,
 		Aggregates:  map[string]string{},
 	},
 	{
@@ -648,7 +795,7 @@ var testCases = []struct {
 				}},
 			},
 		},
-		QueryString: `label_join(quantile_over_time(0.100000, go_gc_duration_seconds[1m]), "dest", "-", "job", "instance")`,
+		QueryString: `label_join(quantile_over_time(0.1, go_gc_duration_seconds[1m]), "dest", "-", "job", "instance")`,
 		Aggregates:  map[string]string{},
 	},
 	{
@@ -702,7 +849,7 @@ var testCases = []struct {
 				{Key: "clamp_max", Value: 1},
 			},
 		},
-		QueryString: `clamp_max(go_gc_duration_seconds, 1.000000)`,
+		QueryString: `clamp_max(go_gc_duration_seconds, 1)`,
 		Aggregates:  map[string]string{},
 	},
 	{
@@ -972,6 +1119,10 @@ func TestCollectionQueryExplain(t *testing.T) {
 				Variables:  map[string]any{},
 				Arguments:  arguments,
 				Runtime:    &metadata.RuntimeSettings{},
+				Metric: metadata.MetricInfo{
+					Labels: tc.Labels,
+					Type:   tc.MetricType,
+				},
 			}
 
 			validatedRequest, err := EvalCollectionRequest(&tc.Request, arguments, executor.Variables, executor.Runtime)
@@ -1032,7 +1183,7 @@ func TestCollectionQueryExplainHistogramQuantile(t *testing.T) {
 					).Encode(),
 				},
 			},
-			QueryString: `histogram_quantile(0.900000, rate(hasura_graphql_execution_time_seconds_bucket{instance=~"localhost:9090|node-exporter:9100",job="node"}[5m] offset 5m0s) >= 0.000000)`,
+			QueryString: `histogram_quantile(0.9, rate(hasura_graphql_execution_time_seconds_bucket{instance=~"localhost:9090|node-exporter:9100",job="node"}[5m] offset 5m) >= 0)`,
 		},
 		{
 			Name: "histogram_quantile_sum",
@@ -1066,7 +1217,7 @@ func TestCollectionQueryExplainHistogramQuantile(t *testing.T) {
 			Groups: &QueryCollectionGroupingExplainResult{
 				Dimensions: []string{"job", "instance", "le"},
 				AggregateQueries: map[string]string{
-					"sum": `histogram_quantile(0.950000, sum by (job, instance, le) (rate(hasura_graphql_execution_time_seconds_bucket{instance=~"localhost:9090|node-exporter:9100",job="node"}[5m] offset 5m0s) >= 0.000000))`,
+					"sum": `histogram_quantile(0.95, sum by (job, instance, le) (rate(hasura_graphql_execution_time_seconds_bucket{instance=~"localhost:9090|node-exporter:9100",job="node"}[5m] offset 5m) >= 0))`,
 				},
 			},
 		},
@@ -1110,3 +1261,87 @@ func TestCollectionQueryExplainHistogramQuantile(t *testing.T) {
 		})
 	}
 }
+
+func TestQueryCollectionExplainAutoRange(t *testing.T) {
+	testCases := []struct {
+		Name           string
+		Functions      []map[string]any
+		Predicate      schema.ExpressionEncoder
+		ScrapeInterval *model.Duration
+		QueryString    string
+		AutoRange      string
+	}{
+		{
+			Name:        "instant",
+			Functions:   []map[string]any{{"rate": "auto"}},
+			QueryString: `rate(http_requests_total[1m])`,
+			AutoRange:   "1m (step 0s, scrape interval 15s)",
+		},
+		{
+			Name:      "range",
+			Functions: []map[string]any{{"increase": "auto"}, {"sum": []string{"job"}}},
+			Predicate: schema.NewExpressionAnd(
+				schema.NewExpressionBinaryComparisonOperator(*schema.NewComparisonTargetColumn("timestamp"), "_lt", schema.NewComparisonValueScalar("2024-09-11T00:00:00Z")),
+				schema.NewExpressionBinaryComparisonOperator(*schema.NewComparisonTargetColumn("timestamp"), "_gt", schema.NewComparisonValueScalar("2024-09-10T00:00:00Z")),
+			),
+			ScrapeInterval: utils.ToPtr(model.Duration(30 * time.Second)),
+			QueryString:    `sum by (job) (increase(http_requests_total[5m30s]))`,
+			AutoRange:      "5m30s (step 5m, scrape interval 30s)",
+		},
+		{
+			Name:        "predict_linear",
+			Functions:   []map[string]any{{"predict_linear": map[string]any{"t": 3600, "range": "auto"}}},
+			QueryString: `predict_linear(http_requests_total[1m], 3600)`,
+			AutoRange:   "1m (step 0s, scrape interval 15s)",
+		},
+		{
+			Name:        "explicit_range",
+			Functions:   []map[string]any{{"rate": "5m"}},
+			QueryString: `rate(http_requests_total[5m])`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			request := schema.QueryRequest{
+				Collection: "http_requests_total",
+				Arguments: schema.QueryRequestArguments{
+					"fn": schema.NewArgumentLiteral(tc.Functions).Encode(),
+				},
+			}
+
+			if tc.Predicate != nil {
+				request.Query.Predicate = tc.Predicate.Encode()
+			}
+
+			arguments, err := utils.ResolveArgumentVariables(request.Arguments, map[string]any{})
+			assert.NilError(t, err)
+
+			executor := &QueryCollectionExecutor{
+				Request:    &request,
+				MetricName: request.Collection,
+				Metric: metadata.MetricInfo{
+					Type:           model.MetricTypeCounter,
+					ScrapeInterval: tc.ScrapeInterval,
+				},
+				Variables: map[string]any{},
+				Arguments: arguments,
+				Runtime:   &metadata.RuntimeSettings{},
+			}
+
+			validatedRequest, err := EvalCollectionRequest(&request, arguments, executor.Variables, executor.Runtime)
+			assert.NilError(t, err)
+
+			result, err := executor.Explain(validatedRequest)
+			assert.NilError(t, err)
+			assert.Equal(t, tc.QueryString, result.QueryString)
+
+			explainResponse, err := result.ToExplainResponse()
+			assert.NilError(t, err)
+
+			autoRange, ok := explainResponse.Details["auto_range"]
+			assert.Equal(t, tc.AutoRange != "", ok)
+			assert.Equal(t, tc.AutoRange, autoRange)
+		})
+	}
+}
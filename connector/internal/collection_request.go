@@ -11,11 +11,14 @@ import (
 	"github.com/hasura/ndc-sdk-go/schema"
 	"github.com/hasura/ndc-sdk-go/utils"
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
 )
 
 // ColumnOrder the structured sorting columns.
 type ColumnOrder struct {
-	Name       string
+	Name string
+	// The nested field of the column, e.g. the statistic of the summary column.
+	Field      string
 	Descending bool
 }
 
@@ -39,6 +42,11 @@ type CollectionValidatedArguments struct {
 	Timeout    time.Duration
 	Offset     time.Duration
 	OffsetUsed bool
+	MaxPoints  int
+	// The time zone location to format timestamps and evaluate time functions.
+	Location *time.Location
+	// The calendar-aware bucket of the timestamp grouping dimension.
+	TimestampBucket *TimestampBucket
 
 	start     *time.Time
 	end       *time.Time
@@ -55,6 +63,22 @@ func (cva CollectionValidatedArguments) GetStep() time.Duration {
 	return cva.Range.Step
 }
 
+// get the time range that the query is evaluated in.
+func (cva CollectionValidatedArguments) getEvaluationRange() (time.Time, time.Time) {
+	switch {
+	case cva.Timestamp != nil:
+		return *cva.Timestamp, *cva.Timestamp
+	case cva.Range != nil && !cva.Range.Start.IsZero():
+		return cva.Range.Start, cva.Range.End
+	case cva.Range != nil:
+		return cva.Range.End, cva.Range.End
+	default:
+		now := time.Now()
+
+		return now, now
+	}
+}
+
 func (cva CollectionValidatedArguments) getComparisonTimestamp(
 	cmpValue schema.ComparisonValue,
 ) (*time.Time, error) {
@@ -77,6 +101,26 @@ type CollectionRequest struct {
 	Functions        []KeyValue
 	Groups           *Grouping
 	Aggregates       schema.QueryAggregates
+	// The range of range functions with the auto value.
+	AutoRange *AutoRange
+}
+
+// AutoRange represents the range of range functions that is resolved from the step and scrape interval,
+// max(step + scrape interval, 4 * scrape interval).
+type AutoRange struct {
+	Range          time.Duration
+	Step           time.Duration
+	ScrapeInterval time.Duration
+}
+
+// String implements the fmt.Stringer interface.
+func (ar AutoRange) String() string {
+	return fmt.Sprintf(
+		"%s (step %s, scrape interval %s)",
+		model.Duration(ar.Range),
+		model.Duration(ar.Step),
+		model.Duration(ar.ScrapeInterval),
+	)
 }
 
 // HasRangeVectorFunction checks if a range vector function exists in the request.
@@ -97,9 +141,15 @@ func EvalCollectionRequest(
 	variables map[string]any,
 	runtime *metadata.RuntimeSettings,
 ) (*CollectionRequest, error) {
+	location, err := runtime.GetLocation(nil)
+	if err != nil {
+		return nil, schema.UnprocessableContentError(err.Error(), nil)
+	}
+
 	result := &CollectionRequest{
 		LabelExpressions: make(map[string]*LabelExpression),
 		CollectionValidatedArguments: CollectionValidatedArguments{
+			Location:  location,
 			variables: variables,
 			runtime:   runtime,
 		},
@@ -127,6 +177,10 @@ func EvalCollectionRequest(
 		return nil, err
 	}
 
+	if err := result.evalTimestampBucket(step); err != nil {
+		return nil, schema.UnprocessableContentError(err.Error(), nil)
+	}
+
 	orderBy, err := evalCollectionOrderBy(request.Query.OrderBy)
 	if err != nil {
 		return nil, err
@@ -179,6 +233,37 @@ func (pr *CollectionRequest) evalArguments(arguments map[string]any) (time.Durat
 		pr.Quantile = &quantile
 	}
 
+	if rawMaxPoints, ok := arguments[metadata.ArgumentKeyMaxPoints]; ok {
+		maxPoints, err := decodeMaxPoints(rawMaxPoints)
+		if err != nil {
+			return 0, err
+		}
+
+		pr.MaxPoints = maxPoints
+	}
+
+	if rawTimezone, ok := arguments[metadata.ArgumentKeyTimezone]; ok {
+		location, err := decodeLocation(rawTimezone, pr.runtime)
+		if err != nil {
+			return 0, err
+		}
+
+		pr.Location = location
+	}
+
+	if rawBucket, ok := arguments[metadata.ArgumentKeyTimestampBucket]; ok {
+		bucket, err := decodeTimestampBucket(rawBucket)
+		if err != nil {
+			return 0, err
+		}
+
+		if bucket != nil && bucket.Location == nil {
+			bucket.Location = pr.Location
+		}
+
+		pr.TimestampBucket = bucket
+	}
+
 	var step time.Duration
 
 	if rawStep, ok := arguments[metadata.ArgumentKeyStep]; ok {
@@ -220,6 +305,27 @@ func (pr *CollectionRequest) evalArguments(arguments map[string]any) (time.Durat
 	return step, nil
 }
 
+// evalTimestampBucket validates aggregates of the timestamp bucket grouping.
+// If the step isn't set, the estimated step is reduced so every bucket has samples.
+func (pr *CollectionRequest) evalTimestampBucket(step time.Duration) error {
+	if pr.TimestampBucket == nil || pr.Groups == nil ||
+		!slices.Contains(pr.Groups.Dimensions, metadata.TimestampKey) {
+		return nil
+	}
+
+	for key, aggregate := range pr.Groups.Aggregates {
+		if err := validateTimestampBucketAggregate(aggregate); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+
+	if step == 0 && pr.Range != nil {
+		pr.Range.Step = min(pr.Range.Step, pr.TimestampBucket.MaxStep())
+	}
+
+	return nil
+}
+
 func (pr *CollectionRequest) evalQueryPredicate(expression schema.Expression) error {
 	switch expr := expression.Interface().(type) {
 	case *schema.ExpressionAnd:
@@ -260,15 +366,24 @@ func (pr *CollectionRequest) evalExpressionBinaryComparisonOperator(
 			}
 
 			pr.Value = expr
-		default:
-			if le, ok := pr.LabelExpressions[target.Name]; ok {
-				le.Expressions = append(le.Expressions, *expr)
-			} else {
-				pr.LabelExpressions[target.Name] = &LabelExpression{
-					Name:        target.Name,
-					Expressions: []schema.ExpressionBinaryComparisonOperator{*expr},
-				}
+		case metadata.LabelsKey:
+			value, err := getComparisonValue(expr.Value, pr.variables)
+			if err != nil {
+				return schema.UnprocessableContentError(err.Error(), nil)
+			}
+
+			labelExprs, err := evalLabelSetComparison(expr.Operator, value)
+			if err != nil {
+				return schema.UnprocessableContentError(err.Error(), map[string]any{
+					"field": metadata.LabelsKey,
+				})
+			}
+
+			for _, labelExpr := range labelExprs {
+				pr.addLabelExpression(labelExpr.Name, labelExpr.Expressions[0])
 			}
+		default:
+			pr.addLabelExpression(target.Name, *expr)
 		}
 	default:
 	}
@@ -276,6 +391,20 @@ func (pr *CollectionRequest) evalExpressionBinaryComparisonOperator(
 	return nil
 }
 
+func (pr *CollectionRequest) addLabelExpression(
+	name string,
+	expr schema.ExpressionBinaryComparisonOperator,
+) {
+	if le, ok := pr.LabelExpressions[name]; ok {
+		le.Expressions = append(le.Expressions, expr)
+	} else {
+		pr.LabelExpressions[name] = &LabelExpression{
+			Name:        name,
+			Expressions: []schema.ExpressionBinaryComparisonOperator{expr},
+		}
+	}
+}
+
 func (pr *CollectionRequest) evalComparisonTargetColumnTimestamp(
 	expr *schema.ExpressionBinaryComparisonOperator,
 ) error {
@@ -349,6 +478,21 @@ func evalCollectionOrderBy(orderBy *schema.OrderBy) ([]ColumnOrder, error) {
 				Name:       target.Name,
 				Descending: elem.OrderDirection == schema.OrderDirectionDesc,
 			}
+
+			if target.Name == metadata.SummaryKey {
+				if len(target.FieldPath) != 1 ||
+					!slices.Contains(metadata.SummaryFields, target.FieldPath[0]) {
+					return nil, fmt.Errorf(
+						"ordering by `%s` requires a field path of one of %v, got: %v",
+						target.Name,
+						metadata.SummaryFields,
+						target.FieldPath,
+					)
+				}
+
+				orderBy.Field = target.FieldPath[0]
+			}
+
 			results = append(results, orderBy)
 		default:
 			return nil, fmt.Errorf("support ordering by column only, got: %v", elem.Target)
@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/hasura/ndc-sdk-go/utils"
+	"github.com/prometheus/common/model"
+)
+
+// decodeMaxPoints decodes and validates the max_points argument.
+func decodeMaxPoints(value any) (int, error) {
+	maxPoints, err := utils.DecodeNullableInt[int64](value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid max_points argument: %w", err)
+	}
+
+	if maxPoints == nil {
+		return 0, nil
+	}
+
+	if *maxPoints <= 0 {
+		return 0, fmt.Errorf(
+			"invalid max_points argument: expected a positive integer, got %d",
+			*maxPoints,
+		)
+	}
+
+	return int(*maxPoints), nil
+}
+
+// downsampleMatrix reduces values of each series in the matrix to the maximum number of points.
+func downsampleMatrix(matrix model.Matrix, maxPoints int) model.Matrix {
+	if maxPoints <= 0 {
+		return matrix
+	}
+
+	for _, series := range matrix {
+		series.Values = downsampleLTTB(series.Values, maxPoints)
+	}
+
+	return matrix
+}
+
+// downsampleLTTB downsamples the sorted sample pairs with the [Largest-Triangle-Three-Buckets] algorithm.
+// The first and last points are always kept. Each of remaining buckets selects the point
+// that forms the largest triangle with the previously selected point and the average of the next bucket.
+//
+// [Largest-Triangle-Three-Buckets]: https://skemman.is/bitstream/1946/15343/3/SS_MSthesis.pdf
+func downsampleLTTB(values []model.SamplePair, threshold int) []model.SamplePair {
+	length := len(values)
+	if threshold >= length || threshold <= 0 {
+		return values
+	}
+
+	switch threshold {
+	case 1:
+		return []model.SamplePair{values[length-1]}
+	case 2:
+		return []model.SamplePair{values[0], values[length-1]}
+	}
+
+	results := make([]model.SamplePair, 0, threshold)
+	results = append(results, values[0])
+
+	// the first and last points are excluded from buckets.
+	bucketSize := float64(length-2) / float64(threshold-2)
+	selectedIndex := 0
+
+	for i := range threshold - 2 {
+		// calculate the average point of the next bucket.
+		nextStart := int(math.Floor(float64(i+1)*bucketSize)) + 1
+		nextEnd := min(int(math.Floor(float64(i+2)*bucketSize))+1, length)
+
+		var avgX, avgY float64
+
+		var count int
+
+		for _, next := range values[nextStart:nextEnd] {
+			if math.IsNaN(float64(next.Value)) {
+				continue
+			}
+
+			avgX += float64(next.Timestamp)
+			avgY += float64(next.Value)
+			count++
+		}
+
+		if count > 0 {
+			avgX /= float64(count)
+			avgY /= float64(count)
+		} else {
+			avgX = float64(values[length-1].Timestamp)
+			avgY = float64(values[length-1].Value)
+		}
+
+		// select the point with the largest triangle area in the current bucket.
+		start := int(math.Floor(float64(i)*bucketSize)) + 1
+		end := nextStart
+		pointA := values[selectedIndex]
+		maxArea := -1.0
+		maxIndex := start
+
+		for j := start; j < end; j++ {
+			area := math.Abs(
+				(float64(pointA.Timestamp)-avgX)*(float64(values[j].Value)-float64(pointA.Value))-
+					(float64(pointA.Timestamp)-float64(values[j].Timestamp))*(avgY-float64(pointA.Value)),
+			) / 2
+
+			if math.IsNaN(area) {
+				continue
+			}
+
+			if area > maxArea {
+				maxArea = area
+				maxIndex = j
+			}
+		}
+
+		results = append(results, values[maxIndex])
+		selectedIndex = maxIndex
+	}
+
+	return append(results, values[length-1])
+}
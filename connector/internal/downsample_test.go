@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"gotest.tools/v3/assert"
+)
+
+func TestDownsampleLTTB(t *testing.T) {
+	now := model.Now()
+	values := make([]model.SamplePair, 1000)
+
+	for i := range values {
+		values[i] = model.SamplePair{
+			Timestamp: now.Add(time.Duration(i) * time.Second),
+			Value:     model.SampleValue(math.Sin(float64(i) / 50)),
+		}
+	}
+
+	// add a spike that must be preserved.
+	values[500].Value = 100
+
+	testCases := []struct {
+		Name      string
+		Threshold int
+		Expected  int
+	}{
+		{Name: "disabled", Threshold: 0, Expected: 1000},
+		{Name: "larger_than_length", Threshold: 2000, Expected: 1000},
+		{Name: "last_only", Threshold: 1, Expected: 1},
+		{Name: "first_last", Threshold: 2, Expected: 2},
+		{Name: "buckets", Threshold: 100, Expected: 100},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			results := downsampleLTTB(values, tc.Threshold)
+			assert.Equal(t, len(results), tc.Expected)
+			assert.DeepEqual(t, results[len(results)-1], values[len(values)-1])
+
+			for i := 1; i < len(results); i++ {
+				assert.Assert(t, results[i-1].Timestamp < results[i].Timestamp)
+			}
+
+			if tc.Threshold > 2 {
+				assert.DeepEqual(t, results[0], values[0])
+
+				var spike bool
+
+				for _, v := range results {
+					spike = spike || v.Value == 100
+				}
+
+				assert.Assert(t, spike, "the spike point must be kept")
+			}
+		})
+	}
+}
+
+func TestDecodeMaxPoints(t *testing.T) {
+	result, err := decodeMaxPoints(nil)
+	assert.NilError(t, err)
+	assert.Equal(t, result, 0)
+
+	result, err = decodeMaxPoints(500)
+	assert.NilError(t, err)
+	assert.Equal(t, result, 500)
+
+	_, err = decodeMaxPoints(-1)
+	assert.ErrorContains(t, err, "expected a positive integer")
+}
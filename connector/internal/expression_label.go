@@ -9,6 +9,8 @@ import (
 	"github.com/hasura/ndc-prometheus/connector/metadata"
 	"github.com/hasura/ndc-sdk-go/schema"
 	"github.com/hasura/ndc-sdk-go/utils"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
 )
 
 // LabelExpressionField the structured data of a label field expression.
@@ -26,41 +28,89 @@ type LabelExpression struct {
 type LabelExpressionBuilder struct {
 	LabelExpression
 
+	// The type information of the label.
+	Label metadata.LabelInfo
+
 	includes []LabelExpressionField
 	excludes map[LabelExpressionField]*regexp.Regexp
 }
 
-// Evaluate evaluates the list of expressions and returns the query string.
-func (le *LabelExpressionBuilder) Evaluate(variables map[string]any) (string, bool, error) {
+// Evaluate evaluates the list of expressions and returns label matchers of the vector selector.
+func (le *LabelExpressionBuilder) Evaluate(
+	variables map[string]any,
+) ([]*labels.Matcher, bool, error) {
 	if len(le.Expressions) == 0 {
-		return "", true, nil
+		return nil, true, nil
 	}
 
 	le.includes = []LabelExpressionField{}
 	le.excludes = map[LabelExpressionField]*regexp.Regexp{}
 
+	labelType := le.Label.GetType()
+
+	var int64Range *int64LabelRange
+
 	for _, expr := range le.Expressions {
 		value, err := getComparisonValue(expr.Value, variables)
 		if err != nil {
-			return "", false, err
+			return nil, false, err
+		}
+
+		if labelType != metadata.LabelTypeString {
+			if labelType == metadata.LabelTypeInt64 && isRangeComparisonOperator(expr.Operator) {
+				if utils.IsNil(value) {
+					continue
+				}
+
+				intValue, err := utils.DecodeInt[int64](value)
+				if err != nil {
+					return nil, false, fmt.Errorf("%s: %w", le.Name, err)
+				}
+
+				if int64Range == nil {
+					int64Range = &int64LabelRange{}
+				}
+
+				int64Range.Add(expr.Operator, intValue)
+
+				continue
+			}
+
+			value, err = evalTypedLabelComparison(le.Label, expr.Operator, value)
+			if err != nil {
+				return nil, false, fmt.Errorf("%s: %w", le.Name, err)
+			}
 		}
 
 		ok, err := le.evalLabelComparison(expr.Operator, value)
 		if err != nil || !ok {
-			return "", false, err
+			return nil, false, err
+		}
+	}
+
+	// integer ranges are compiled to a regular expression after other comparisons,
+	// so equal values are filtered by the range.
+	if int64Range != nil {
+		if int64Range.IsEmpty() {
+			return nil, false, nil
+		}
+
+		ok, err := le.evalLabelComparisonRegex(metadata.Regex, int64Range.Regex())
+		if err != nil || !ok {
+			return nil, false, err
 		}
 	}
 
 	var isIncludeRegex bool
 
-	includes := []string{}
+	includes := []LabelExpressionField{}
 
 	for _, inc := range le.includes {
 		if le.excludeField(inc) {
 			continue
 		}
 
-		includes = append(includes, inc.Value)
+		includes = append(includes, inc)
 		isIncludeRegex = isIncludeRegex || inc.IsRegex
 	}
 
@@ -68,38 +118,91 @@ func (le *LabelExpressionBuilder) Evaluate(variables map[string]any) (string, bo
 		(len(includes) == 0 && len(le.excludes) == 0) {
 		// all equal and not-equal labels are matched together,
 		// so the result is always empty
-		return "", false, nil
+		return nil, false, nil
+	}
+
+	// exclude only
+	if len(includes) == 0 {
+		matcher, err := le.buildExcludeMatcher()
+		if err != nil {
+			return nil, false, err
+		}
+
+		return []*labels.Matcher{matcher}, true, nil
 	}
 
 	// if the label equals A or B but not C => equals A or B
-	if len(includes) > 0 {
-		operator := "="
+	matchType := labels.MatchEqual
+
+	if len(includes) > 1 || isIncludeRegex {
+		matchType = labels.MatchRegexp
+	}
 
-		if len(includes) > 1 || isIncludeRegex {
-			operator = "=~"
+	matcher, err := le.newMatcher(matchType, includes)
+	if err != nil {
+		return nil, false, err
+	}
+
+	matchers := []*labels.Matcher{matcher}
+
+	// the regular expression may match excluded values, so not-equal matchers are kept.
+	if isIncludeRegex && len(le.excludes) > 0 {
+		excludeMatcher, err := le.buildExcludeMatcher()
+		if err != nil {
+			return nil, false, err
 		}
 
-		return fmt.Sprintf(`%s%s"%s"`, le.Name, operator, strings.Join(includes, "|")), true, nil
+		matchers = append(matchers, excludeMatcher)
 	}
 
-	// exclude only
+	return matchers, true, nil
+}
+
+func (le *LabelExpressionBuilder) buildExcludeMatcher() (*labels.Matcher, error) {
 	var isExcludeRegex bool
 
-	excludes := make([]string, 0, len(le.excludes))
+	excludes := make([]LabelExpressionField, 0, len(le.excludes))
 
 	for ev := range le.excludes {
-		excludes = append(excludes, ev.Value)
+		excludes = append(excludes, ev)
 		isExcludeRegex = isExcludeRegex || ev.IsRegex
 	}
 
-	slices.Sort(excludes)
+	slices.SortFunc(excludes, func(a, b LabelExpressionField) int {
+		return strings.Compare(a.Value, b.Value)
+	})
 
-	operator := "!="
+	matchType := labels.MatchNotEqual
 	if len(excludes) > 1 || isExcludeRegex {
-		operator = "!~"
+		matchType = labels.MatchNotRegexp
+	}
+
+	return le.newMatcher(matchType, excludes)
+}
+
+// newMatcher creates the label matcher. Values are joined as alternatives of a regular expression
+// if the matcher type is regex. Literal values are escaped in regular expressions.
+func (le *LabelExpressionBuilder) newMatcher(
+	matchType labels.MatchType,
+	fields []LabelExpressionField,
+) (*labels.Matcher, error) {
+	values := make([]string, len(fields))
+	isRegex := matchType == labels.MatchRegexp || matchType == labels.MatchNotRegexp
+
+	for i, field := range fields {
+		if isRegex && !field.IsRegex {
+			values[i] = regexp.QuoteMeta(field.Value)
+		} else {
+			values[i] = field.Value
+		}
+	}
+
+	matcher, err := labels.NewMatcher(matchType, le.Name, strings.Join(values, "|"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", le.Name, err)
 	}
 
-	return fmt.Sprintf(`%s%s"%s"`, le.Name, operator, strings.Join(excludes, "|")), true, nil
+	return matcher, nil
 }
 
 func (le *LabelExpressionBuilder) excludeField(inc LabelExpressionField) bool {
@@ -298,3 +401,90 @@ func (le *LabelExpressionBuilder) evalLabelComparisonNotIn(value any) (bool, err
 
 	return true, nil
 }
+
+// evalLabelSetComparison converts the comparison of the labels column to comparisons of label names.
+// The _contains_key operator accepts a label name. Other operators accept a map of label names and values.
+func evalLabelSetComparison(
+	operator string,
+	value any,
+) ([]LabelExpression, error) {
+	if utils.IsNil(value) {
+		return nil, nil
+	}
+
+	if operator == metadata.ContainsKey {
+		name, err := utils.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", operator, err)
+		}
+
+		if err := validateLabelName(name); err != nil {
+			return nil, err
+		}
+
+		return []LabelExpression{
+			newLabelExpression(name, metadata.NotEqual, ""),
+		}, nil
+	}
+
+	switch operator {
+	case metadata.Equal, metadata.NotEqual, metadata.Regex, metadata.NotRegex:
+	default:
+		return nil, fmt.Errorf(
+			"unsupported comparison operator `%s` for the %s column",
+			operator,
+			metadata.LabelsKey,
+		)
+	}
+
+	labelValues, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf(
+			"%s: expected an object of label names and values, got: %v",
+			operator,
+			value,
+		)
+	}
+
+	results := make([]LabelExpression, 0, len(labelValues))
+
+	for _, name := range utils.GetSortedKeys(labelValues) {
+		if err := validateLabelName(name); err != nil {
+			return nil, err
+		}
+
+		labelValue, err := utils.DecodeString(labelValues[name])
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid value of label `%s`: %w", operator, name, err)
+		}
+
+		results = append(results, newLabelExpression(name, operator, labelValue))
+	}
+
+	return results, nil
+}
+
+func newLabelExpression(name string, operator string, value string) LabelExpression {
+	return LabelExpression{
+		Name: name,
+		Expressions: []schema.ExpressionBinaryComparisonOperator{
+			*schema.NewExpressionBinaryComparisonOperator(
+				*schema.NewComparisonTargetColumn(name),
+				operator,
+				schema.NewComparisonValueScalar(value),
+			),
+		},
+	}
+}
+
+// validateLabelName checks if the label name matches the Prometheus label grammar.
+func validateLabelName(name string) error {
+	if !model.LabelName(name).IsValidLegacy() {
+		return fmt.Errorf(
+			"invalid label name `%s`; must match the regular expression [a-zA-Z_][a-zA-Z0-9_]*",
+			name,
+		)
+	}
+
+	return nil
+}
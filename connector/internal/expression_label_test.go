@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/hasura/ndc-prometheus/connector/metadata"
+	"github.com/hasura/ndc-sdk-go/schema"
+	"gotest.tools/v3/assert"
+)
+
+func TestEvalLabelSetComparison(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Operator string
+		Value    any
+		Expected []LabelExpression
+		Error    string
+	}{
+		{
+			Name:     "contains_key",
+			Operator: metadata.ContainsKey,
+			Value:    "env",
+			Expected: []LabelExpression{
+				newLabelExpression("env", metadata.NotEqual, ""),
+			},
+		},
+		{
+			Name:     "eq_sorted",
+			Operator: metadata.Equal,
+			Value:    map[string]any{"job": "node", "env": "prod"},
+			Expected: []LabelExpression{
+				newLabelExpression("env", metadata.Equal, "prod"),
+				newLabelExpression("job", metadata.Equal, "node"),
+			},
+		},
+		{
+			Name:     "invalid_label_name",
+			Operator: metadata.Equal,
+			Value:    map[string]any{`job"} or vector(1)`: "node"},
+			Error:    "invalid label name",
+		},
+		{
+			Name:     "invalid_contains_key",
+			Operator: metadata.ContainsKey,
+			Value:    "1env",
+			Error:    "invalid label name",
+		},
+		{
+			Name:     "unsupported_operator",
+			Operator: metadata.In,
+			Value:    map[string]any{"job": "node"},
+			Error:    "unsupported comparison operator",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			result, err := evalLabelSetComparison(tc.Operator, tc.Value)
+			if tc.Error != "" {
+				assert.ErrorContains(t, err, tc.Error)
+
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.DeepEqual(t, result, tc.Expected)
+		})
+	}
+}
+
+func TestLabelExpressionBuilderEvaluate(t *testing.T) {
+	testCases := []struct {
+		Name        string
+		Expressions []schema.ExpressionBinaryComparisonOperator
+		Expected    string
+	}{
+		{
+			Name: "escape_equal",
+			Expressions: []schema.ExpressionBinaryComparisonOperator{
+				newLabelExpression("job", metadata.Equal, "a\"b\\c\nd").Expressions[0],
+			},
+			Expected: `up{job="a\"b\\c\nd"}`,
+		},
+		{
+			Name: "escape_in",
+			Expressions: []schema.ExpressionBinaryComparisonOperator{
+				*schema.NewExpressionBinaryComparisonOperator(
+					*schema.NewComparisonTargetColumn("job"),
+					metadata.In,
+					schema.NewComparisonValueScalar([]any{"a.b", `c"d`}),
+				),
+				newLabelExpression("job", metadata.NotEqual, "e").Expressions[0],
+			},
+			Expected: `up{job=~"a\\.b|c\"d"}`,
+		},
+		{
+			Name: "regex_exclude",
+			Expressions: []schema.ExpressionBinaryComparisonOperator{
+				newLabelExpression("job", metadata.Regex, "node.*").Expressions[0],
+				newLabelExpression("job", metadata.NotEqual, "node.a").Expressions[0],
+				newLabelExpression("job", metadata.NotEqual, "node-b").Expressions[0],
+			},
+			Expected: `up{job!~"node-b|node\\.a",job=~"node.*"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			matchers, ok, err := (&LabelExpressionBuilder{
+				LabelExpression: LabelExpression{
+					Name:        "job",
+					Expressions: tc.Expressions,
+				},
+			}).Evaluate(map[string]any{})
+			assert.NilError(t, err)
+			assert.Assert(t, ok)
+			assert.Equal(t, newMetricSelector("up", matchers).String(), tc.Expected)
+		})
+	}
+}
@@ -0,0 +1,549 @@
+package internal
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hasura/ndc-prometheus/connector/metadata"
+	"github.com/hasura/ndc-sdk-go/schema"
+	"github.com/hasura/ndc-sdk-go/utils"
+	"github.com/prometheus/common/model"
+)
+
+// formatLabelValue converts the label value to the configured label type.
+// Returns nil if the label is typed and the value can't be parsed.
+func formatLabelValue(
+	value model.LabelValue,
+	label metadata.LabelInfo,
+	format metadata.RuntimeFormatSettings,
+) any {
+	labelType := label.GetType()
+	if labelType == metadata.LabelTypeString {
+		return string(value)
+	}
+
+	if value == "" {
+		return nil
+	}
+
+	switch labelType {
+	case metadata.LabelTypeInt64:
+		result, err := strconv.ParseInt(string(value), 10, 64)
+		if err != nil {
+			return nil
+		}
+
+		return result
+	case metadata.LabelTypeFloat64:
+		result, err := strconv.ParseFloat(string(value), 64)
+		if err != nil {
+			return nil
+		}
+
+		// infinite upper bounds of histogram buckets are serialized with the format setting.
+		format.Value = metadata.ValueFloat64
+
+		return formatValue(model.SampleValue(result), format)
+	case metadata.LabelTypeBoolean:
+		result, err := strconv.ParseBool(string(value))
+		if err != nil {
+			return nil
+		}
+
+		return result
+	default:
+		return string(value)
+	}
+}
+
+// int64LabelRange holds inclusive bounds of range comparisons of an int64 label.
+type int64LabelRange struct {
+	lower *int64
+	upper *int64
+}
+
+// IsEmpty checks if no integer satisfies the range.
+func (ir int64LabelRange) IsEmpty() bool {
+	return ir.lower != nil && ir.upper != nil && *ir.lower > *ir.upper
+}
+
+// Add narrows the range with a comparison operator.
+func (ir *int64LabelRange) Add(operator string, value int64) {
+	switch operator {
+	case metadata.Greater:
+		if value == math.MaxInt64 {
+			ir.setLower(value)
+			ir.setUpper(value - 1)
+
+			return
+		}
+
+		ir.setLower(value + 1)
+	case metadata.GreaterOrEqual:
+		ir.setLower(value)
+	case metadata.Least:
+		if value == math.MinInt64 {
+			ir.setLower(value + 1)
+			ir.setUpper(value)
+
+			return
+		}
+
+		ir.setUpper(value - 1)
+	case metadata.LeastOrEqual:
+		ir.setUpper(value)
+	}
+}
+
+func (ir *int64LabelRange) setLower(value int64) {
+	if ir.lower == nil || *ir.lower < value {
+		ir.lower = &value
+	}
+}
+
+func (ir *int64LabelRange) setUpper(value int64) {
+	if ir.upper == nil || *ir.upper > value {
+		ir.upper = &value
+	}
+}
+
+// Regex generates the regular expression that matches decimal integers in the range.
+func (ir int64LabelRange) Regex() string {
+	var patterns []string
+
+	// non-negative numbers
+	if ir.upper == nil || *ir.upper >= 0 {
+		var lower uint64
+		if ir.lower != nil && *ir.lower > 0 {
+			lower = uint64(*ir.lower)
+		}
+
+		var upper *uint64
+		if ir.upper != nil {
+			upper = utils.ToPtr(uint64(*ir.upper))
+		}
+
+		patterns = append(patterns, uintRangePatterns(lower, upper)...)
+	}
+
+	// negative numbers are matched by ranges of their absolute values.
+	if ir.lower == nil || *ir.lower < 0 {
+		var absLower uint64 = 1
+		if ir.upper != nil && *ir.upper < 0 {
+			absLower = absInt64(*ir.upper)
+		}
+
+		var absUpper *uint64
+		if ir.lower != nil {
+			absUpper = utils.ToPtr(absInt64(*ir.lower))
+		}
+
+		negativePatterns := uintRangePatterns(absLower, absUpper)
+		if len(negativePatterns) == 1 {
+			patterns = append(patterns, "-"+negativePatterns[0])
+		} else {
+			patterns = append(patterns, "-("+strings.Join(negativePatterns, "|")+")")
+		}
+	}
+
+	return "(" + strings.Join(patterns, "|") + ")"
+}
+
+func absInt64(value int64) uint64 {
+	if value >= 0 {
+		return uint64(value)
+	}
+
+	return uint64(-(value + 1)) + 1
+}
+
+// uintRangePatterns builds patterns of decimal numbers in the range.
+// If the upper bound is nil, numbers with more digits than the lower bound are matched by a repetition.
+func uintRangePatterns(lower uint64, upper *uint64) []string {
+	lowerDigits := strconv.FormatUint(lower, 10)
+
+	if upper == nil {
+		return append(
+			digitRangePatterns(lowerDigits, strings.Repeat("9", len(lowerDigits))),
+			fmt.Sprintf("[1-9][0-9]{%d,}", len(lowerDigits)),
+		)
+	}
+
+	if lower > *upper {
+		return nil
+	}
+
+	upperDigits := strconv.FormatUint(*upper, 10)
+	if len(lowerDigits) == len(upperDigits) {
+		return digitRangePatterns(lowerDigits, upperDigits)
+	}
+
+	// split the range by the number of digits.
+	patterns := digitRangePatterns(lowerDigits, strings.Repeat("9", len(lowerDigits)))
+
+	for length := len(lowerDigits) + 1; length < len(upperDigits); length++ {
+		patterns = append(patterns, "[1-9]"+anyDigitsPattern(length-1))
+	}
+
+	return append(
+		patterns,
+		digitRangePatterns("1"+strings.Repeat("0", len(upperDigits)-1), upperDigits)...,
+	)
+}
+
+// digitRangePatterns builds patterns of the range whose bounds have the same number of digits.
+func digitRangePatterns(lower string, upper string) []string {
+	length := len(lower)
+
+	switch {
+	case lower == upper:
+		return []string{lower}
+	case strings.Trim(lower, "0") == "" && strings.Trim(upper, "9") == "":
+		return []string{anyDigitsPattern(length)}
+	}
+
+	first, last := lower[0], upper[0]
+
+	if first == last {
+		patterns := digitRangePatterns(lower[1:], upper[1:])
+		for i, pattern := range patterns {
+			patterns[i] = string(first) + pattern
+		}
+
+		return patterns
+	}
+
+	var head, tail []string
+
+	// the first digit of lower and upper bounds may be partially matched.
+	if strings.Trim(lower[1:], "0") != "" {
+		for _, pattern := range digitRangePatterns(lower[1:], strings.Repeat("9", length-1)) {
+			head = append(head, string(first)+pattern)
+		}
+
+		first++
+	}
+
+	if strings.Trim(upper[1:], "9") != "" {
+		for _, pattern := range digitRangePatterns(strings.Repeat("0", length-1), upper[1:]) {
+			tail = append(tail, string(last)+pattern)
+		}
+
+		last--
+	}
+
+	if first <= last {
+		digitClass := string(first)
+		if first < last {
+			digitClass = fmt.Sprintf("[%c-%c]", first, last)
+		}
+
+		head = append(head, digitClass+anyDigitsPattern(length-1))
+	}
+
+	return append(head, tail...)
+}
+
+func anyDigitsPattern(length int) string {
+	switch length {
+	case 0:
+		return ""
+	case 1:
+		return "[0-9]"
+	default:
+		return fmt.Sprintf("[0-9]{%d}", length)
+	}
+}
+
+// evalTypedLabelComparison converts the comparison value of a typed label to the label value string.
+func evalTypedLabelComparison(label metadata.LabelInfo, operator string, value any) (any, error) {
+	switch operator {
+	case metadata.Regex, metadata.NotRegex:
+		// regular expressions match the raw label value.
+		return value, nil
+	case metadata.In, metadata.NotIn:
+		values, err := decodeTypedLabelValues(label, value)
+		if err != nil || values == nil {
+			return nil, err
+		}
+
+		return values, nil
+	case metadata.Equal, metadata.NotEqual:
+		return decodeTypedLabelValue(label, value)
+	default:
+		return nil, fmt.Errorf(
+			"unsupported comparison operator `%s` for the %s label",
+			operator,
+			label.GetType(),
+		)
+	}
+}
+
+func decodeTypedLabelValue(label metadata.LabelInfo, value any) (*string, error) {
+	if utils.IsNil(value) {
+		return nil, nil
+	}
+
+	switch label.GetType() {
+	case metadata.LabelTypeInt64:
+		intValue, err := utils.DecodeInt[int64](value)
+		if err != nil {
+			return nil, err
+		}
+
+		return utils.ToPtr(strconv.FormatInt(intValue, 10)), nil
+	case metadata.LabelTypeFloat64:
+		floatValue, err := utils.DecodeFloat[float64](value)
+		if err != nil {
+			return nil, err
+		}
+
+		return utils.ToPtr(model.SampleValue(floatValue).String()), nil
+	case metadata.LabelTypeBoolean:
+		boolValue, err := utils.DecodeBoolean(value)
+		if err != nil {
+			return nil, err
+		}
+
+		return utils.ToPtr(strconv.FormatBool(boolValue)), nil
+	case metadata.LabelTypeEnum:
+		strValue, err := utils.DecodeString(value)
+		if err != nil {
+			return nil, err
+		}
+
+		if !slices.Contains(label.Values, strValue) {
+			return nil, fmt.Errorf(
+				"invalid enum value; expected one of %v, got: %s",
+				label.Values,
+				strValue,
+			)
+		}
+
+		return &strValue, nil
+	default:
+		return utils.DecodeNullableString(value)
+	}
+}
+
+func decodeTypedLabelValues(label metadata.LabelInfo, value any) ([]string, error) {
+	if utils.IsNil(value) {
+		return nil, nil
+	}
+
+	if str, ok := value.(string); ok {
+		// try to parse the slice from the json string
+		var arrayValue []any
+		if err := json.Unmarshal([]byte(str), &arrayValue); err != nil {
+			return nil, err
+		}
+
+		value = arrayValue
+	}
+
+	rawValues, err := decodeTypedSlice(value)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]string, len(rawValues))
+
+	for i, rawValue := range rawValues {
+		strValue, err := decodeTypedLabelValue(label, rawValue)
+		if err != nil {
+			return nil, err
+		}
+
+		if strValue == nil {
+			return nil, fmt.Errorf("%s: null value in the array is not allowed", label.GetType())
+		}
+
+		results[i] = *strValue
+	}
+
+	return results, nil
+}
+
+func decodeTypedSlice(value any) ([]any, error) {
+	switch values := value.(type) {
+	case []any:
+		return values, nil
+	case []string:
+		return toAnySlice(values), nil
+	case []int64:
+		return toAnySlice(values), nil
+	case []int:
+		return toAnySlice(values), nil
+	case []float64:
+		return toAnySlice(values), nil
+	case []bool:
+		return toAnySlice(values), nil
+	default:
+		return nil, fmt.Errorf("expected an array, got: %v", value)
+	}
+}
+
+func toAnySlice[T any](values []T) []any {
+	results := make([]any, len(values))
+	for i, v := range values {
+		results[i] = v
+	}
+
+	return results
+}
+
+// matchTypedLabelValue checks if the label value satisfies the comparison in Go.
+// Numeric comparisons are evaluated with the parsed value of the label.
+func matchTypedLabelValue(
+	label metadata.LabelInfo,
+	labelValue model.LabelValue,
+	expr schema.ExpressionBinaryComparisonOperator,
+	variables map[string]any,
+) (bool, error) {
+	rawValue, err := getComparisonValue(expr.Value, variables)
+	if err != nil {
+		return false, err
+	}
+
+	if utils.IsNil(rawValue) {
+		return true, nil
+	}
+
+	if isRangeComparisonOperator(expr.Operator) {
+		return matchOrderedLabelValue(label, labelValue, expr.Operator, rawValue)
+	}
+
+	if expr.Operator == metadata.Regex || expr.Operator == metadata.NotRegex {
+		pattern, err := utils.DecodeString(rawValue)
+		if err != nil {
+			return false, err
+		}
+
+		// label matchers of Prometheus are fully anchored.
+		regex, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return false, fmt.Errorf("invalid regular expression %s: %w", pattern, err)
+		}
+
+		return regex.MatchString(string(labelValue)) == (expr.Operator == metadata.Regex), nil
+	}
+
+	value, err := evalTypedLabelComparison(label, expr.Operator, rawValue)
+	if err != nil {
+		return false, err
+	}
+
+	var values []string
+
+	switch v := value.(type) {
+	case *string:
+		values = []string{*v}
+	case []string:
+		values = v
+	}
+
+	matched := slices.ContainsFunc(values, func(v string) bool {
+		return equalTypedLabelValue(label, labelValue, v)
+	})
+
+	if expr.Operator == metadata.NotEqual || expr.Operator == metadata.NotIn {
+		return !matched, nil
+	}
+
+	return matched, nil
+}
+
+// equalTypedLabelValue compares the parsed values, so 0.50 equals 0.5 for float64 labels.
+func equalTypedLabelValue(
+	label metadata.LabelInfo,
+	labelValue model.LabelValue,
+	value string,
+) bool {
+	switch label.GetType() {
+	case metadata.LabelTypeInt64:
+		a, errA := strconv.ParseInt(string(labelValue), 10, 64)
+		b, errB := strconv.ParseInt(value, 10, 64)
+
+		return errA == nil && errB == nil && a == b
+	case metadata.LabelTypeFloat64:
+		a, errA := strconv.ParseFloat(string(labelValue), 64)
+		b, errB := strconv.ParseFloat(value, 64)
+
+		return errA == nil && errB == nil && a == b
+	case metadata.LabelTypeBoolean:
+		a, errA := strconv.ParseBool(string(labelValue))
+		b, errB := strconv.ParseBool(value)
+
+		return errA == nil && errB == nil && a == b
+	default:
+		return string(labelValue) == value
+	}
+}
+
+func matchOrderedLabelValue(
+	label metadata.LabelInfo,
+	labelValue model.LabelValue,
+	operator string,
+	value any,
+) (bool, error) {
+	var result int
+
+	switch label.GetType() {
+	case metadata.LabelTypeInt64:
+		intValue, err := utils.DecodeInt[int64](value)
+		if err != nil {
+			return false, err
+		}
+
+		parsed, err := strconv.ParseInt(string(labelValue), 10, 64)
+		if err != nil {
+			return false, nil
+		}
+
+		result = cmp.Compare(parsed, intValue)
+	case metadata.LabelTypeFloat64:
+		floatValue, err := utils.DecodeFloat[float64](value)
+		if err != nil {
+			return false, err
+		}
+
+		parsed, err := strconv.ParseFloat(string(labelValue), 64)
+		if err != nil || math.IsNaN(parsed) {
+			return false, nil
+		}
+
+		result = cmp.Compare(parsed, floatValue)
+	default:
+		return false, fmt.Errorf(
+			"unsupported comparison operator `%s` for the %s label",
+			operator,
+			label.GetType(),
+		)
+	}
+
+	switch operator {
+	case metadata.Least:
+		return result < 0, nil
+	case metadata.LeastOrEqual:
+		return result <= 0, nil
+	case metadata.Greater:
+		return result > 0, nil
+	default:
+		return result >= 0, nil
+	}
+}
+
+func isRangeComparisonOperator(operator string) bool {
+	switch operator {
+	case metadata.Least, metadata.LeastOrEqual, metadata.Greater, metadata.GreaterOrEqual:
+		return true
+	default:
+		return false
+	}
+}
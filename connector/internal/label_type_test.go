@@ -0,0 +1,191 @@
+package internal
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/hasura/ndc-prometheus/connector/metadata"
+	"github.com/hasura/ndc-sdk-go/schema"
+	"github.com/hasura/ndc-sdk-go/utils"
+	"github.com/prometheus/common/model"
+	"gotest.tools/v3/assert"
+)
+
+func TestInt64LabelRangeRegex(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Lower    *int64
+		Upper    *int64
+		Expected string
+	}{
+		{
+			Name:     "http_5xx",
+			Lower:    utils.ToPtr[int64](500),
+			Upper:    utils.ToPtr[int64](599),
+			Expected: "(5[0-9]{2})",
+		},
+		{
+			Name:     "gte",
+			Lower:    utils.ToPtr[int64](500),
+			Expected: "([5-9][0-9]{2}|[1-9][0-9]{3,})",
+		},
+		{
+			Name:     "partial",
+			Lower:    utils.ToPtr[int64](17),
+			Upper:    utils.ToPtr[int64](1234),
+			Expected: "(1[7-9]|[2-9][0-9]|[1-9][0-9]{2}|1[0-1][0-9]{2}|12[0-2][0-9]|123[0-4])",
+		},
+		{
+			Name:     "lt",
+			Upper:    utils.ToPtr[int64](2),
+			Expected: "([0-2]|-([1-9]|[1-9][0-9]{1,}))",
+		},
+		{
+			Name:     "negative",
+			Lower:    utils.ToPtr[int64](-15),
+			Upper:    utils.ToPtr[int64](-3),
+			Expected: "(-([3-9]|1[0-5]))",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert.Equal(t, tc.Expected, int64LabelRange{lower: tc.Lower, upper: tc.Upper}.Regex())
+		})
+	}
+}
+
+func TestInt64LabelRangeMatches(t *testing.T) {
+	bounds := []*int64{
+		nil,
+		utils.ToPtr[int64](-1001),
+		utils.ToPtr[int64](-100),
+		utils.ToPtr[int64](-9),
+		utils.ToPtr[int64](-1),
+		utils.ToPtr[int64](0),
+		utils.ToPtr[int64](1),
+		utils.ToPtr[int64](10),
+		utils.ToPtr[int64](99),
+		utils.ToPtr[int64](100),
+		utils.ToPtr[int64](450),
+		utils.ToPtr[int64](1009),
+	}
+
+	for _, lower := range bounds {
+		for _, upper := range bounds {
+			ir := int64LabelRange{lower: lower, upper: upper}
+			if ir.IsEmpty() {
+				continue
+			}
+
+			pattern := ir.Regex()
+			regex := regexp.MustCompile("^(?:" + pattern + ")$")
+
+			for i := int64(-1200); i <= 1200; i++ {
+				expected := (lower == nil || i >= *lower) && (upper == nil || i <= *upper)
+				if regex.MatchString(strconv.FormatInt(i, 10)) != expected {
+					t.Fatalf("%s: expected matching %d to be %t", pattern, i, expected)
+				}
+			}
+		}
+	}
+}
+
+func TestInt64LabelRangeAdd(t *testing.T) {
+	ir := int64LabelRange{}
+	ir.Add(metadata.GreaterOrEqual, 400)
+	ir.Add(metadata.Greater, 499)
+	ir.Add(metadata.Least, 600)
+	ir.Add(metadata.LeastOrEqual, 700)
+
+	assert.Equal(t, int64(500), *ir.lower)
+	assert.Equal(t, int64(599), *ir.upper)
+	assert.Assert(t, !ir.IsEmpty())
+
+	ir.Add(metadata.Greater, math.MaxInt64)
+	assert.Assert(t, ir.IsEmpty())
+}
+
+func TestFormatLabelValue(t *testing.T) {
+	format := metadata.RuntimeFormatSettings{
+		Value: metadata.ValueString,
+		Inf:   "+Inf",
+	}
+
+	testCases := []struct {
+		Value    model.LabelValue
+		Label    metadata.LabelInfo
+		Expected any
+	}{
+		{Value: "500", Label: metadata.LabelInfo{}, Expected: "500"},
+		{Value: "500", Label: metadata.LabelInfo{Type: metadata.LabelTypeInt64}, Expected: int64(500)},
+		{Value: "", Label: metadata.LabelInfo{Type: metadata.LabelTypeInt64}, Expected: nil},
+		{Value: "5xx", Label: metadata.LabelInfo{Type: metadata.LabelTypeInt64}, Expected: nil},
+		{Value: "0.25", Label: metadata.LabelInfo{Type: metadata.LabelTypeFloat64}, Expected: 0.25},
+		{Value: "+Inf", Label: metadata.LabelInfo{Type: metadata.LabelTypeFloat64}, Expected: "+Inf"},
+		{Value: "true", Label: metadata.LabelInfo{Type: metadata.LabelTypeBoolean}, Expected: true},
+		{
+			Value:    "GET",
+			Label:    metadata.LabelInfo{Type: metadata.LabelTypeEnum, Values: []string{"GET"}},
+			Expected: "GET",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.Label.GetType())+"_"+string(tc.Value), func(t *testing.T) {
+			assert.DeepEqual(t, tc.Expected, formatLabelValue(tc.Value, tc.Label, format))
+		})
+	}
+}
+
+func TestMatchTypedLabelValue(t *testing.T) {
+	float64Label := metadata.LabelInfo{Type: metadata.LabelTypeFloat64}
+	int64Label := metadata.LabelInfo{Type: metadata.LabelTypeInt64}
+	enumLabel := metadata.LabelInfo{Type: metadata.LabelTypeEnum, Values: []string{"GET", "POST"}}
+
+	testCases := []struct {
+		Name       string
+		Label      metadata.LabelInfo
+		LabelValue model.LabelValue
+		Operator   string
+		Value      any
+		Expected   bool
+		ErrorMsg   string
+	}{
+		{Name: "float_eq", Label: float64Label, LabelValue: "0.50", Operator: "_eq", Value: 0.5, Expected: true},
+		{Name: "float_lte", Label: float64Label, LabelValue: "0.25", Operator: "_lte", Value: "0.5", Expected: true},
+		{Name: "float_gt_inf", Label: float64Label, LabelValue: "+Inf", Operator: "_gt", Value: 10, Expected: true},
+		{Name: "float_nin", Label: float64Label, LabelValue: "1", Operator: "_nin", Value: []any{1.0, 2.5}, Expected: false},
+		{Name: "float_regex", Label: float64Label, LabelValue: "+Inf", Operator: "_regex", Value: `\+Inf`, Expected: true},
+		{Name: "int_in", Label: int64Label, LabelValue: "503", Operator: "_in", Value: []any{500, 503}, Expected: true},
+		{Name: "int_lt", Label: int64Label, LabelValue: "503", Operator: "_lt", Value: 500, Expected: false},
+		{Name: "int_invalid_label", Label: int64Label, LabelValue: "", Operator: "_gte", Value: 0, Expected: false},
+		{Name: "enum_neq", Label: enumLabel, LabelValue: "GET", Operator: "_neq", Value: "POST", Expected: true},
+		{Name: "enum_invalid", Label: enumLabel, LabelValue: "GET", Operator: "_eq", Value: "PUT", ErrorMsg: "invalid enum value"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			result, err := matchTypedLabelValue(
+				tc.Label,
+				tc.LabelValue,
+				*schema.NewExpressionBinaryComparisonOperator(
+					*schema.NewComparisonTargetColumn("label"),
+					tc.Operator,
+					schema.NewComparisonValueScalar(tc.Value),
+				),
+				map[string]any{},
+			)
+			if tc.ErrorMsg != "" {
+				assert.ErrorContains(t, err, tc.ErrorMsg)
+
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Equal(t, tc.Expected, result)
+		})
+	}
+}
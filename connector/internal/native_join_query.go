@@ -0,0 +1,451 @@
+package internal
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hasura/ndc-prometheus/connector/metadata"
+	"github.com/hasura/ndc-sdk-go/schema"
+	"github.com/hasura/ndc-sdk-go/utils"
+	"github.com/prometheus/common/model"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
+)
+
+// joinedRow represents a row of expression values that are joined on labels and the timestamp.
+type joinedRow struct {
+	Labels    model.Metric
+	Timestamp model.Time
+	Values    map[string]model.SampleValue
+}
+
+// ExplainJoin evaluates queries of expressions of the native query.
+func (nqe *NativeQueryExecutor) ExplainJoin() (*NativeQueryRequest, map[string]string, error) {
+	if len(nqe.Request.Query.Aggregates) > 0 || nqe.Request.Query.Groups != nil {
+		return nil, nil, schema.UnprocessableContentError(
+			"aggregates and groups are not supported by native queries of expressions",
+			map[string]any{
+				"collection": nqe.Request.Collection,
+			},
+		)
+	}
+
+	params, err := EvalNativeQueryRequest(nqe.Request, nqe.Arguments, nqe.Variables, nqe.Runtime)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	expressions := nqe.NativeQuery.ExpressionQueries()
+	queries := make(map[string]string, len(expressions))
+
+	for _, name := range utils.GetSortedKeys(expressions) {
+		expression := expressions[name]
+		executor := *nqe
+		executor.NativeQuery = &expression
+
+		queryString, err := executor.evalArguments(params)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		queries[name] = queryString
+	}
+
+	return params, queries, nil
+}
+
+func (nqe *NativeQueryExecutor) executeJoin(ctx context.Context) (*schema.RowSet, error) {
+	params, queries, err := nqe.ExplainJoin()
+	if err != nil {
+		return nil, err
+	}
+
+	// instant queries of all expressions are evaluated at the same time to be joined.
+	if utils.IsNil(params.Timestamp) && params.Range == nil {
+		now := time.Now()
+		params.Timestamp = &now
+	}
+
+	concurrencyLimit := nqe.Runtime.ConcurrencyLimit
+	if concurrencyLimit <= 0 {
+		concurrencyLimit = len(queries)
+	}
+
+	var lock sync.Mutex
+
+	matrices := make(map[string]model.Matrix, len(queries))
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(concurrencyLimit)
+
+	for name, queryString := range queries {
+		eg.Go(func() error {
+			matrix, err := nqe.queryJoinExpression(egCtx, queryString, params)
+			if err != nil {
+				return err
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			matrices[name] = matrix
+
+			return nil
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	rows, err := nqe.joinExpressionResults(matrices)
+	if err != nil {
+		return nil, err
+	}
+
+	span := trace.SpanFromContext(ctx)
+	span.AddEvent("post_filter", trace.WithAttributes(
+		utils.JSONAttribute("expression", params.Expression),
+		attribute.Int("pre_filter_count", len(rows)),
+	))
+
+	rows, err = nqe.filterJoinedRows(rows, params.Expression)
+	if err != nil {
+		return nil, schema.UnprocessableContentError(err.Error(), nil)
+	}
+
+	span.AddEvent(
+		"post_filter_results",
+		trace.WithAttributes(attribute.Int("post_filter_count", len(rows))),
+	)
+
+	nqe.sortJoinedRows(rows, params.OrderBy)
+
+	results, err := utils.EvalObjectsWithColumnSelection(
+		nqe.Request.Query.Fields,
+		paginateQueryResults(nqe.createJoinedResults(rows, params), nqe.Request.Query),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &schema.RowSet{
+		Aggregates: schema.RowSetAggregates{},
+		Rows:       results,
+	}, nil
+}
+
+// queryJoinExpression evaluates the query of the expression.
+// Samples of instant queries are converted to series of a single point.
+func (nqe *NativeQueryExecutor) queryJoinExpression(
+	ctx context.Context,
+	queryString string,
+	params *NativeQueryRequest,
+) (model.Matrix, error) {
+	if !utils.IsNil(params.Timestamp) {
+		vector, _, err := nqe.Client.Query(ctx, queryString, params.Timestamp, params.Timeout)
+		if err != nil {
+			return nil, schema.UnprocessableContentError(err.Error(), nil)
+		}
+
+		matrix := make(model.Matrix, len(vector))
+
+		for i, sample := range vector {
+			matrix[i] = &model.SampleStream{
+				Metric: sample.Metric,
+				Values: []model.SamplePair{{Timestamp: sample.Timestamp, Value: sample.Value}},
+			}
+		}
+
+		return matrix, nil
+	}
+
+	matrix, _, err := nqe.Client.QueryRange(ctx, queryString, *params.Range, params.Timeout)
+	if err != nil {
+		return nil, schema.UnprocessableContentError(err.Error(), nil)
+	}
+
+	return matrix, nil
+}
+
+// joinExpressionResults joins series of expressions on join labels and timestamps.
+// Rows that don't have a series of an expression have a null value of the expression.
+func (nqe *NativeQueryExecutor) joinExpressionResults(
+	matrices map[string]model.Matrix,
+) ([]*joinedRow, error) {
+	rows := []*joinedRow{}
+	rowIndexes := map[string]*joinedRow{}
+
+	for _, name := range utils.GetSortedKeys(matrices) {
+		for _, series := range matrices[name] {
+			labels := model.Metric{}
+
+			for _, key := range nqe.NativeQuery.JoinLabels {
+				if value, ok := series.Metric[model.LabelName(key)]; ok {
+					labels[model.LabelName(key)] = value
+				}
+			}
+
+			for _, sample := range series.Values {
+				key := createJoinKey(nqe.NativeQuery.JoinLabels, labels, sample.Timestamp)
+
+				row, ok := rowIndexes[key]
+				if !ok {
+					row = &joinedRow{
+						Labels:    labels,
+						Timestamp: sample.Timestamp,
+						Values:    map[string]model.SampleValue{},
+					}
+					rowIndexes[key] = row
+					rows = append(rows, row)
+				}
+
+				if _, ok := row.Values[name]; ok {
+					return nil, schema.UnprocessableContentError(
+						fmt.Sprintf(
+							"expression `%s` returns many series of the join labels %s. Aggregate the expression by join labels, e.g. sum by (%s)",
+							name,
+							labels.String(),
+							strings.Join(nqe.NativeQuery.JoinLabels, ", "),
+						),
+						map[string]any{
+							"collection": nqe.Request.Collection,
+						},
+					)
+				}
+
+				row.Values[name] = sample.Value
+			}
+		}
+	}
+
+	return rows, nil
+}
+
+func createJoinKey(joinLabels []string, labels model.Metric, timestamp model.Time) string {
+	var sb strings.Builder
+
+	for _, key := range joinLabels {
+		sb.WriteString(string(labels[model.LabelName(key)]))
+		sb.WriteByte(model.SeparatorByte)
+	}
+
+	sb.WriteString(timestamp.String())
+
+	return sb.String()
+}
+
+func (nqe *NativeQueryExecutor) filterJoinedRows(
+	rows []*joinedRow,
+	expr schema.Expression,
+) ([]*joinedRow, error) {
+	if expr == nil || len(rows) == 0 {
+		return rows, nil
+	}
+
+	results := []*joinedRow{}
+
+	for _, row := range rows {
+		valid, err := nqe.validateJoinedRowBoolExp(row, expr)
+		if err != nil {
+			return nil, err
+		}
+
+		if valid {
+			results = append(results, row)
+		}
+	}
+
+	return results, nil
+}
+
+// validateJoinedRowBoolExp checks if the row matches the where expression.
+// Comparisons of expression columns are false if the value of the expression is null.
+func (nqe *NativeQueryExecutor) validateJoinedRowBoolExp(
+	row *joinedRow,
+	expr schema.Expression,
+) (bool, error) {
+	switch exprs := expr.Interface().(type) {
+	case *schema.ExpressionAnd:
+		for _, e := range exprs.Expressions {
+			valid, err := nqe.validateJoinedRowBoolExp(row, e)
+			if !valid || err != nil {
+				return false, err
+			}
+		}
+
+		return true, nil
+	case *schema.ExpressionOr:
+		if len(exprs.Expressions) == 0 {
+			return true, nil
+		}
+
+		for _, e := range exprs.Expressions {
+			valid, err := nqe.validateJoinedRowBoolExp(row, e)
+			if valid || err != nil {
+				return valid, err
+			}
+		}
+
+		return false, nil
+	case *schema.ExpressionNot:
+		valid, err := nqe.validateJoinedRowBoolExp(row, exprs.Expression)
+		if err != nil {
+			return false, err
+		}
+
+		return !valid, nil
+	case *schema.ExpressionBinaryComparisonOperator:
+		target, err := getComparisonTargetColumn(exprs.Column)
+		if err != nil {
+			return false, err
+		}
+
+		if _, ok := nqe.NativeQuery.Expressions[target.Name]; !ok {
+			return nqe.validateExpressionBinaryComparisonColumn(row.Labels, 0, exprs, target)
+		}
+
+		value, ok := row.Values[target.Name]
+		if !ok {
+			return false, nil
+		}
+
+		return nqe.validateExpressionBinaryComparisonColumnValue(value, exprs)
+	case *schema.ExpressionUnaryComparisonOperator:
+		target, err := getComparisonTargetColumn(exprs.Column)
+		if err != nil {
+			return false, err
+		}
+
+		if _, ok := nqe.NativeQuery.Expressions[target.Name]; !ok {
+			return nqe.validateExpressionUnaryComparisonOperator(row.Labels, exprs)
+		}
+
+		if exprs.Operator != schema.UnaryComparisonOperatorIsNull {
+			return false, fmt.Errorf("unsupported comparison operator %s", exprs.Operator)
+		}
+
+		_, ok := row.Values[target.Name]
+
+		return !ok, nil
+	default:
+		return false, fmt.Errorf("unsupported expression %v", expr)
+	}
+}
+
+func getComparisonTargetColumn(
+	column schema.ComparisonTarget,
+) (*schema.ComparisonTargetColumn, error) {
+	targetT, err := column.InterfaceT()
+	if err != nil {
+		return nil, err
+	}
+
+	target, ok := targetT.(*schema.ComparisonTargetColumn)
+	if !ok {
+		return nil, fmt.Errorf("unsupported comparison target %s", targetT.Type())
+	}
+
+	return target, nil
+}
+
+// sortJoinedRows sorts rows by the requested columns, then join labels and timestamps.
+// Null values of expressions are ordered first.
+func (nqe *NativeQueryExecutor) sortJoinedRows(rows []*joinedRow, sortElements []ColumnOrder) {
+	slices.SortStableFunc(rows, func(a *joinedRow, b *joinedRow) int {
+		for _, elem := range sortElements {
+			ordering := nqe.compareJoinedRowColumn(a, b, elem.Name)
+			if ordering == 0 {
+				continue
+			}
+
+			if elem.Descending {
+				return -ordering
+			}
+
+			return ordering
+		}
+
+		for _, key := range nqe.NativeQuery.JoinLabels {
+			if ordering := nqe.compareJoinedRowColumn(a, b, key); ordering != 0 {
+				return ordering
+			}
+		}
+
+		return cmp.Compare(a.Timestamp, b.Timestamp)
+	})
+}
+
+func (nqe *NativeQueryExecutor) compareJoinedRowColumn(
+	a *joinedRow,
+	b *joinedRow,
+	name string,
+) int {
+	if name == metadata.TimestampKey {
+		return cmp.Compare(a.Timestamp, b.Timestamp)
+	}
+
+	if _, ok := nqe.NativeQuery.Expressions[name]; !ok {
+		return strings.Compare(
+			string(a.Labels[model.LabelName(name)]),
+			string(b.Labels[model.LabelName(name)]),
+		)
+	}
+
+	valueA, okA := a.Values[name]
+	valueB, okB := b.Values[name]
+
+	switch {
+	case !okA && !okB:
+		return 0
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	default:
+		return compareVectorValue(&model.Sample{Value: valueA}, &model.Sample{Value: valueB})
+	}
+}
+
+func (nqe *NativeQueryExecutor) createJoinedResults(
+	rows []*joinedRow,
+	params *NativeQueryRequest,
+) []map[string]any {
+	results := make([]map[string]any, len(rows))
+
+	for i, row := range rows {
+		result := map[string]any{
+			metadata.TimestampKey: formatTimestamp(
+				row.Timestamp,
+				nqe.Runtime.Format.Timestamp,
+				params.Location,
+			),
+		}
+
+		for _, key := range nqe.NativeQuery.JoinLabels {
+			result[key] = formatLabelValue(
+				row.Labels[model.LabelName(key)],
+				nqe.NativeQuery.Labels[key],
+				nqe.Runtime.Format,
+			)
+		}
+
+		for name := range nqe.NativeQuery.Expressions {
+			value, ok := row.Values[name]
+			if ok {
+				result[name] = formatValue(value, nqe.Runtime.Format)
+			} else {
+				result[name] = nil
+			}
+		}
+
+		results[i] = result
+	}
+
+	return results
+}
@@ -0,0 +1,164 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hasura/ndc-prometheus/connector/client"
+	"github.com/hasura/ndc-prometheus/connector/metadata"
+	"github.com/hasura/ndc-sdk-go/schema"
+	"github.com/hasura/ndc-sdk-go/utils"
+	"gotest.tools/v3/assert"
+)
+
+func TestNativeQueryExecuteJoin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NilError(t, r.ParseForm())
+
+		var data string
+
+		switch r.Form.Get("query") {
+		case `sum by (job) (http_requests_total)`:
+			data = `{"resultType":"vector","result":[{"metric":{"job":"api"},"value":[1700000000,"10"]},{"metric":{"job":"web"},"value":[1700000000,"20"]}]}`
+		case `sum by (job) (http_requests_total{code=~"5.."})`:
+			data = `{"resultType":"vector","result":[{"metric":{"job":"api"},"value":[1700000000,"2"]}]}`
+		case `http_requests_total`:
+			data = `{"resultType":"vector","result":[{"metric":{"job":"api","code":"200"},"value":[1700000000,"8"]},{"metric":{"job":"api","code":"500"},"value":[1700000000,"2"]}]}`
+		default:
+			t.Errorf("unexpected query %s", r.Form.Get("query"))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":` + data + `}`))
+	}))
+	defer server.Close()
+
+	apiClient, err := client.NewClient(context.TODO(), client.ClientSettings{
+		URL: utils.NewEnvStringValue(server.URL),
+	})
+	assert.NilError(t, err)
+
+	expressions := map[string]string{
+		"requests": `sum by (job) (http_requests_total)`,
+		"errors":   `sum by (job) (http_requests_total{code=~"5.."})`,
+	}
+
+	testCases := []struct {
+		Name        string
+		Expressions map[string]string
+		Predicate   schema.ExpressionEncoder
+		OrderBy     *schema.OrderBy
+		Expected    []map[string]any
+		ErrorMsg    string
+	}{
+		{
+			Name:        "full_outer_join",
+			Expressions: expressions,
+			Expected: []map[string]any{
+				{"timestamp": int64(1700000000), "job": "api", "requests": float64(10), "errors": float64(2)},
+				{"timestamp": int64(1700000000), "job": "web", "requests": float64(20), "errors": nil},
+			},
+		},
+		{
+			Name:        "where_expression_value",
+			Expressions: expressions,
+			Predicate: schema.NewExpressionBinaryComparisonOperator(
+				*schema.NewComparisonTargetColumn("errors"),
+				metadata.Greater,
+				schema.NewComparisonValueScalar(1),
+			),
+			Expected: []map[string]any{
+				{"timestamp": int64(1700000000), "job": "api", "requests": float64(10), "errors": float64(2)},
+			},
+		},
+		{
+			Name:        "where_null_expression",
+			Expressions: expressions,
+			Predicate: schema.NewExpressionUnaryComparisonOperator(
+				*schema.NewComparisonTargetColumn("errors"),
+				schema.UnaryComparisonOperatorIsNull,
+			),
+			Expected: []map[string]any{
+				{"timestamp": int64(1700000000), "job": "web", "requests": float64(20), "errors": nil},
+			},
+		},
+		{
+			Name:        "order_by_expression",
+			Expressions: expressions,
+			OrderBy: &schema.OrderBy{
+				Elements: []schema.OrderByElement{
+					{
+						OrderDirection: schema.OrderDirectionDesc,
+						Target:         schema.NewOrderByColumn("requests", nil).Encode(),
+					},
+				},
+			},
+			Expected: []map[string]any{
+				{"timestamp": int64(1700000000), "job": "web", "requests": float64(20), "errors": nil},
+				{"timestamp": int64(1700000000), "job": "api", "requests": float64(10), "errors": float64(2)},
+			},
+		},
+		{
+			Name: "duplicated_series",
+			Expressions: map[string]string{
+				"requests": `http_requests_total`,
+			},
+			ErrorMsg: "expression `requests` returns many series of the join labels {job=\"api\"}",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			nativeQuery := metadata.NativeQuery{
+				Expressions: tc.Expressions,
+				JoinLabels:  []string{"job"},
+			}
+			assert.NilError(t, nativeQuery.Validate())
+
+			var predicate schema.Expression
+			if tc.Predicate != nil {
+				predicate = tc.Predicate.Encode()
+			}
+
+			fields := schema.QueryFields{
+				metadata.TimestampKey: schema.NewColumnField(metadata.TimestampKey).Encode(),
+				"job":                 schema.NewColumnField("job").Encode(),
+			}
+
+			for key := range tc.Expressions {
+				fields[key] = schema.NewColumnField(key).Encode()
+			}
+
+			nqe := &NativeQueryExecutor{
+				Client: apiClient,
+				Runtime: &metadata.RuntimeSettings{
+					Format: metadata.RuntimeFormatSettings{
+						Timestamp: metadata.TimestampUnix,
+						Value:     metadata.ValueFloat64,
+					},
+				},
+				Request: &schema.QueryRequest{
+					Collection: tc.Name,
+					Query: schema.Query{
+						Fields:    fields,
+						Predicate: predicate,
+						OrderBy:   tc.OrderBy,
+					},
+				},
+				NativeQuery: &nativeQuery,
+			}
+
+			result, err := nqe.executeJoin(context.TODO())
+			if tc.ErrorMsg != "" {
+				assert.ErrorContains(t, err, tc.ErrorMsg)
+
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.DeepEqual(t, tc.Expected, result.Rows)
+		})
+	}
+}
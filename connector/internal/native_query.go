@@ -6,12 +6,14 @@ import (
 	"regexp"
 	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/hasura/ndc-prometheus/connector/client"
 	"github.com/hasura/ndc-prometheus/connector/metadata"
 	"github.com/hasura/ndc-sdk-go/schema"
 	"github.com/hasura/ndc-sdk-go/utils"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/prometheus/common/model"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -39,16 +41,9 @@ func (nqe *NativeQueryExecutor) Explain(ctx context.Context) (*NativeQueryReques
 		return nil, "", err
 	}
 
-	if unresolvedArguments := metadata.FindNativeQueryVariableNames(queryString); len(
-		unresolvedArguments,
-	) > 0 {
-		return nil, "", schema.UnprocessableContentError(
-			fmt.Sprintf("unresolved variables %v in the query", unresolvedArguments),
-			map[string]any{
-				"collection": nqe.Request.Collection,
-				"query":      queryString,
-			},
-		)
+	queryString, err = nqe.evalFilterPushdown(params, queryString)
+	if err != nil {
+		return nil, "", err
 	}
 
 	return params, queryString, nil
@@ -59,6 +54,19 @@ func (nqe *NativeQueryExecutor) Execute(ctx context.Context) (*schema.RowSet, er
 	ctx, span := nqe.Tracer.Start(ctx, "Execute Native Query")
 	defer span.End()
 
+	if nqe.NativeQuery.IsJoinQuery() {
+		return nqe.executeJoin(ctx)
+	}
+
+	if nqe.IsCollectionQuery() {
+		executor, explainResult, err := nqe.ExplainCollection()
+		if err != nil {
+			return nil, err
+		}
+
+		return executor.Execute(ctx, explainResult)
+	}
+
 	params, queryString, err := nqe.Explain(ctx)
 	if err != nil {
 		return nil, err
@@ -67,13 +75,69 @@ func (nqe *NativeQueryExecutor) Execute(ctx context.Context) (*schema.RowSet, er
 	return nqe.execute(ctx, params, queryString)
 }
 
+// IsCollectionQuery checks if the request has PromQL functions, aggregates or groups
+// that are planned by the collection query executor.
+func (nqe *NativeQueryExecutor) IsCollectionQuery() bool {
+	return nqe.NativeQuery.GetResultType() == metadata.NativeQueryResultVector &&
+		!nqe.NativeQuery.IsJoinQuery() &&
+		(!utils.IsNil(nqe.Arguments[metadata.ArgumentKeyFunctions]) ||
+			len(nqe.Request.Query.Aggregates) > 0 ||
+			nqe.Request.Query.Groups != nil)
+}
+
+// ExplainCollection evaluates the native query as a subexpression
+// and explains the request with the collection query planner.
+func (nqe *NativeQueryExecutor) ExplainCollection() (
+	*QueryCollectionExecutor, *QueryCollectionExplainResult, error,
+) {
+	request, err := EvalCollectionRequest(nqe.Request, nqe.Arguments, nqe.Variables, nqe.Runtime)
+	if err != nil {
+		return nil, nil, schema.UnprocessableContentError(err.Error(), map[string]any{
+			"collection": nqe.Request.Collection,
+		})
+	}
+
+	queryString, err := nqe.evalQueryTemplate(request.Range)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	subquery, err := metadata.ParsePromQL(queryString)
+	if err != nil {
+		return nil, nil, schema.UnprocessableContentError(err.Error(), map[string]any{
+			"collection": nqe.Request.Collection,
+			"query":      queryString,
+		})
+	}
+
+	executor := &QueryCollectionExecutor{
+		Client:     nqe.Client,
+		Tracer:     nqe.Tracer,
+		Runtime:    nqe.Runtime,
+		Request:    nqe.Request,
+		MetricName: nqe.Request.Collection,
+		Metric: metadata.MetricInfo{
+			Description: nqe.NativeQuery.Description,
+			Labels:      nqe.NativeQuery.Labels,
+		},
+		Variables: nqe.Variables,
+		Arguments: nqe.Arguments,
+		Subquery:  subquery,
+	}
+
+	explainResult, err := executor.Explain(request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return executor, explainResult, nil
+}
+
 func (nqe *NativeQueryExecutor) evalArguments(params *NativeQueryRequest) (string, error) {
 	var step time.Duration
 
 	var err error
 
-	queryString := nqe.NativeQuery.Query
-
 	for key, arg := range nqe.Arguments {
 		switch key {
 		case metadata.ArgumentKeyStep:
@@ -86,10 +150,15 @@ func (nqe *NativeQueryExecutor) evalArguments(params *NativeQueryRequest) (strin
 			if err != nil {
 				return "", schema.UnprocessableContentError(err.Error(), nil)
 			}
-		default:
-			queryString, err = nqe.evalUnknownArguments(queryString, key, arg)
+		case metadata.ArgumentKeyMaxPoints:
+			params.MaxPoints, err = decodeMaxPoints(arg)
 			if err != nil {
-				return "", err
+				return "", schema.UnprocessableContentError(err.Error(), nil)
+			}
+		case metadata.ArgumentKeyTimezone:
+			params.Location, err = decodeLocation(arg, nqe.Runtime)
+			if err != nil {
+				return "", schema.UnprocessableContentError(err.Error(), nil)
 			}
 		}
 	}
@@ -101,63 +170,308 @@ func (nqe *NativeQueryExecutor) evalArguments(params *NativeQueryRequest) (strin
 		}
 	}
 
+	return nqe.evalQueryTemplate(params.Range)
+}
+
+// evalQueryTemplate substitutes arguments into the native query according to their syntactic contexts.
+// Built-in variables are evaluated from the time range of the request.
+func (nqe *NativeQueryExecutor) evalQueryTemplate(queryRange *v1.Range) (string, error) {
+	template, err := nqe.NativeQuery.Template()
+	if err != nil {
+		return "", schema.UnprocessableContentError(err.Error(), nil)
+	}
+
+	builtinValues := metadata.EvalBuiltinVariables(queryRange, nqe.Runtime.GetScrapeInterval())
+	unresolvedArguments := []string{}
+
+	for _, variable := range template.Variables {
+		if metadata.IsBuiltinVariable(variable.Name) {
+			if _, ok := builtinValues[variable.Name]; !ok {
+				return "", schema.UnprocessableContentError(
+					fmt.Sprintf(
+						"the built-in variable $%s requires the start and end of the time range",
+						variable.Name,
+					),
+					map[string]any{
+						"collection": nqe.Request.Collection,
+					},
+				)
+			}
+
+			continue
+		}
+
+		argInfo, isDefined := nqe.NativeQuery.Arguments[variable.Name]
+		if _, ok := nqe.Arguments[variable.Name]; !isDefined || (!ok && !argInfo.IsOptional()) {
+			unresolvedArguments = append(unresolvedArguments, variable.Name)
+		}
+	}
+
+	if len(unresolvedArguments) > 0 {
+		return "", schema.UnprocessableContentError(
+			fmt.Sprintf("unresolved variables %v in the query", unresolvedArguments),
+			map[string]any{
+				"collection": nqe.Request.Collection,
+				"query":      template.Query,
+			},
+		)
+	}
+
+	queryString, err := template.Render(
+		func(variable metadata.NativeQueryVariable) (string, bool, error) {
+			if value, ok := builtinValues[variable.Name]; ok &&
+				metadata.IsBuiltinVariable(variable.Name) {
+				return value, true, nil
+			}
+
+			return nqe.formatArgument(variable)
+		},
+	)
+	if err != nil {
+		return "", schema.UnprocessableContentError(err.Error(), nil)
+	}
+
 	return queryString, nil
 }
 
-func (nqe *NativeQueryExecutor) evalUnknownArguments(
-	queryString string,
-	key string,
+// formatArgument formats the argument value in the context of the variable.
+// Returns false if the value is null.
+func (nqe *NativeQueryExecutor) formatArgument(
+	variable metadata.NativeQueryVariable,
+) (string, bool, error) {
+	argInfo := nqe.NativeQuery.Arguments[variable.Name]
+
+	arg := nqe.Arguments[variable.Name]
+	if utils.IsNil(arg) {
+		arg = argInfo.Default
+	}
+
+	if utils.IsNil(arg) {
+		return "", false, nil
+	}
+
+	if !argInfo.Array {
+		value, err := nqe.formatArgumentValue(variable, argInfo, arg)
+
+		return value, err == nil, err
+	}
+
+	values, err := decodeArraySlice(arg)
+	if err != nil {
+		return "", false, fmt.Errorf("%s: %w", variable.Name, err)
+	}
+
+	if len(values) == 0 {
+		return "", false, nil
+	}
+
+	items := make([]string, len(values))
+
+	for i, value := range values {
+		if variable.Context == metadata.VariableContextLabelList {
+			items[i], err = nqe.formatArgumentValue(variable, argInfo, value)
+			if err != nil {
+				return "", false, err
+			}
+
+			continue
+		}
+
+		// elements of regex label matchers are matched literally.
+		item, err := nqe.formatStringArgument(variable.Name, argInfo, value)
+		if err != nil {
+			return "", false, err
+		}
+
+		items[i] = regexp.QuoteMeta(item)
+	}
+
+	if variable.Context == metadata.VariableContextLabelList {
+		return strings.Join(items, ", "), true, nil
+	}
+
+	value, err := metadata.EscapePromQLString(strings.Join(items, "|"), variable.Quote)
+	if err != nil {
+		return "", false, fmt.Errorf("%s: %w", variable.Name, err)
+	}
+
+	return value, true, nil
+}
+
+func (nqe *NativeQueryExecutor) formatArgumentValue(
+	variable metadata.NativeQueryVariable,
+	argInfo metadata.NativeQueryArgumentInfo,
 	arg any,
 ) (string, error) {
-	argInfo, ok := nqe.NativeQuery.Arguments[key]
-	if !ok {
-		return queryString, nil
-	}
+	argType := metadata.ScalarName(argInfo.Type)
 
-	var argString string
+	switch variable.Context {
+	case metadata.VariableContextDuration:
+		duration, err := nqe.parseDurationArgument(variable.Name, arg)
+		if err != nil {
+			return "", err
+		}
 
-	var err error
+		return duration.String(), nil
+	case metadata.VariableContextNumber:
+		return nqe.formatNumberArgument(variable.Name, argType, arg)
+	case metadata.VariableContextMetricName:
+		value, err := nqe.formatStringArgument(variable.Name, argInfo, arg)
+		if err != nil {
+			return "", err
+		}
+
+		if !model.IsValidLegacyMetricName(value) {
+			return "", fmt.Errorf(
+				"%s: invalid metric name `%s`; must match the regular expression [a-zA-Z_:][a-zA-Z0-9_:]*",
+				variable.Name,
+				value,
+			)
+		}
+
+		return value, nil
+	case metadata.VariableContextLabelList, metadata.VariableContextLabelName:
+		value, err := nqe.formatStringArgument(variable.Name, argInfo, arg)
+		if err != nil {
+			return "", err
+		}
+
+		if err := validateLabelName(value); err != nil {
+			return "", fmt.Errorf("%s: %w", variable.Name, err)
+		}
+
+		return value, nil
+	default:
+		value, err := nqe.formatStringArgument(variable.Name, argInfo, arg)
+		if err != nil {
+			return "", err
+		}
+
+		if variable.IsRegex() {
+			if err := metadata.ValidateLabelMatcherRegex(value); err != nil {
+				return "", fmt.Errorf("%s: invalid regular expression: %w", variable.Name, err)
+			}
+		}
+
+		value, err = metadata.EscapePromQLString(value, variable.Quote)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", variable.Name, err)
+		}
 
-	switch metadata.ScalarName(argInfo.Type) {
+		return value, nil
+	}
+}
+
+// formatNumberArgument formats the argument as a PromQL number literal.
+// String values must be valid numbers, so they can't change the query.
+func (nqe *NativeQueryExecutor) formatNumberArgument(
+	name string,
+	argType metadata.ScalarName,
+	arg any,
+) (string, error) {
+	switch argType {
 	case metadata.ScalarInt64:
 		argInt, err := utils.DecodeInt[int64](arg)
 		if err != nil {
-			return "", schema.UnprocessableContentError(err.Error(), nil)
+			return "", fmt.Errorf("%s: %w", name, err)
 		}
 
-		argString = strconv.FormatInt(argInt, 10)
+		return strconv.FormatInt(argInt, 10), nil
 	case metadata.ScalarFloat64:
 		argFloat, err := utils.DecodeFloat[float64](arg)
 		if err != nil {
-			return "", schema.UnprocessableContentError(err.Error(), nil)
+			return "", fmt.Errorf("%s: %w", name, err)
 		}
 
-		argString = fmt.Sprint(argFloat)
+		return fmt.Sprint(argFloat), nil
 	case metadata.ScalarDuration:
-		duration, err := nqe.Runtime.ParseRangeResolution(arg)
+		duration, err := nqe.parseDurationArgument(name, arg)
 		if err != nil {
-			return "", schema.UnprocessableContentError(err.Error(), nil)
+			return "", err
 		}
 
-		if duration == nil {
-			return "", schema.UnprocessableContentError(
-				fmt.Sprintf("argument `%s` is required", key),
-				nil,
-			)
+		if duration.Resolution != 0 {
+			return "", fmt.Errorf("%s: expected a duration, got %v", name, arg)
 		}
 
-		argString = duration.String()
+		return duration.Range.String(), nil
 	default:
-		argString, err = utils.DecodeString(arg)
+		argString, err := utils.DecodeString(arg)
 		if err != nil {
-			return "", schema.UnprocessableContentError(
-				fmt.Sprintf("%s: %s", key, err.Error()),
-				nil,
-			)
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+
+		argFloat, err := strconv.ParseFloat(argString, 64)
+		if err != nil {
+			return "", fmt.Errorf("%s: invalid number %q", name, argString)
 		}
+
+		return fmt.Sprint(argFloat), nil
+	}
+}
+
+// parseDurationArgument parses the duration of the argument.
+// The auto range is resolved by range functions of collections only, so it can't be substituted into native queries.
+func (nqe *NativeQueryExecutor) parseDurationArgument(
+	name string,
+	arg any,
+) (*metadata.RangeResolution, error) {
+	duration, err := nqe.Runtime.ParseRangeResolution(arg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
 	}
 
-	return metadata.ReplaceNativeQueryVariable(queryString, key, argString), nil
+	if duration == nil {
+		return nil, fmt.Errorf("argument `%s` is required", name)
+	}
+
+	if duration.Auto {
+		return nil, fmt.Errorf(
+			"%s: the `%s` range isn't supported in native queries",
+			name,
+			metadata.RangeResolutionAuto,
+		)
+	}
+
+	return duration, nil
+}
+
+// formatStringArgument formats the argument as an unescaped string.
+// Values of enum arguments must be in the allowed list.
+func (nqe *NativeQueryExecutor) formatStringArgument(
+	name string,
+	argInfo metadata.NativeQueryArgumentInfo,
+	arg any,
+) (string, error) {
+	argType := metadata.ScalarName(argInfo.Type)
+
+	switch argType {
+	case metadata.ScalarInt64, metadata.ScalarFloat64:
+		return nqe.formatNumberArgument(name, argType, arg)
+	case metadata.ScalarDuration:
+		duration, err := nqe.parseDurationArgument(name, arg)
+		if err != nil {
+			return "", err
+		}
+
+		return duration.String(), nil
+	default:
+		argString, err := utils.DecodeString(arg)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+
+		if len(argInfo.Enum) > 0 && !slices.Contains(argInfo.Enum, argString) {
+			return "", fmt.Errorf(
+				"%s: the value %s is not in the enum %v",
+				name,
+				argString,
+				argInfo.Enum,
+			)
+		}
+
+		return argString, nil
+	}
 }
 
 func (nqe *NativeQueryExecutor) execute(
@@ -177,11 +491,21 @@ func (nqe *NativeQueryExecutor) execute(
 
 	var rawResults []map[string]any
 
-	flat := nqe.Runtime.IsFlat(nullableFlat)
+	resultType := nqe.NativeQuery.GetResultType()
+	// scalar and string results are rows of timestamps and values.
+	flat := nqe.Runtime.IsFlat(nullableFlat) || !resultType.IsSeries()
 
-	if !utils.IsNil(params.Timestamp) {
+	switch {
+	case !utils.IsNil(params.Timestamp) || params.Range == nil:
 		rawResults, err = nqe.queryInstant(ctx, queryString, params, flat)
-	} else {
+	case !resultType.SupportsRangeQuery():
+		return nil, schema.UnprocessableContentError(
+			fmt.Sprintf("%s results are supported in instant queries only", resultType),
+			map[string]any{
+				"collection": nqe.Request.Collection,
+			},
+		)
+	default:
 		rawResults, err = nqe.queryRange(ctx, queryString, params, flat)
 	}
 
@@ -206,18 +530,52 @@ func (nqe *NativeQueryExecutor) queryInstant(
 	params *NativeQueryRequest,
 	flat bool,
 ) ([]map[string]any, error) {
-	vector, _, err := nqe.Client.Query(ctx, queryString, params.Timestamp, params.Timeout)
+	var value model.Value
+
+	var err error
+
+	if nqe.NativeQuery.GetResultType() == metadata.NativeQueryResultString {
+		value, _, err = nqe.Client.QueryString(ctx, queryString, params.Timestamp, params.Timeout)
+	} else {
+		value, _, err = nqe.Client.QueryValue(ctx, queryString, params.Timestamp, params.Timeout)
+	}
+
 	if err != nil {
 		return nil, schema.UnprocessableContentError(err.Error(), nil)
 	}
 
+	switch result := value.(type) {
+	case model.Vector:
+		return nqe.createVectorResults(ctx, result, params, flat)
+	case *model.Scalar:
+		return nqe.createVectorResults(ctx, model.Vector{
+			{Timestamp: result.Timestamp, Value: result.Value},
+		}, params, flat)
+	case model.Matrix:
+		return nqe.createMatrixResults(ctx, result, params, flat)
+	case *model.String:
+		return nqe.createStringResults(result, params)
+	default:
+		return nil, schema.UnprocessableContentError(
+			fmt.Sprintf("unsupported result type %s", value.Type()),
+			nil,
+		)
+	}
+}
+
+func (nqe *NativeQueryExecutor) createVectorResults(
+	ctx context.Context,
+	vector model.Vector,
+	params *NativeQueryRequest,
+	flat bool,
+) ([]map[string]any, error) {
 	span := trace.SpanFromContext(ctx)
 	span.AddEvent("post_filter", trace.WithAttributes(
 		utils.JSONAttribute("expression", params.Expression),
 		attribute.Int("pre_filter_count", len(vector)),
 	))
 
-	vector, err = nqe.filterVectorResults(vector, params.Expression)
+	vector, err := nqe.filterVectorResults(vector, params.Expression)
 	if err != nil {
 		return nil, schema.UnprocessableContentError(err.Error(), nil)
 	}
@@ -228,7 +586,13 @@ func (nqe *NativeQueryExecutor) queryInstant(
 	)
 	sortVector(vector, params.OrderBy)
 	vector = paginateVector(vector, nqe.Request.Query)
-	results := createQueryResultsFromVector(vector, nqe.NativeQuery.Labels, nqe.Runtime, flat)
+	results := createQueryResultsFromVector(
+		vector,
+		nqe.NativeQuery.Labels,
+		nqe.Runtime,
+		params.Location,
+		flat,
+	)
 
 	return results, nil
 }
@@ -249,13 +613,22 @@ func (nqe *NativeQueryExecutor) queryRange(
 		return nil, schema.UnprocessableContentError(err.Error(), nil)
 	}
 
+	return nqe.createMatrixResults(ctx, matrix, params, flat)
+}
+
+func (nqe *NativeQueryExecutor) createMatrixResults(
+	ctx context.Context,
+	matrix model.Matrix,
+	params *NativeQueryRequest,
+	flat bool,
+) ([]map[string]any, error) {
 	span := trace.SpanFromContext(ctx)
 	span.AddEvent("post_filter", trace.WithAttributes(
 		utils.JSONAttribute("expression", params.Expression),
 		attribute.Int("pre_filter_count", len(matrix)),
 	))
 
-	matrix, err = nqe.filterMatrixResults(matrix, params)
+	matrix, err := nqe.filterMatrixResults(matrix, params)
 	if err != nil {
 		return nil, schema.UnprocessableContentError(err.Error(), nil)
 	}
@@ -264,8 +637,51 @@ func (nqe *NativeQueryExecutor) queryRange(
 		"post_filter_results",
 		trace.WithAttributes(attribute.Int("post_filter_count", len(matrix))),
 	)
-	sortMatrix(matrix, params.OrderBy)
-	results := createQueryResultsFromMatrix(matrix, nqe.NativeQuery.Labels, nqe.Runtime, flat)
+	// summaries are calculated from original values of series before downsampling.
+	summaries := evalMatrixSummaries(
+		matrix,
+		params.OrderBy,
+		!flat && isSummarySelected(nqe.Request.Query.Fields),
+	)
+	sortMatrix(matrix, params.OrderBy, summaries)
+	matrix = downsampleMatrix(matrix, params.MaxPoints)
+	results := createQueryResultsFromMatrix(
+		matrix,
+		summaries,
+		nqe.NativeQuery.Labels,
+		nqe.Runtime,
+		params.Location,
+		flat,
+	)
+
+	return paginateQueryResults(results, nqe.Request.Query), nil
+}
+
+// createStringResults creates the row of the string result.
+// String values can't be filtered because the where expression compares sample values.
+func (nqe *NativeQueryExecutor) createStringResults(
+	result *model.String,
+	params *NativeQueryRequest,
+) ([]map[string]any, error) {
+	if params.Expression != nil {
+		return nil, schema.UnprocessableContentError(
+			"where expressions are not supported for string results",
+			map[string]any{
+				"collection": nqe.Request.Collection,
+			},
+		)
+	}
+
+	results := []map[string]any{
+		{
+			metadata.TimestampKey: formatTimestamp(
+				result.Timestamp,
+				nqe.Runtime.Format.Timestamp,
+				params.Location,
+			),
+			metadata.ValueKey: result.Value,
+		},
+	}
 
 	return paginateQueryResults(results, nqe.Request.Query), nil
 }
@@ -414,12 +830,29 @@ func (nqe *NativeQueryExecutor) validateExpressionBinaryComparisonColumn(
 	exprs *schema.ExpressionBinaryComparisonOperator,
 	target *schema.ComparisonTargetColumn,
 ) (bool, error) {
-	if target.Name == metadata.ValueKey {
+	switch target.Name {
+	case metadata.ValueKey:
 		return nqe.validateExpressionBinaryComparisonColumnValue(value, exprs)
+	case metadata.LabelsKey:
+		return nqe.validateExpressionBinaryComparisonColumnLabels(labels, exprs)
 	}
 
 	labelValue := labels[model.LabelName(target.Name)]
 
+	if nqe.NativeQuery != nil {
+		label := nqe.NativeQuery.Labels[target.Name]
+		if label.GetType() != metadata.LabelTypeString {
+			return matchTypedLabelValue(label, labelValue, *exprs, nqe.Variables)
+		}
+	}
+
+	return nqe.validateLabelValueComparison(labelValue, exprs)
+}
+
+func (nqe *NativeQueryExecutor) validateLabelValueComparison(
+	labelValue model.LabelValue,
+	exprs *schema.ExpressionBinaryComparisonOperator,
+) (bool, error) {
 	switch exprs.Operator {
 	case metadata.Equal, metadata.NotEqual, metadata.Regex, metadata.NotRegex:
 		value, err := getComparisonValueString(exprs.Value, nqe.Variables)
@@ -469,6 +902,33 @@ func (nqe *NativeQueryExecutor) validateExpressionBinaryComparisonColumn(
 	return false, nil
 }
 
+func (nqe *NativeQueryExecutor) validateExpressionBinaryComparisonColumnLabels(
+	labels model.Metric,
+	exprs *schema.ExpressionBinaryComparisonOperator,
+) (bool, error) {
+	rawValue, err := getComparisonValue(exprs.Value, nqe.Variables)
+	if err != nil {
+		return false, err
+	}
+
+	labelExprs, err := evalLabelSetComparison(exprs.Operator, rawValue)
+	if err != nil {
+		return false, err
+	}
+
+	for _, labelExpr := range labelExprs {
+		valid, err := nqe.validateLabelValueComparison(
+			labels[model.LabelName(labelExpr.Name)],
+			&labelExpr.Expressions[0],
+		)
+		if !valid || err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
 func (nqe *NativeQueryExecutor) validateExpressionBinaryComparisonColumnValue(
 	value model.SampleValue,
 	exprs *schema.ExpressionBinaryComparisonOperator,
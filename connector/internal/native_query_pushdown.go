@@ -0,0 +1,310 @@
+package internal
+
+import (
+	"slices"
+
+	"github.com/hasura/ndc-prometheus/connector/metadata"
+	"github.com/hasura/ndc-sdk-go/schema"
+	"github.com/hasura/ndc-sdk-go/utils"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// label comparison operators that are evaluated in the same way by label matchers and post-filters.
+var pushdownLabelOperators = []string{
+	metadata.Equal,
+	metadata.NotEqual,
+	metadata.In,
+	metadata.NotIn,
+}
+
+// functions that keep labels of the input series except the metric name,
+// so label matchers can be injected into the argument.
+var labelPreservingFunctions = []metadata.PromQLFunctionName{
+	metadata.Absolute,
+	metadata.Ceil,
+	metadata.Floor,
+	metadata.Round,
+	metadata.Exponential,
+	metadata.Ln,
+	metadata.Log2,
+	metadata.Log10,
+	metadata.Sqrt,
+	metadata.Sgn,
+	metadata.Clamp,
+	metadata.ClampMin,
+	metadata.ClampMax,
+	metadata.Sort,
+	metadata.SortDesc,
+	metadata.Increase,
+	metadata.Rate,
+	metadata.IRate,
+	metadata.Changes,
+	metadata.Derivative,
+	metadata.Delta,
+	metadata.IDelta,
+	metadata.Resets,
+	metadata.PredictLinear,
+	metadata.AvgOverTime,
+	metadata.MinOverTime,
+	metadata.MaxOverTime,
+	metadata.MadOverTime,
+	metadata.SumOverTime,
+	metadata.CountOverTime,
+	metadata.StddevOverTime,
+	metadata.StdvarOverTime,
+	metadata.LastOverTime,
+	metadata.PresentOverTime,
+	metadata.QuantileOverTime,
+}
+
+// evalFilterPushdown injects label and value comparisons of the where expression into the native query,
+// so series are filtered by Prometheus instead of after the whole result is fetched.
+// Label matchers are injected if the top-level expression is a selector, or an aggregation
+// and label-preserving function that keeps the label. Values are compared if the query returns a vector.
+// Comparisons that can't be injected safely are kept in the expression to be filtered after the query.
+func (nqe *NativeQueryExecutor) evalFilterPushdown(
+	params *NativeQueryRequest,
+	queryString string,
+) (string, error) {
+	if params.Expression == nil {
+		return queryString, nil
+	}
+
+	query, err := metadata.ParsePromQL(queryString)
+	if err != nil {
+		// the query is sent as it is, so the error is returned by the server.
+		return queryString, nil //nolint:nilerr
+	}
+
+	var pushed bool
+
+	var valueConditions []*schema.ExpressionBinaryComparisonOperator
+
+	remaining := []schema.ExpressionEncoder{}
+
+	for _, cond := range flattenExpressionAnd(params.Expression) {
+		binaryExpr, ok := cond.(*schema.ExpressionBinaryComparisonOperator)
+		if !ok {
+			remaining = append(remaining, cond)
+
+			continue
+		}
+
+		column, err := binaryExpr.Column.AsColumn()
+		if err != nil {
+			remaining = append(remaining, cond)
+
+			continue
+		}
+
+		if column.Name == metadata.ValueKey {
+			valueConditions = append(valueConditions, binaryExpr)
+
+			continue
+		}
+
+		ok, err = nqe.injectLabelCondition(query, column.Name, binaryExpr)
+		if err != nil {
+			return "", schema.UnprocessableContentError(err.Error(), nil)
+		}
+
+		if ok {
+			pushed = true
+		} else {
+			remaining = append(remaining, cond)
+		}
+	}
+
+	// values are compared after label matchers are injected, because the comparison wraps the query.
+	for _, cond := range valueConditions {
+		newQuery, ok, err := nqe.injectValueCondition(query, cond)
+		if err != nil {
+			return "", schema.UnprocessableContentError(err.Error(), nil)
+		}
+
+		if ok {
+			pushed = true
+			query = newQuery
+		} else {
+			remaining = append(remaining, cond)
+		}
+	}
+
+	if !pushed {
+		return queryString, nil
+	}
+
+	params.HasValueBoolExp = slices.ContainsFunc(remaining, hasValueComparison)
+
+	switch len(remaining) {
+	case 0:
+		params.Expression = nil
+	case 1:
+		params.Expression = remaining[0].Encode()
+	default:
+		params.Expression = schema.NewExpressionAnd(remaining...).Encode()
+	}
+
+	return query.String(), nil
+}
+
+// injectLabelCondition injects label matchers of the comparison into selectors of the query.
+// Returns false if the label or operator can't be compared in label matchers.
+func (nqe *NativeQueryExecutor) injectLabelCondition(
+	query parser.Expr,
+	name string,
+	expr *schema.ExpressionBinaryComparisonOperator,
+) (bool, error) {
+	if name == metadata.LabelsKey || name == labels.MetricName ||
+		!slices.Contains(pushdownLabelOperators, expr.Operator) ||
+		nqe.NativeQuery.Labels[name].GetType() != metadata.LabelTypeString ||
+		!keepsLabel(query, name) {
+		return false, nil
+	}
+
+	// null values are ignored by post-filters.
+	value, err := getComparisonValue(expr.Value, nqe.Variables)
+	if err != nil || utils.IsNil(value) {
+		return false, err
+	}
+
+	matchers, ok, err := (&LabelExpressionBuilder{
+		LabelExpression: LabelExpression{
+			Name:        name,
+			Expressions: []schema.ExpressionBinaryComparisonOperator{*expr},
+		},
+	}).Evaluate(nqe.Variables)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	injectLabelMatchers(query, matchers)
+
+	return true, nil
+}
+
+// injectValueCondition wraps the query in the comparison of sample values.
+// Returns false if the query doesn't return a vector.
+func (nqe *NativeQueryExecutor) injectValueCondition(
+	query parser.Expr,
+	expr *schema.ExpressionBinaryComparisonOperator,
+) (parser.Expr, bool, error) {
+	op, ok := valueBinaryOperators[expr.Operator]
+	if !ok || query.Type() != parser.ValueTypeVector {
+		return nil, false, nil
+	}
+
+	value, err := getComparisonValueFloat64(expr.Value, nqe.Variables)
+	if err != nil || value == nil {
+		return nil, false, err
+	}
+
+	return newValueComparisonExpr(op, query, *value), true, nil
+}
+
+// keepsLabel checks if series of the query keep the label of the input series,
+// so filtering the input series by the label is equivalent to filtering the result.
+func keepsLabel(query parser.Expr, name string) bool {
+	switch expr := query.(type) {
+	case *parser.VectorSelector:
+		return true
+	case *parser.MatrixSelector:
+		return keepsLabel(expr.VectorSelector, name)
+	case *parser.SubqueryExpr:
+		return keepsLabel(expr.Expr, name)
+	case *parser.ParenExpr:
+		return keepsLabel(expr.Expr, name)
+	case *parser.AggregateExpr:
+		// count_values adds the label of values.
+		if expr.Op == parser.COUNT_VALUES || slices.Contains(expr.Grouping, name) == expr.Without {
+			return false
+		}
+
+		return keepsLabel(expr.Expr, name)
+	case *parser.Call:
+		arg := findSeriesArgument(expr)
+
+		return arg != nil &&
+			slices.Contains(labelPreservingFunctions, metadata.PromQLFunctionName(expr.Func.Name)) &&
+			keepsLabel(arg, name)
+	default:
+		return false
+	}
+}
+
+// injectLabelMatchers appends label matchers to the selector of the query.
+// The query must be checked by keepsLabel before.
+func injectLabelMatchers(query parser.Expr, matchers []*labels.Matcher) {
+	switch expr := query.(type) {
+	case *parser.VectorSelector:
+		expr.LabelMatchers = append(expr.LabelMatchers, matchers...)
+	case *parser.MatrixSelector:
+		injectLabelMatchers(expr.VectorSelector, matchers)
+	case *parser.SubqueryExpr:
+		injectLabelMatchers(expr.Expr, matchers)
+	case *parser.ParenExpr:
+		injectLabelMatchers(expr.Expr, matchers)
+	case *parser.AggregateExpr:
+		injectLabelMatchers(expr.Expr, matchers)
+	case *parser.Call:
+		injectLabelMatchers(findSeriesArgument(expr), matchers)
+	}
+}
+
+// findSeriesArgument returns the only vector or matrix argument of the function call.
+func findSeriesArgument(call *parser.Call) parser.Expr {
+	var result parser.Expr
+
+	for _, arg := range call.Args {
+		if arg.Type() != parser.ValueTypeVector && arg.Type() != parser.ValueTypeMatrix {
+			continue
+		}
+
+		if result != nil {
+			return nil
+		}
+
+		result = arg
+	}
+
+	return result
+}
+
+// flattenExpressionAnd returns conditions of nested and expressions.
+func flattenExpressionAnd(expression schema.Expression) []schema.ExpressionEncoder {
+	switch expr := expression.Interface().(type) {
+	case *schema.ExpressionAnd:
+		var results []schema.ExpressionEncoder
+
+		for _, nestedExpr := range expr.Expressions {
+			results = append(results, flattenExpressionAnd(nestedExpr)...)
+		}
+
+		return results
+	default:
+		return []schema.ExpressionEncoder{expr}
+	}
+}
+
+// hasValueComparison checks if the expression compares sample values.
+func hasValueComparison(expression schema.ExpressionEncoder) bool {
+	switch expr := expression.(type) {
+	case *schema.ExpressionAnd:
+		return slices.ContainsFunc(expr.Expressions, func(e schema.Expression) bool {
+			return hasValueComparison(e.Interface())
+		})
+	case *schema.ExpressionOr:
+		return slices.ContainsFunc(expr.Expressions, func(e schema.Expression) bool {
+			return hasValueComparison(e.Interface())
+		})
+	case *schema.ExpressionNot:
+		return hasValueComparison(expr.Expression.Interface())
+	case *schema.ExpressionBinaryComparisonOperator:
+		column, err := expr.Column.AsColumn()
+
+		return err == nil && column.Name == metadata.ValueKey
+	default:
+		return false
+	}
+}
@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/hasura/ndc-prometheus/connector/metadata"
+	"github.com/hasura/ndc-sdk-go/schema"
+	"gotest.tools/v3/assert"
+)
+
+func TestNativeQueryEvalFilterPushdown(t *testing.T) {
+	jobEqual := schema.NewExpressionBinaryComparisonOperator(
+		*schema.NewComparisonTargetColumn("job"),
+		metadata.Equal,
+		schema.NewComparisonValueScalar("node"),
+	)
+	instanceIn := schema.NewExpressionBinaryComparisonOperator(
+		*schema.NewComparisonTargetColumn("instance"),
+		metadata.In,
+		schema.NewComparisonValueScalar([]string{"a:9090", "b:9090"}),
+	)
+	jobRegex := schema.NewExpressionBinaryComparisonOperator(
+		*schema.NewComparisonTargetColumn("job"),
+		metadata.Regex,
+		schema.NewComparisonValueScalar("no.*"),
+	)
+	codeEqual := schema.NewExpressionBinaryComparisonOperator(
+		*schema.NewComparisonTargetColumn("code"),
+		metadata.Equal,
+		schema.NewComparisonValueScalar(200),
+	)
+	valueGreater := schema.NewExpressionBinaryComparisonOperator(
+		*schema.NewComparisonTargetColumn("value"),
+		metadata.Greater,
+		schema.NewComparisonValueScalar(1),
+	)
+	nullVariable := schema.NewExpressionBinaryComparisonOperator(
+		*schema.NewComparisonTargetColumn("job"),
+		metadata.Equal,
+		schema.NewComparisonValueVariable("job"),
+	)
+
+	testCases := []struct {
+		Name            string
+		Query           string
+		Expression      schema.ExpressionEncoder
+		Expected        string
+		Remaining       schema.ExpressionEncoder
+		HasValueBoolExp bool
+	}{
+		{
+			Name:       "selector",
+			Query:      `up{env="prod"}`,
+			Expression: schema.NewExpressionAnd(jobEqual, instanceIn, valueGreater),
+			Expected:   `up{env="prod",instance=~"a:9090|b:9090",job="node"} > 1`,
+		},
+		{
+			Name:       "aggregation_keeps_label",
+			Query:      `sum by (job) (rate(http_requests_total[5m]))`,
+			Expression: schema.NewExpressionAnd(jobEqual, instanceIn),
+			Expected:   `sum by (job) (rate(http_requests_total{job="node"}[5m]))`,
+			Remaining:  instanceIn,
+		},
+		{
+			Name:       "aggregation_without",
+			Query:      `max without (instance) (up)`,
+			Expression: schema.NewExpressionAnd(jobEqual, instanceIn),
+			Expected:   `max without (instance) (up{job="node"})`,
+			Remaining:  instanceIn,
+		},
+		{
+			Name:       "binary_value",
+			Query:      `sum by (job) (up) / 2`,
+			Expression: schema.NewExpressionAnd(jobEqual, valueGreater),
+			Expected:   `(sum by (job) (up) / 2) > 1`,
+			Remaining:  jobEqual,
+		},
+		{
+			Name:            "scalar",
+			Query:           `scalar(up{job="node"})`,
+			Expression:      valueGreater,
+			Expected:        `scalar(up{job="node"})`,
+			Remaining:       valueGreater,
+			HasValueBoolExp: true,
+		},
+		{
+			Name:       "label_replace",
+			Query:      `label_replace(up, "job", "$1", "service", "(.*)")`,
+			Expression: jobEqual,
+			Expected:   `label_replace(up, "job", "$1", "service", "(.*)")`,
+			Remaining:  jobEqual,
+		},
+		{
+			Name:       "count_values",
+			Query:      `count_values by (job) ("job", up)`,
+			Expression: jobEqual,
+			Expected:   `count_values by (job) ("job", up)`,
+			Remaining:  jobEqual,
+		},
+		{
+			Name:       "unsupported_operators",
+			Query:      `up`,
+			Expression: schema.NewExpressionAnd(jobRegex, codeEqual, nullVariable),
+			Expected:   `up`,
+			Remaining:  schema.NewExpressionAnd(jobRegex, codeEqual, nullVariable),
+		},
+		{
+			Name:  "or",
+			Query: `up`,
+			Expression: schema.NewExpressionAnd(
+				schema.NewExpressionOr(jobEqual, valueGreater),
+				instanceIn,
+			),
+			Expected:        `up{instance=~"a:9090|b:9090"}`,
+			Remaining:       schema.NewExpressionOr(jobEqual, valueGreater),
+			HasValueBoolExp: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			nqe := &NativeQueryExecutor{
+				NativeQuery: &metadata.NativeQuery{
+					Query: tc.Query,
+					Labels: map[string]metadata.LabelInfo{
+						"job":      {},
+						"instance": {},
+						"code":     {Type: metadata.LabelTypeInt64},
+					},
+				},
+				Variables: map[string]any{
+					"job": nil,
+				},
+			}
+			params := &NativeQueryRequest{
+				Expression:      tc.Expression.Encode(),
+				HasValueBoolExp: true,
+			}
+
+			result, err := nqe.evalFilterPushdown(params, tc.Query)
+			assert.NilError(t, err)
+			assert.Equal(t, tc.Expected, result)
+
+			if tc.Remaining == nil {
+				assert.Assert(t, params.Expression == nil)
+			} else {
+				assert.DeepEqual(t, tc.Remaining.Encode(), params.Expression)
+			}
+
+			if tc.Expected != tc.Query {
+				assert.Equal(t, tc.HasValueBoolExp, params.HasValueBoolExp)
+			}
+		})
+	}
+}
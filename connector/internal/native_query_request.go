@@ -23,8 +23,14 @@ func EvalNativeQueryRequest(
 	variables map[string]any,
 	runtime *metadata.RuntimeSettings,
 ) (*NativeQueryRequest, error) {
+	location, err := runtime.GetLocation(nil)
+	if err != nil {
+		return nil, schema.UnprocessableContentError(err.Error(), nil)
+	}
+
 	result := &NativeQueryRequest{
 		CollectionValidatedArguments: CollectionValidatedArguments{
+			Location:  location,
 			variables: variables,
 			runtime:   runtime,
 		},
@@ -1,12 +1,18 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/hasura/ndc-prometheus/connector/client"
+	"github.com/hasura/ndc-prometheus/connector/metadata"
 	"github.com/hasura/ndc-sdk-go/schema"
+	"github.com/hasura/ndc-sdk-go/utils"
 	"github.com/prometheus/common/model"
 	"gotest.tools/v3/assert"
 )
@@ -89,6 +95,24 @@ func TestFilterVectorResults(t *testing.T) {
 			).Encode(),
 			Expected: vectorFixtures,
 		},
+		{
+			Name: "labels_eq",
+			Expression: schema.NewExpressionBinaryComparisonOperator(
+				*schema.NewComparisonTargetColumn("labels"),
+				"_eq",
+				schema.NewComparisonValueScalar(map[string]any{"job": "ndc-prometheus"}),
+			).Encode(),
+			Expected: vectorFixtures,
+		},
+		{
+			Name: "labels_contains_key",
+			Expression: schema.NewExpressionBinaryComparisonOperator(
+				*schema.NewComparisonTargetColumn("labels"),
+				"_contains_key",
+				schema.NewComparisonValueScalar("foo"),
+			).Encode(),
+			Expected: model.Vector{},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -192,5 +216,739 @@ func TestFilterMatrixResults(t *testing.T) {
 			assert.DeepEqual(t, results, tc.Expected)
 		})
 	}
+}
+
+func TestNativeQueryEvalQueryTemplate(t *testing.T) {
+	nativeQuery := &metadata.NativeQuery{
+		Query: `label_replace(rate(http_requests_total{job="${job}", path=~'${path}'}[${range}] offset ${offset}), "dst", "${dst}", "", "") > ${value}`,
+		Arguments: map[string]metadata.NativeQueryArgumentInfo{
+			"job":    {Type: string(metadata.ScalarString)},
+			"path":   {Type: string(metadata.ScalarString)},
+			"range":  {Type: string(metadata.ScalarDuration)},
+			"offset": {Type: string(metadata.ScalarDuration)},
+			"dst":    {Type: string(metadata.ScalarInt64)},
+			"value":  {},
+		},
+	}
+	assert.NilError(t, nativeQuery.Validate())
+
+	testCases := []struct {
+		Name      string
+		Arguments map[string]any
+		Expected  string
+		ErrorMsg  string
+	}{
+		{
+			Name: "success",
+			Arguments: map[string]any{
+				"job":    "node",
+				"path":   "/api/.*",
+				"range":  "5m",
+				"offset": "1h",
+				"dst":    10,
+				"value":  "0.5",
+			},
+			Expected: `label_replace(rate(http_requests_total{job="node", path=~'/api/.*'}[5m] offset 1h), "dst", "10", "", "") > 0.5`,
+		},
+		{
+			Name: "escape",
+			Arguments: map[string]any{
+				"job":    `"} or vector(1) or up{a="`,
+				"path":   `it's\d`,
+				"range":  "5m",
+				"offset": "1h",
+				"dst":    10,
+				"value":  "1",
+			},
+			Expected: `label_replace(rate(http_requests_total{job="\"} or vector(1) or up{a=\"", path=~'it\'s\\d'}[5m] offset 1h), "dst", "10", "", "") > 1`,
+		},
+		{
+			Name: "invalid_number",
+			Arguments: map[string]any{
+				"job":    "node",
+				"path":   ".*",
+				"range":  "5m",
+				"offset": "1h",
+				"dst":    10,
+				"value":  "1 or vector(1)",
+			},
+			ErrorMsg: `value: invalid number "1 or vector(1)"`,
+		},
+		{
+			Name: "invalid_duration",
+			Arguments: map[string]any{
+				"job":    "node",
+				"path":   ".*",
+				"range":  "5m] or up[5m",
+				"offset": "1h",
+				"dst":    10,
+				"value":  "1",
+			},
+			ErrorMsg: "range:",
+		},
+		{
+			Name: "auto_duration",
+			Arguments: map[string]any{
+				"job":    "node",
+				"path":   ".*",
+				"range":  "auto",
+				"offset": "1h",
+				"dst":    10,
+				"value":  "1",
+			},
+			ErrorMsg: "range: the `auto` range isn't supported in native queries",
+		},
+		{
+			Name: "invalid_regex",
+			Arguments: map[string]any{
+				"job":    "node",
+				"path":   "(",
+				"range":  "5m",
+				"offset": "1h",
+				"dst":    10,
+				"value":  "1",
+			},
+			ErrorMsg: "path: invalid regular expression",
+		},
+		{
+			Name: "unresolved",
+			Arguments: map[string]any{
+				"job": "node",
+			},
+			ErrorMsg: "unresolved variables [path range offset dst value] in the query",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			nqe := &NativeQueryExecutor{
+				Runtime:     &metadata.RuntimeSettings{},
+				Request:     &schema.QueryRequest{Collection: "test"},
+				NativeQuery: nativeQuery,
+				Arguments:   tc.Arguments,
+			}
+
+			result, err := nqe.evalQueryTemplate(nil)
+			if tc.ErrorMsg != "" {
+				assert.ErrorContains(t, err, tc.ErrorMsg)
+
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Equal(t, result, tc.Expected)
+
+			_, err = metadata.ParsePromQL(result)
+			assert.NilError(t, err)
+		})
+	}
+}
+
+func TestNativeQueryEvalIdentifierArguments(t *testing.T) {
+	nativeQuery := &metadata.NativeQuery{
+		Query: `sum by (job) (rate(${metric}{${label}="x"}[5m])) > ${value}`,
+		Arguments: map[string]metadata.NativeQueryArgumentInfo{
+			"metric": {},
+			"label":  {},
+			"value":  {},
+		},
+	}
+	assert.NilError(t, nativeQuery.Validate())
+
+	testCases := []struct {
+		Name      string
+		Arguments map[string]any
+		Expected  string
+		ErrorMsg  string
+	}{
+		{
+			Name: "success",
+			Arguments: map[string]any{
+				"metric": "http_requests_total",
+				"label":  "job",
+				"value":  "1",
+			},
+			Expected: `sum by (job) (rate(http_requests_total{job="x"}[5m])) > 1`,
+		},
+		{
+			Name: "invalid_metric_name",
+			Arguments: map[string]any{
+				"metric": "up or vector(1)",
+				"label":  "job",
+				"value":  "1",
+			},
+			ErrorMsg: "metric: invalid metric name `up or vector(1)`",
+		},
+		{
+			Name: "invalid_label_name",
+			Arguments: map[string]any{
+				"metric": "up",
+				"label":  `job="x",a`,
+				"value":  "1",
+			},
+			ErrorMsg: "label: invalid label name",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			nqe := &NativeQueryExecutor{
+				Runtime:     &metadata.RuntimeSettings{},
+				Request:     &schema.QueryRequest{Collection: "test"},
+				NativeQuery: nativeQuery,
+				Arguments:   tc.Arguments,
+			}
+
+			result, err := nqe.evalQueryTemplate(nil)
+			if tc.ErrorMsg != "" {
+				assert.ErrorContains(t, err, tc.ErrorMsg)
+
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Equal(t, result, tc.Expected)
+		})
+	}
+}
+
+func TestNativeQueryEvalMetricNameArguments(t *testing.T) {
+	testCases := map[string]metadata.NativeQuery{
+		`sum(up)`: {
+			Query:     `sum(${metric})`,
+			Arguments: map[string]metadata.NativeQueryArgumentInfo{"metric": {}},
+		},
+		`up`: {
+			Query: `${metric}`,
+			Arguments: map[string]metadata.NativeQueryArgumentInfo{
+				"metric": {Type: string(metadata.ScalarString)},
+			},
+		},
+	}
+
+	for expected, nativeQuery := range testCases {
+		t.Run(nativeQuery.Query, func(t *testing.T) {
+			assert.NilError(t, nativeQuery.Validate())
+
+			nqe := &NativeQueryExecutor{
+				Runtime:     &metadata.RuntimeSettings{},
+				Request:     &schema.QueryRequest{Collection: "test"},
+				NativeQuery: &nativeQuery,
+				Arguments:   map[string]any{"metric": "up"},
+			}
+
+			result, err := nqe.evalQueryTemplate(nil)
+			assert.NilError(t, err)
+			assert.Equal(t, expected, result)
+		})
+	}
+}
+
+func TestNativeQueryEvalOptionalArguments(t *testing.T) {
+	nativeQuery := &metadata.NativeQuery{
+		Query: `sum by (${labels}) (up{job=~"${jobs}", env="${env}"[[, namespace="${namespace}"]]})`,
+		Arguments: map[string]metadata.NativeQueryArgumentInfo{
+			"labels":    {Type: string(metadata.ScalarString), Array: true},
+			"jobs":      {Type: string(metadata.ScalarString), Array: true},
+			"env":       {Enum: []string{"dev", "prod"}, Default: "prod"},
+			"namespace": {Type: string(metadata.ScalarString), Nullable: true},
+		},
+	}
+	assert.NilError(t, nativeQuery.Validate())
+
+	testCases := []struct {
+		Name      string
+		Arguments map[string]any
+		Expected  string
+		ErrorMsg  string
+	}{
+		{
+			Name: "all",
+			Arguments: map[string]any{
+				"labels":    []any{"job", "instance"},
+				"jobs":      []any{"node", "a.b"},
+				"env":       "dev",
+				"namespace": "default",
+			},
+			Expected: `sum by (job, instance) (up{job=~"node|a\\.b", env="dev", namespace="default"})`,
+		},
+		{
+			Name: "default_and_null",
+			Arguments: map[string]any{
+				"labels":    []string{"job"},
+				"jobs":      []string{"node"},
+				"namespace": nil,
+			},
+			Expected: `sum by (job) (up{job=~"node", env="prod"})`,
+		},
+		{
+			Name: "invalid_enum",
+			Arguments: map[string]any{
+				"labels": []any{"job"},
+				"jobs":   []any{"node"},
+				"env":    "test",
+			},
+			ErrorMsg: "env: the value test is not in the enum [dev prod]",
+		},
+		{
+			Name: "invalid_label_name",
+			Arguments: map[string]any{
+				"labels": []any{"job) or vector(1"},
+				"jobs":   []any{"node"},
+			},
+			ErrorMsg: "labels: invalid label name",
+		},
+		{
+			Name: "empty_required_array",
+			Arguments: map[string]any{
+				"labels": []any{},
+				"jobs":   []any{"node"},
+			},
+			ErrorMsg: "argument `labels` is required",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			nqe := &NativeQueryExecutor{
+				Runtime:     &metadata.RuntimeSettings{},
+				Request:     &schema.QueryRequest{Collection: "test"},
+				NativeQuery: nativeQuery,
+				Arguments:   tc.Arguments,
+			}
+
+			result, err := nqe.evalQueryTemplate(nil)
+			if tc.ErrorMsg != "" {
+				assert.ErrorContains(t, err, tc.ErrorMsg)
+
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Equal(t, result, tc.Expected)
+
+			_, err = metadata.ParsePromQL(result)
+			assert.NilError(t, err)
+		})
+	}
+}
+
+func TestNativeQueryExplainCollection(t *testing.T) {
+	nativeQuery := &metadata.NativeQuery{
+		Query: `rate(http_requests_total{job="${job}"}[5m]) or vector(0)`,
+		Labels: map[string]metadata.LabelInfo{
+			"job":  {},
+			"code": {Type: metadata.LabelTypeInt64},
+		},
+		Arguments: map[string]metadata.NativeQueryArgumentInfo{
+			"job": {},
+		},
+	}
+	assert.NilError(t, nativeQuery.Validate())
+
+	testCases := []struct {
+		Name         string
+		Request      schema.QueryRequest
+		IsCollection bool
+		QueryString  string
+		Aggregates   map[string]string
+		Groups       *QueryCollectionGroupingExplainResult
+		ErrorMsg     string
+	}{
+		{
+			Name: "filter_only",
+			Request: schema.QueryRequest{
+				Collection: "http_rate",
+				Arguments: schema.QueryRequestArguments{
+					"job": schema.NewArgumentLiteral("api").Encode(),
+				},
+			},
+		},
+		{
+			Name: "fn",
+			Request: schema.QueryRequest{
+				Collection: "http_rate",
+				Arguments: schema.QueryRequestArguments{
+					"job": schema.NewArgumentLiteral("api").Encode(),
+					"fn": schema.NewArgumentLiteral([]map[string]any{
+						{"max_over_time": "1h"},
+						{"sum": []string{"code"}},
+					}).Encode(),
+				},
+				Query: schema.Query{
+					Predicate: schema.NewExpressionBinaryComparisonOperator(
+						*schema.NewComparisonTargetColumn("value"),
+						"_gt",
+						schema.NewComparisonValueScalar(1),
+					).Encode(),
+				},
+			},
+			IsCollection: true,
+			QueryString:  `sum by (code) (max_over_time((rate(http_requests_total{job="api"}[5m]) or vector(0))[1h:])) > 1`,
+			Aggregates:   map[string]string{},
+		},
+		{
+			Name: "aggregates",
+			Request: schema.QueryRequest{
+				Collection: "http_rate",
+				Arguments: schema.QueryRequestArguments{
+					"job": schema.NewArgumentLiteral("api").Encode(),
+				},
+				Query: schema.Query{
+					Aggregates: schema.QueryAggregates{
+						"value__sum": schema.NewAggregateSingleColumn("value", "sum").Encode(),
+					},
+				},
+			},
+			IsCollection: true,
+			Aggregates: map[string]string{
+				"value__sum": `sum((rate(http_requests_total{job="api"}[5m]) or vector(0)))`,
+			},
+		},
+		{
+			Name: "groups",
+			Request: schema.QueryRequest{
+				Collection: "http_rate",
+				Arguments: schema.QueryRequestArguments{
+					"job": schema.NewArgumentLiteral("api").Encode(),
+				},
+				Query: schema.Query{
+					Groups: &schema.Grouping{
+						Aggregates: schema.GroupingAggregates{
+							"count": schema.NewAggregateStarCount().Encode(),
+						},
+						Dimensions: []schema.Dimension{
+							schema.NewDimensionColumn("code", nil).Encode(),
+						},
+					},
+				},
+			},
+			IsCollection: true,
+			Aggregates:   map[string]string{},
+			Groups: &QueryCollectionGroupingExplainResult{
+				Dimensions: []string{"code"},
+				AggregateQueries: map[string]string{
+					"count": `count by (code) ((rate(http_requests_total{job="api"}[5m]) or vector(0)))`,
+				},
+			},
+		},
+		{
+			Name: "label_filter_with_aggregates",
+			Request: schema.QueryRequest{
+				Collection: "http_rate",
+				Arguments: schema.QueryRequestArguments{
+					"job": schema.NewArgumentLiteral("api").Encode(),
+					"fn": schema.NewArgumentLiteral([]map[string]any{
+						{"sum": []string{"code"}},
+					}).Encode(),
+				},
+				Query: schema.Query{
+					Predicate: schema.NewExpressionBinaryComparisonOperator(
+						*schema.NewComparisonTargetColumn("code"),
+						"_eq",
+						schema.NewComparisonValueScalar("200"),
+					).Encode(),
+				},
+			},
+			IsCollection: true,
+			ErrorMsg:     "the label code can't be filtered with the sum function",
+		},
+		{
+			Name: "label_filter",
+			Request: schema.QueryRequest{
+				Collection: "http_rate",
+				Arguments: schema.QueryRequestArguments{
+					"job": schema.NewArgumentLiteral("api").Encode(),
+					"fn": schema.NewArgumentLiteral([]map[string]any{
+						{"abs": true},
+					}).Encode(),
+				},
+				Query: schema.Query{
+					Predicate: schema.NewExpressionBinaryComparisonOperator(
+						*schema.NewComparisonTargetColumn("code"),
+						"_eq",
+						schema.NewComparisonValueScalar("200"),
+					).Encode(),
+				},
+			},
+			IsCollection: true,
+			QueryString:  `abs((rate(http_requests_total{job="api"}[5m]) or vector(0)))`,
+			Aggregates:   map[string]string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			arguments, err := utils.ResolveArgumentVariables(tc.Request.Arguments, map[string]any{})
+			assert.NilError(t, err)
+
+			nqe := &NativeQueryExecutor{
+				Runtime:     &metadata.RuntimeSettings{},
+				Request:     &tc.Request,
+				NativeQuery: nativeQuery,
+				Arguments:   arguments,
+				Variables:   map[string]any{},
+			}
+
+			assert.Equal(t, tc.IsCollection, nqe.IsCollectionQuery())
+
+			if !tc.IsCollection {
+				return
+			}
+
+			executor, result, err := nqe.ExplainCollection()
+			if tc.ErrorMsg != "" {
+				assert.ErrorContains(t, err, tc.ErrorMsg)
+
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.DeepEqual(t, nativeQuery.Labels, executor.Metric.Labels)
+			assert.Equal(t, tc.QueryString, result.QueryString)
+			assert.DeepEqual(t, tc.Aggregates, result.Aggregates)
+			assert.DeepEqual(t, tc.Groups, result.Groups)
+
+			if tc.QueryString != "" {
+				_, err = metadata.ParsePromQL(tc.QueryString)
+				assert.NilError(t, err)
+			}
+		})
+	}
+}
+
+func TestNativeQueryEvalFragments(t *testing.T) {
+	nativeQuery := &metadata.NativeQuery{
+		Query: `{{ error_ratio(http_requests_total) }} > ${threshold}`,
+		Arguments: map[string]metadata.NativeQueryArgumentInfo{
+			"job":       {Type: string(metadata.ScalarString)},
+			"threshold": {Type: string(metadata.ScalarFloat64)},
+		},
+	}
+	assert.NilError(t, nativeQuery.ExpandFragments(map[string]metadata.NativeQueryFragment{
+		"error_ratio": {
+			Query:      `(sum(rate({{ metric }}{job="${job}", code=~"5.."}[5m])) / sum(rate({{ metric }}{job="${job}"}[5m])))`,
+			Parameters: []string{"metric"},
+		},
+	}))
+	assert.NilError(t, nativeQuery.Validate())
+
+	nqe := &NativeQueryExecutor{
+		Runtime:     &metadata.RuntimeSettings{},
+		Request:     &schema.QueryRequest{Collection: "test"},
+		NativeQuery: nativeQuery,
+		Arguments: map[string]any{
+			"job":       "api",
+			"threshold": 0.1,
+		},
+	}
+
+	result, err := nqe.evalArguments(&NativeQueryRequest{})
+	assert.NilError(t, err)
+	assert.Equal(
+		t,
+		`(sum(rate(http_requests_total{job="api", code=~"5.."}[5m])) / sum(rate(http_requests_total{job="api"}[5m]))) > 0.1`,
+		result,
+	)
+}
+
+func TestNativeQueryEvalBuiltinVariables(t *testing.T) {
+	nativeQuery := &metadata.NativeQuery{
+		Query: `sum(rate(http_requests_total[$__rate_interval])) / ${__range_s} + count_over_time(up[$__range])`,
+	}
+	assert.NilError(t, nativeQuery.Validate())
+
+	testCases := []struct {
+		Name      string
+		Predicate schema.ExpressionEncoder
+		Expected  string
+		ErrorMsg  string
+	}{
+		{
+			Name: "range",
+			Predicate: schema.NewExpressionAnd(
+				schema.NewExpressionBinaryComparisonOperator(
+					*schema.NewComparisonTargetColumn(metadata.TimestampKey),
+					metadata.GreaterOrEqual,
+					schema.NewComparisonValueScalar("2024-01-01T00:00:00Z"),
+				),
+				schema.NewExpressionBinaryComparisonOperator(
+					*schema.NewComparisonTargetColumn(metadata.TimestampKey),
+					metadata.LeastOrEqual,
+					schema.NewComparisonValueScalar("2024-01-01T06:00:00Z"),
+				),
+			),
+			Expected: `sum(rate(http_requests_total[2m])) / 21600 + count_over_time(up[6h])`,
+		},
+		{
+			Name: "instant",
+			Predicate: schema.NewExpressionBinaryComparisonOperator(
+				*schema.NewComparisonTargetColumn(metadata.TimestampKey),
+				metadata.Equal,
+				schema.NewComparisonValueScalar("2024-01-01T00:00:00Z"),
+			),
+			ErrorMsg: "the built-in variable $__range_s requires the start and end of the time range",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			nqe := &NativeQueryExecutor{
+				Runtime: &metadata.RuntimeSettings{
+					ScrapeInterval: utils.ToPtr(model.Duration(30 * time.Second)),
+				},
+				Request: &schema.QueryRequest{
+					Collection: "test",
+					Query: schema.Query{
+						Predicate: tc.Predicate.Encode(),
+					},
+				},
+				NativeQuery: nativeQuery,
+				Arguments: map[string]any{
+					metadata.ArgumentKeyStep: "1m",
+				},
+			}
+
+			_, result, err := nqe.Explain(context.TODO())
+			if tc.ErrorMsg != "" {
+				assert.ErrorContains(t, err, tc.ErrorMsg)
+
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Equal(t, tc.Expected, result)
+		})
+	}
+}
+
+func TestNativeQueryExecuteResultTypes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NilError(t, r.ParseForm())
+
+		var data string
+
+		switch r.Form.Get("query") {
+		case `scalar(count(up))`:
+			data = `{"resultType":"scalar","result":[1700000000,"3"]}`
+		case `"v1.0.0"`:
+			data = `{"resultType":"string","result":[1700000000,"v1.0.0"]}`
+		case `up[1m]`:
+			data = `{"resultType":"matrix","result":[{"metric":{"job":"node"},"values":[[1699999970,"1"],[1700000000,"0"]]}]}`
+		default:
+			t.Errorf("unexpected query %s", r.Form.Get("query"))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":` + data + `}`))
+	}))
+	defer server.Close()
+
+	apiClient, err := client.NewClient(context.TODO(), client.ClientSettings{
+		URL: utils.NewEnvStringValue(server.URL),
+	})
+	assert.NilError(t, err)
+
+	testCases := []struct {
+		Name        string
+		NativeQuery metadata.NativeQuery
+		Arguments   map[string]any
+		Predicate   schema.ExpressionEncoder
+		Expected    []map[string]any
+		ErrorMsg    string
+	}{
+		{
+			Name: "scalar",
+			NativeQuery: metadata.NativeQuery{
+				Query:      `scalar(count(up))`,
+				ResultType: metadata.NativeQueryResultScalar,
+			},
+			Expected: []map[string]any{
+				{"timestamp": int64(1700000000), "value": float64(3)},
+			},
+		},
+		{
+			Name: "string",
+			NativeQuery: metadata.NativeQuery{
+				Query:      `"v1.0.0"`,
+				ResultType: metadata.NativeQueryResultString,
+			},
+			Expected: []map[string]any{
+				{"timestamp": int64(1700000000), "value": "v1.0.0"},
+			},
+		},
+		{
+			Name: "matrix",
+			NativeQuery: metadata.NativeQuery{
+				Query:      `up[1m]`,
+				ResultType: metadata.NativeQueryResultMatrix,
+			},
+			Arguments: map[string]any{
+				metadata.ArgumentKeyFlat: true,
+			},
+			Expected: []map[string]any{
+				{"timestamp": int64(1699999970), "value": float64(1)},
+				{"timestamp": int64(1700000000), "value": float64(0)},
+			},
+		},
+		{
+			Name: "string_range",
+			NativeQuery: metadata.NativeQuery{
+				Query:      `"v1.0.0"`,
+				ResultType: metadata.NativeQueryResultString,
+			},
+			Predicate: schema.NewExpressionBinaryComparisonOperator(
+				*schema.NewComparisonTargetColumn(metadata.TimestampKey),
+				metadata.Greater,
+				schema.NewComparisonValueScalar("2023-11-14T00:00:00Z"),
+			),
+			ErrorMsg: "string results are supported in instant queries only",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert.NilError(t, tc.NativeQuery.Validate())
+
+			var predicate schema.Expression
+			if tc.Predicate != nil {
+				predicate = tc.Predicate.Encode()
+			}
+
+			nqe := &NativeQueryExecutor{
+				Client: apiClient,
+				Runtime: &metadata.RuntimeSettings{
+					Format: metadata.RuntimeFormatSettings{
+						Timestamp: metadata.TimestampUnix,
+						Value:     metadata.ValueFloat64,
+					},
+				},
+				Request: &schema.QueryRequest{
+					Collection: tc.Name,
+					Query: schema.Query{
+						Fields: schema.QueryFields{
+							metadata.TimestampKey: schema.NewColumnField(metadata.TimestampKey).Encode(),
+							metadata.ValueKey:     schema.NewColumnField(metadata.ValueKey).Encode(),
+						},
+						Predicate: predicate,
+					},
+				},
+				NativeQuery: &tc.NativeQuery,
+				Arguments:   tc.Arguments,
+			}
+
+			params, queryString, err := nqe.Explain(context.TODO())
+			assert.NilError(t, err)
+
+			result, err := nqe.execute(context.TODO(), params, queryString)
+			if tc.ErrorMsg != "" {
+				assert.ErrorContains(t, err, tc.ErrorMsg)
 
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.DeepEqual(t, tc.Expected, result.Rows)
+		})
+	}
 }
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"cmp"
 	"math"
 	"slices"
 	"strings"
@@ -38,6 +39,20 @@ func sortVector(vector model.Vector, sortElements []ColumnOrder) {
 				}
 
 				return int(difference) * iOrder
+			case metadata.SummaryKey:
+				// the summary of a single sample has the same statistics as its value,
+				// except the count and standard deviation which are constant.
+				if elem.Field == metadata.SummaryFieldCount ||
+					elem.Field == metadata.SummaryFieldStddev {
+					continue
+				}
+
+				ordering := compareVectorValue(a, b)
+				if ordering == 0 {
+					continue
+				}
+
+				return ordering * iOrder
 			default:
 				if len(a.Metric) == 0 {
 					continue
@@ -83,7 +98,12 @@ func compareVectorValue(a *model.Sample, b *model.Sample) int {
 	return -1
 }
 
-func sortMatrix(matrix model.Matrix, sortElements []ColumnOrder) {
+// sortMatrix sorts series of the matrix. Summaries of series are required to order by summary fields.
+func sortMatrix(
+	matrix model.Matrix,
+	sortElements []ColumnOrder,
+	summaries map[*model.SampleStream]*SeriesSummary,
+) {
 	if len(sortElements) == 0 {
 		return
 	}
@@ -97,8 +117,34 @@ func sortMatrix(matrix model.Matrix, sortElements []ColumnOrder) {
 
 			switch elem.Name {
 			case metadata.ValueKey, metadata.TimestampKey:
-				sortSamplePair(a.Values, elem.Name, iOrder)
-				sortSamplePair(b.Values, elem.Name, iOrder)
+				// series are ordered by the last point which is also the value of the result row.
+				ordering := compareSeriesLastPoint(a, b, elem.Name)
+				if ordering == 0 {
+					continue
+				}
+
+				return ordering * iOrder
+			case metadata.SummaryKey:
+				summaryA, summaryB := summaries[a], summaries[b]
+
+				// null summaries are ordered last regardless of the direction.
+				if summaryA == nil || summaryB == nil {
+					switch {
+					case summaryA == summaryB:
+						continue
+					case summaryA == nil:
+						return 1
+					default:
+						return -1
+					}
+				}
+
+				ordering := compareSummaryField(summaryA, summaryB, elem.Field)
+				if ordering == 0 {
+					continue
+				}
+
+				return ordering * iOrder
 			default:
 				if len(a.Metric) == 0 {
 					continue
@@ -124,33 +170,43 @@ func sortMatrix(matrix model.Matrix, sortElements []ColumnOrder) {
 	})
 }
 
-func sortSamplePair(values []model.SamplePair, key string, iOrder int) {
-	slices.SortFunc(values, func(a model.SamplePair, b model.SamplePair) int {
-		switch key {
-		case metadata.ValueKey:
-			if a.Value.Equal(b.Value) {
-				return 0
-			}
+// compareSummaryField compares a statistic of non-null summaries.
+func compareSummaryField(a *SeriesSummary, b *SeriesSummary, field string) int {
+	valueA, _ := a.Get(field)
+	valueB, _ := b.Get(field)
 
-			if math.IsNaN(float64(a.Value)) {
-				return 1 * iOrder
-			}
+	return compareVectorValue(
+		&model.Sample{Value: model.SampleValue(valueA)},
+		&model.Sample{Value: model.SampleValue(valueB)},
+	)
+}
 
-			if math.IsNaN(float64(b.Value)) {
-				return -1 * iOrder
-			}
+// compareSeriesLastPoint compares the value or timestamp of the last points of series.
+// Empty series are greater than others.
+func compareSeriesLastPoint(a *model.SampleStream, b *model.SampleStream, key string) int {
+	lenA := len(a.Values)
+	lenB := len(b.Values)
 
-			if a.Value > b.Value {
-				return 1 * iOrder
-			} else {
-				return -1 * iOrder
-			}
-		case metadata.TimestampKey:
-			return int(a.Timestamp.Sub(b.Timestamp)) * iOrder
-		default:
-			return 0
-		}
-	})
+	switch {
+	case lenA == 0 && lenB == 0:
+		return 0
+	case lenA == 0:
+		return 1
+	case lenB == 0:
+		return -1
+	}
+
+	lastA := a.Values[lenA-1]
+	lastB := b.Values[lenB-1]
+
+	if key == metadata.TimestampKey {
+		return cmp.Compare(lastA.Timestamp, lastB.Timestamp)
+	}
+
+	return compareVectorValue(
+		&model.Sample{Value: lastA.Value},
+		&model.Sample{Value: lastB.Value},
+	)
 }
 
 func paginateVector(vector model.Vector, q schema.Query) model.Vector {
@@ -97,45 +97,99 @@ func TestSortAndPaginateMatrix(t *testing.T) {
 	sortMatrix(results, []ColumnOrder{{
 		Name:       metadata.ValueKey,
 		Descending: false,
-	}})
+	}}, nil)
 
 	for i := 1; i < len(results); i++ {
-		assert.Assert(t, results[i].Values[0].Value <= results[i].Values[1].Value)
+		assert.Assert(t, results[i-1].Values[1].Value <= results[i].Values[1].Value)
+		assert.Assert(t, results[i].Values[0].Timestamp < results[i].Values[1].Timestamp)
 	}
 
 	sortMatrix(results, []ColumnOrder{{
 		Name:       metadata.ValueKey,
 		Descending: true,
-	}})
+	}}, nil)
 
 	for i := 1; i < len(results); i++ {
-		assert.Assert(t, results[i].Values[0].Value >= results[i].Values[1].Value)
+		assert.Assert(t, results[i-1].Values[1].Value >= results[i].Values[1].Value)
+		assert.Assert(t, results[i].Values[0].Timestamp < results[i].Values[1].Timestamp)
 	}
 
 	sortMatrix(results, []ColumnOrder{{
 		Name:       metadata.TimestampKey,
-		Descending: false,
-	}})
+		Descending: true,
+	}}, nil)
 
 	for i := 1; i < len(results); i++ {
+		assert.Assert(t, results[i-1].Values[1].Timestamp > results[i].Values[1].Timestamp)
 		assert.Assert(t, results[i].Values[0].Timestamp < results[i].Values[1].Timestamp)
 	}
 
 	sortMatrix(results, []ColumnOrder{{
 		Name:       "instance",
 		Descending: false,
-	}})
+	}}, nil)
 
 	for i := 1; i < len(results); i++ {
 		assert.Assert(t, strings.Compare(string(results[i-1].Metric["instance"]), string(results[i].Metric["instance"])) == -1)
 	}
 
-	mapResults := createGroupQueryResultsFromMatrix(results, map[string]metadata.LabelInfo{
+	summaryOrder := []ColumnOrder{{
+		Name:       metadata.SummaryKey,
+		Field:      metadata.SummaryFieldMax,
+		Descending: true,
+	}}
+	sortMatrix(results, summaryOrder, evalMatrixSummaries(results, summaryOrder, false))
+
+	for i := 1; i < len(results); i++ {
+		assert.Assert(t, NewSeriesSummary(results[i-1].Values).Max >= NewSeriesSummary(results[i].Values).Max)
+	}
+
+	// the pagination applies to ordered series.
+	summaryOrder = []ColumnOrder{{
+		Name:       metadata.SummaryKey,
+		Field:      metadata.SummaryFieldAvg,
+		Descending: false,
+	}}
+	summaries := evalMatrixSummaries(results, summaryOrder, false)
+	sortMatrix(results, summaryOrder, summaries)
+
+	pagedResults := paginateQueryResults(createGroupQueryResultsFromMatrix(results, summaries, map[string]metadata.LabelInfo{
+		"instance": {},
+	}, &metadata.RuntimeSettings{}, time.UTC), schema.Query{
+		Limit: utils.ToPtr(3),
+	})
+	assert.Equal(t, len(pagedResults), 3)
+
+	for i, row := range pagedResults {
+		assert.Equal(t, row["instance"], string(results[i].Metric["instance"]))
+	}
+
+	mapResults := createGroupQueryResultsFromMatrix(results, summaries, map[string]metadata.LabelInfo{
 		"job":      {},
 		"instance": {},
-	}, &metadata.RuntimeSettings{})
+	}, &metadata.RuntimeSettings{}, time.UTC)
 	assert.DeepEqual(t, paginateQueryResults(mapResults, schema.Query{
 		Offset: utils.ToPtr(5),
 		Limit:  utils.ToPtr(1),
 	})[0], mapResults[5])
 }
+
+func TestSortMatrixNullSummaries(t *testing.T) {
+	matrix := model.Matrix{
+		{Metric: model.Metric{"job": "empty"}},
+		{Metric: model.Metric{"job": "low"}, Values: []model.SamplePair{{Timestamp: 1000, Value: 1}}},
+		{Metric: model.Metric{"job": "high"}, Values: []model.SamplePair{{Timestamp: 1000, Value: 9}}},
+	}
+
+	for _, descending := range []bool{false, true} {
+		orderBy := []ColumnOrder{{
+			Name:       metadata.SummaryKey,
+			Field:      metadata.SummaryFieldMax,
+			Descending: descending,
+		}}
+		sortMatrix(matrix, orderBy, evalMatrixSummaries(matrix, orderBy, false))
+		assert.Equal(t, model.LabelValue("empty"), matrix[2].Metric["job"])
+	}
+
+	assert.Equal(t, model.LabelValue("high"), matrix[0].Metric["job"])
+}
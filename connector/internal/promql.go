@@ -3,12 +3,44 @@ package internal
 import (
 	"errors"
 	"fmt"
-	"strings"
+	"slices"
+	"time"
 
 	"github.com/hasura/ndc-prometheus/connector/metadata"
 	"github.com/hasura/ndc-sdk-go/utils"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
 )
 
+const (
+	// the maximum number of UTC offset transitions of time zones that time function queries branch on,
+	// i.e. DST transitions of two years.
+	maxTimezoneShiftTransitions = 4
+	// the default lookback delta of Prometheus that samples at the start of ranges may be selected from.
+	timezoneShiftLookbackDelta = 5 * time.Minute
+)
+
+// idempotent functions that return the same result if they are applied many times.
+var idempotentFunctions = []metadata.PromQLFunctionName{
+	metadata.Absolute,
+	metadata.Ceil,
+	metadata.Floor,
+	metadata.Sgn,
+	metadata.Sort,
+	metadata.SortDesc,
+}
+
+// aggregateOperators maps names of aggregation operators to PromQL item types.
+var aggregateOperators = map[string]parser.ItemType{}
+
+func init() {
+	for itemType, name := range parser.ItemTypeStr {
+		if itemType.IsAggregator() {
+			aggregateOperators[name] = itemType
+		}
+	}
+}
+
 // ValueBoundaryInput represents the lower and upper input arguments.
 type ValueBoundaryInput struct {
 	Min float64 `mapstructure:"min"`
@@ -22,16 +54,6 @@ type LabelJoinInput struct {
 	SourceLabels []string `mapstructure:"source_labels"`
 }
 
-// String implements the fmt.Stringer interface.
-func (lji LabelJoinInput) String() string {
-	return fmt.Sprintf(
-		`"%s", "%s"%s`,
-		lji.DestLabel,
-		lji.Separator,
-		buildPromQLParametersFromStringSlice(lji.SourceLabels),
-	)
-}
-
 // LabelReplaceInput represents input arguments for the replace_label function.
 type LabelReplaceInput struct {
 	DestLabel   string `mapstructure:"dest_label"`
@@ -40,17 +62,6 @@ type LabelReplaceInput struct {
 	Regex       string `mapstructure:"regex"`
 }
 
-// String implements the fmt.Stringer interface.
-func (lri LabelReplaceInput) String() string {
-	return fmt.Sprintf(
-		`"%s", "%s", "%s", "%s"`,
-		lri.DestLabel,
-		lri.Replacement,
-		lri.SourceLabel,
-		lri.Regex,
-	)
-}
-
 // HoltWintersInput represents input arguments of the holt_winters function.
 type HoltWintersInput struct {
 	Sf    float64
@@ -175,17 +186,213 @@ func (qoti *QuantileOverTimeInput) FromValue(value any, unixTimeUnit metadata.Un
 	return nil
 }
 
-func buildPromQLParametersFromStringSlice(inputs []string) string {
-	if len(inputs) == 0 {
-		return ""
+// buildTimezoneShiftQuery shifts unix timestamp values of the query by the UTC offset of the location,
+// so PromQL time functions return calendar fields in that time zone.
+// If the offset changes in the evaluation range, e.g. DST transitions,
+// the difference is added to values that are equal or later than the transition time.
+// PromQL can't bind the value to a variable, so the query is repeated once per transition.
+// Values outside of the range are shifted by the nearest offset, and the offset at the end of the range
+// is used for all values if the range has more than maxTimezoneShiftTransitions transitions.
+func buildTimezoneShiftQuery(
+	query parser.Expr,
+	location *time.Location,
+	start, end time.Time,
+) parser.Expr {
+	if location == nil {
+		return query
+	}
+
+	// samples at the start of the range may be looked back from earlier timestamps.
+	current := start.Add(-timezoneShiftLookbackDelta).In(location)
+	_, offset := current.Zone()
+
+	var transitions []time.Time
+
+	lastOffset := offset
+
+	for {
+		_, zoneEnd := current.ZoneBounds()
+		if zoneEnd.IsZero() || zoneEnd.After(end) {
+			break
+		}
+
+		// zones may change names without changing offsets.
+		if _, nextOffset := zoneEnd.Zone(); nextOffset != lastOffset {
+			transitions = append(transitions, zoneEnd)
+			lastOffset = nextOffset
+		}
+
+		current = zoneEnd
+	}
+
+	if len(transitions) > maxTimezoneShiftTransitions {
+		_, offset = end.In(location).Zone()
+		transitions = nil
+	}
+
+	if len(transitions) == 0 {
+		if offset == 0 {
+			return query
+		}
+
+		return newBinaryExpr(parser.ADD, query, newPromQLInteger(offset))
+	}
+
+	var shift parser.Expr = &parser.NumberLiteral{Val: float64(offset)}
+
+	lastOffset = offset
+
+	for _, transition := range transitions {
+		_, nextOffset := transition.Zone()
+		shift = newBinaryExpr(
+			parser.ADD,
+			shift,
+			newBinaryExpr(
+				parser.MUL,
+				newPromQLInteger(nextOffset-lastOffset),
+				&parser.ParenExpr{
+					Expr: &parser.BinaryExpr{
+						Op:         parser.GTE,
+						LHS:        query,
+						RHS:        &parser.NumberLiteral{Val: float64(transition.Unix())},
+						ReturnBool: true,
+					},
+				},
+			),
+		)
+		lastOffset = nextOffset
+	}
+
+	return newBinaryExpr(parser.ADD, query, &parser.ParenExpr{Expr: shift})
+}
+
+// newPromQLInteger creates the integer literal in parentheses if it's negative,
+// so the expression is readable after binary operators.
+func newPromQLInteger(value int) parser.Expr {
+	var result parser.Expr = &parser.NumberLiteral{Val: float64(value)}
+
+	if value < 0 {
+		result = &parser.ParenExpr{Expr: result}
+	}
+
+	return result
+}
+
+// newMetricSelector creates the vector selector of the metric.
+// Duplicated label matchers are removed.
+func newMetricSelector(name string, matchers []*labels.Matcher) *parser.VectorSelector {
+	result := &parser.VectorSelector{
+		Name: name,
+		LabelMatchers: []*labels.Matcher{
+			labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, name),
+		},
+	}
+
+	for _, matcher := range matchers {
+		if !slices.ContainsFunc(result.LabelMatchers, func(m *labels.Matcher) bool {
+			return m.Name == matcher.Name && m.Type == matcher.Type && m.Value == matcher.Value
+		}) {
+			result.LabelMatchers = append(result.LabelMatchers, matcher)
+		}
+	}
+
+	return result
+}
+
+func newBinaryExpr(op parser.ItemType, lhs, rhs parser.Expr) *parser.BinaryExpr {
+	return &parser.BinaryExpr{
+		Op:  op,
+		LHS: lhs,
+		RHS: rhs,
+	}
+}
+
+// newValueComparisonExpr creates the comparison of sample values, e.g. up > 1.
+// Binary operators are evaluated from left to right with the same precedence,
+// so the left binary expression is wrapped in parentheses.
+func newValueComparisonExpr(
+	op parser.ItemType,
+	query parser.Expr,
+	value float64,
+) *parser.BinaryExpr {
+	if _, isBinary := query.(*parser.BinaryExpr); isBinary {
+		query = &parser.ParenExpr{Expr: query}
+	}
+
+	return newBinaryExpr(op, query, &parser.NumberLiteral{Val: value})
+}
+
+// newFunctionCall creates the PromQL function call expression.
+func newFunctionCall(name string, args ...parser.Expr) *parser.Call {
+	fn, ok := parser.Functions[name]
+	if !ok {
+		// functions that were removed from the latest Prometheus version, e.g. holt_winters.
+		fn = &parser.Function{
+			Name:       name,
+			ReturnType: parser.ValueTypeVector,
+		}
+	}
+
+	return &parser.Call{
+		Func: fn,
+		Args: args,
+	}
+}
+
+// newAggregateExpr creates the PromQL aggregation expression.
+func newAggregateExpr(
+	name string,
+	expr parser.Expr,
+	param parser.Expr,
+	grouping []string,
+) (*parser.AggregateExpr, error) {
+	op, ok := aggregateOperators[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported aggregation operator `%s`", name)
+	}
+
+	return &parser.AggregateExpr{
+		Op:       op,
+		Expr:     expr,
+		Param:    param,
+		Grouping: grouping,
+	}, nil
+}
+
+// newRangeVectorExpr selects a range of samples back from the instant vector expression.
+// Vector selectors are converted to range vector selectors, other expressions are converted to subqueries.
+func newRangeVectorExpr(
+	expr parser.Expr,
+	rng metadata.RangeResolution,
+	offset time.Duration,
+) parser.Expr {
+	if vs, ok := expr.(*parser.VectorSelector); ok && rng.Resolution == 0 {
+		if offset > 0 {
+			vs.OriginalOffset = offset
+		}
+
+		return &parser.MatrixSelector{
+			VectorSelector: vs,
+			Range:          time.Duration(rng.Range),
+		}
 	}
 
-	builder := strings.Builder{}
-	for _, str := range inputs {
-		_, _ = builder.WriteString(`, "`)
-		_, _ = builder.WriteString(str)
-		_, _ = builder.WriteRune('"')
+	return &parser.SubqueryExpr{
+		Expr:           expr,
+		Range:          time.Duration(rng.Range),
+		Step:           time.Duration(rng.Resolution),
+		OriginalOffset: offset,
 	}
+}
+
+// isRedundantFunctionCall checks if the function is idempotent and the expression is the call of the same function,
+// e.g. abs(abs(x)), so the function can be removed.
+func isRedundantFunctionCall(name string, expr parser.Expr) bool {
+	if !slices.Contains(idempotentFunctions, metadata.PromQLFunctionName(name)) {
+		return false
+	}
+
+	call, ok := expr.(*parser.Call)
 
-	return builder.String()
+	return ok && call.Func.Name == name
 }
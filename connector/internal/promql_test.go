@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"gotest.tools/v3/assert"
+)
+
+func TestBuildTimezoneShiftQuery(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	assert.NilError(t, err)
+
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	assert.NilError(t, err)
+
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, buildTimezoneShiftQuery(newFunctionCall("time"), nil, start, end).String(), "time()")
+	assert.Equal(t, buildTimezoneShiftQuery(newFunctionCall("time"), time.UTC, start, end).String(), "time()")
+	assert.Equal(t, buildTimezoneShiftQuery(newFunctionCall("time"), kolkata, start, end).String(), "time() + 19800")
+	assert.Equal(
+		t,
+		buildTimezoneShiftQuery(newFunctionCall("time"), newYork, end, end).String(),
+		"time() + (-14400)",
+	)
+	assert.Equal(
+		t,
+		buildTimezoneShiftQuery(newFunctionCall("time"), newYork, start, end).String(),
+		"time() + (-18000 + 3600 * (time() >= bool 1710054000))",
+	)
+	// samples at the start may be selected from the lookback window before the transition.
+	assert.Equal(
+		t,
+		buildTimezoneShiftQuery(
+			newFunctionCall("time"),
+			newYork,
+			time.Date(2024, 3, 10, 7, 2, 0, 0, time.UTC),
+			end,
+		).String(),
+		"time() + (-18000 + 3600 * (time() >= bool 1710054000))",
+	)
+	// the offset at the end is used if there are too many transitions.
+	assert.Equal(
+		t,
+		buildTimezoneShiftQuery(
+			newFunctionCall("time"),
+			newYork,
+			time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		).String(),
+		"time() + (-18000)",
+	)
+}
+
+func TestNewMetricSelector(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Matchers []*labels.Matcher
+		Expected string
+	}{
+		{
+			Name:     "no_matcher",
+			Expected: "up",
+		},
+		{
+			Name: "escape",
+			Matchers: []*labels.Matcher{
+				labels.MustNewMatcher(labels.MatchEqual, "job", "a\"b\\c\nd"),
+			},
+			Expected: `up{job="a\"b\\c\nd"}`,
+		},
+		{
+			Name: "merge_duplicates",
+			Matchers: []*labels.Matcher{
+				labels.MustNewMatcher(labels.MatchEqual, "job", "node"),
+				labels.MustNewMatcher(labels.MatchNotEqual, "instance", "localhost:9090"),
+				labels.MustNewMatcher(labels.MatchEqual, "job", "node"),
+			},
+			Expected: `up{instance!="localhost:9090",job="node"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			result := newMetricSelector("up", tc.Matchers)
+			assert.Equal(t, result.String(), tc.Expected)
+
+			_, err := parser.ParseExpr(result.String())
+			assert.NilError(t, err)
+		})
+	}
+}
+
+func TestIsRedundantFunctionCall(t *testing.T) {
+	selector := newMetricSelector("up", nil)
+
+	assert.Assert(t, isRedundantFunctionCall("abs", newFunctionCall("abs", selector)))
+	assert.Assert(t, !isRedundantFunctionCall("abs", newFunctionCall("ceil", selector)))
+	assert.Assert(t, !isRedundantFunctionCall("abs", selector))
+	assert.Assert(t, !isRedundantFunctionCall("exp", newFunctionCall("exp", selector)))
+}
@@ -18,6 +18,8 @@ type rawQueryParameters struct {
 	Timestamp *time.Time
 	Range     *v1.Range
 	Timeout   time.Duration
+	MaxPoints int
+	Location  *time.Location
 }
 
 type RawQueryExecutor struct {
@@ -32,9 +34,14 @@ type RawQueryExecutor struct {
 
 // Explain explains the raw promQL query request.
 func (nqe *RawQueryExecutor) Explain(ctx context.Context) (*rawQueryParameters, string, error) {
-	params := &rawQueryParameters{}
+	location, err := nqe.Runtime.GetLocation(nil)
+	if err != nil {
+		return nil, "", schema.UnprocessableContentError(err.Error(), nil)
+	}
 
-	var err error
+	params := &rawQueryParameters{
+		Location: location,
+	}
 
 	var queryString string
 
@@ -69,6 +76,16 @@ func (nqe *RawQueryExecutor) Explain(ctx context.Context) (*rawQueryParameters,
 			if err != nil {
 				return nil, "", err
 			}
+		case metadata.ArgumentKeyMaxPoints:
+			params.MaxPoints, err = decodeMaxPoints(arg)
+			if err != nil {
+				return nil, "", schema.UnprocessableContentError(err.Error(), nil)
+			}
+		case metadata.ArgumentKeyTimezone:
+			params.Location, err = decodeLocation(arg, nqe.Runtime)
+			if err != nil {
+				return nil, "", schema.UnprocessableContentError(err.Error(), nil)
+			}
 		case metadata.ArgumentKeyQuery:
 			queryString, err = utils.DecodeString(arg)
 			if err != nil {
@@ -81,6 +98,15 @@ func (nqe *RawQueryExecutor) Explain(ctx context.Context) (*rawQueryParameters,
 					nil,
 				)
 			}
+
+			if _, err := metadata.ParsePromQL(queryString); err != nil {
+				return nil, "", schema.UnprocessableContentError(
+					"invalid promQL query: "+err.Error(),
+					map[string]any{
+						"query": queryString,
+					},
+				)
+			}
 		}
 	}
 
@@ -173,6 +199,7 @@ func (nqe *RawQueryExecutor) queryInstant(
 		vector,
 		map[string]metadata.LabelInfo{},
 		nqe.Runtime,
+		params.Location,
 		flat,
 	)
 
@@ -195,10 +222,14 @@ func (nqe *RawQueryExecutor) queryRange(
 		return nil, schema.UnprocessableContentError(err.Error(), nil)
 	}
 
+	// summaries are calculated from original values of series before downsampling.
+	summaries := evalMatrixSummaries(matrix, nil, !flat && isNestedSummarySelected(nqe.selection))
 	results := createQueryResultsFromMatrix(
-		matrix,
+		downsampleMatrix(matrix, params.MaxPoints),
+		summaries,
 		map[string]metadata.LabelInfo{},
 		nqe.Runtime,
+		params.Location,
 		flat,
 	)
 
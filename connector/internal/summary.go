@@ -0,0 +1,206 @@
+package internal
+
+import (
+	"math"
+	"slices"
+
+	"github.com/hasura/ndc-prometheus/connector/metadata"
+	"github.com/hasura/ndc-sdk-go/schema"
+	"github.com/prometheus/common/model"
+)
+
+// SeriesSummary holds the statistical summary of series values.
+type SeriesSummary struct {
+	Min    float64
+	Max    float64
+	Avg    float64
+	Sum    float64
+	Count  int
+	First  float64
+	Last   float64
+	Stddev float64
+	P50    float64
+	P90    float64
+	P99    float64
+}
+
+// NewSeriesSummary calculates the statistical summary of sample pairs. NaN values are ignored.
+// Returns nil if there is no value.
+func NewSeriesSummary(values []model.SamplePair) *SeriesSummary {
+	floats := make([]float64, 0, len(values))
+
+	for _, v := range values {
+		if math.IsNaN(float64(v.Value)) {
+			continue
+		}
+
+		floats = append(floats, float64(v.Value))
+	}
+
+	count := len(floats)
+	if count == 0 {
+		return nil
+	}
+
+	result := &SeriesSummary{
+		Min:   floats[0],
+		Max:   floats[0],
+		Count: count,
+		First: floats[0],
+		Last:  floats[count-1],
+	}
+
+	for _, v := range floats {
+		result.Sum += v
+		result.Min = math.Min(result.Min, v)
+		result.Max = math.Max(result.Max, v)
+	}
+
+	result.Avg = result.Sum / float64(count)
+
+	var variance float64
+
+	for _, v := range floats {
+		delta := v - result.Avg
+		variance += delta * delta
+	}
+
+	result.Stddev = math.Sqrt(variance / float64(count))
+
+	slices.Sort(floats)
+	result.P50 = quantileOfSorted(floats, 0.5)
+	result.P90 = quantileOfSorted(floats, 0.9)
+	result.P99 = quantileOfSorted(floats, 0.99)
+
+	return result
+}
+
+// evalMatrixSummaries calculates summaries of series from their original values before series are downsampled.
+// Summaries are calculated only if the summary field is selected or orders series, otherwise returns nil.
+func evalMatrixSummaries(
+	matrix model.Matrix,
+	sortElements []ColumnOrder,
+	selected bool,
+) map[*model.SampleStream]*SeriesSummary {
+	if !selected && !slices.ContainsFunc(sortElements, func(elem ColumnOrder) bool {
+		return elem.Name == metadata.SummaryKey
+	}) {
+		return nil
+	}
+
+	summaries := make(map[*model.SampleStream]*SeriesSummary, len(matrix))
+
+	for _, series := range matrix {
+		summaries[series] = NewSeriesSummary(series.Values)
+	}
+
+	return summaries
+}
+
+// isSummarySelected checks if the summary column is selected by query fields.
+// All columns are selected if there is no field.
+func isSummarySelected(fields schema.QueryFields) bool {
+	if fields == nil {
+		return true
+	}
+
+	for _, field := range fields {
+		if column, ok := field.Interface().(*schema.ColumnField); ok &&
+			column.Column == metadata.SummaryKey {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isNestedSummarySelected checks if the summary column is selected by nested fields of function results.
+func isNestedSummarySelected(selection schema.NestedField) bool {
+	if selection.IsNil() {
+		return true
+	}
+
+	switch nested := selection.Interface().(type) {
+	case *schema.NestedArray:
+		return isNestedSummarySelected(nested.Fields)
+	case *schema.NestedObject:
+		return isSummarySelected(nested.Fields)
+	default:
+		return true
+	}
+}
+
+// Get returns the value of the summary field.
+func (ss SeriesSummary) Get(field string) (float64, bool) {
+	switch field {
+	case metadata.SummaryFieldMin:
+		return ss.Min, true
+	case metadata.SummaryFieldMax:
+		return ss.Max, true
+	case metadata.SummaryFieldAvg:
+		return ss.Avg, true
+	case metadata.SummaryFieldSum:
+		return ss.Sum, true
+	case metadata.SummaryFieldCount:
+		return float64(ss.Count), true
+	case metadata.SummaryFieldFirst:
+		return ss.First, true
+	case metadata.SummaryFieldLast:
+		return ss.Last, true
+	case metadata.SummaryFieldStddev:
+		return ss.Stddev, true
+	case metadata.SummaryFieldP50:
+		return ss.P50, true
+	case metadata.SummaryFieldP90:
+		return ss.P90, true
+	case metadata.SummaryFieldP99:
+		return ss.P99, true
+	default:
+		return 0, false
+	}
+}
+
+// ToMap serializes the summary to a map with the format setting.
+func (ss SeriesSummary) ToMap(format metadata.RuntimeFormatSettings) map[string]any {
+	result := map[string]any{
+		metadata.SummaryFieldCount: ss.Count,
+	}
+
+	for _, key := range metadata.SummaryFields {
+		if key == metadata.SummaryFieldCount {
+			continue
+		}
+
+		value, _ := ss.Get(key)
+		result[key] = formatValue(model.SampleValue(value), format)
+	}
+
+	return result
+}
+
+func formatSeriesSummary(
+	summary *SeriesSummary,
+	format metadata.RuntimeFormatSettings,
+) map[string]any {
+	if summary == nil {
+		return nil
+	}
+
+	return summary.ToMap(format)
+}
+
+// calculate the φ-quantile of sorted values with linear interpolation,
+// which is the same as the quantile_over_time function of Prometheus.
+func quantileOfSorted(values []float64, q float64) float64 {
+	length := len(values)
+	if length == 0 {
+		return math.NaN()
+	}
+
+	rank := q * float64(length-1)
+	lowerIndex := int(math.Max(0, math.Floor(rank)))
+	upperIndex := int(math.Min(float64(length-1), float64(lowerIndex+1)))
+	weight := rank - math.Floor(rank)
+
+	return values[lowerIndex]*(1-weight) + values[upperIndex]*weight
+}
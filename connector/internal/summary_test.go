@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/hasura/ndc-prometheus/connector/metadata"
+	"github.com/hasura/ndc-sdk-go/schema"
+	"github.com/prometheus/common/model"
+	"gotest.tools/v3/assert"
+)
+
+func TestSeriesSummary(t *testing.T) {
+	values := []model.SamplePair{}
+	for i, v := range []float64{4, 2, math.NaN(), 8, 6, 10} {
+		values = append(values, model.SamplePair{
+			Timestamp: model.Time(i * 1000),
+			Value:     model.SampleValue(v),
+		})
+	}
+
+	result := NewSeriesSummary(values)
+	assert.Assert(t, math.Abs(result.P90-9.2) < 1e-9)
+	assert.Assert(t, math.Abs(result.P99-9.92) < 1e-9)
+
+	result.P90 = 0
+	result.P99 = 0
+	assert.DeepEqual(t, *result, SeriesSummary{
+		Min:    2,
+		Max:    10,
+		Avg:    6,
+		Sum:    30,
+		Count:  5,
+		First:  4,
+		Last:   10,
+		Stddev: math.Sqrt(8),
+		P50:    6,
+	})
+
+	assert.DeepEqual(t, result.ToMap(metadata.RuntimeFormatSettings{
+		Value: metadata.ValueFloat64,
+	})[metadata.SummaryFieldCount], 5)
+
+	assert.Assert(t, NewSeriesSummary(nil) == nil)
+	assert.Assert(t, NewSeriesSummary([]model.SamplePair{{Value: model.SampleValue(math.NaN())}}) == nil)
+}
+
+func TestEvalSummaryOrderBy(t *testing.T) {
+	orderBy, err := evalCollectionOrderBy(&schema.OrderBy{
+		Elements: []schema.OrderByElement{
+			{
+				OrderDirection: schema.OrderDirectionDesc,
+				Target: schema.NewOrderByColumn(metadata.SummaryKey, nil).
+					WithFieldPath([]string{metadata.SummaryFieldP99}).
+					Encode(),
+			},
+		},
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, orderBy, []ColumnOrder{
+		{Name: metadata.SummaryKey, Field: metadata.SummaryFieldP99, Descending: true},
+	})
+
+	_, err = evalCollectionOrderBy(&schema.OrderBy{
+		Elements: []schema.OrderByElement{
+			{
+				OrderDirection: schema.OrderDirectionAsc,
+				Target:         schema.NewOrderByColumn(metadata.SummaryKey, nil).Encode(),
+			},
+		},
+	})
+	assert.ErrorContains(t, err, "requires a field path")
+}
+
+func TestEvalMatrixSummaries(t *testing.T) {
+	values := []model.SamplePair{}
+	for i := range 20 {
+		values = append(values, model.SamplePair{
+			Timestamp: model.Time(i * 1000),
+			Value:     model.SampleValue(i % 7),
+		})
+	}
+
+	matrix := model.Matrix{{Metric: model.Metric{"job": "node"}, Values: values}}
+
+	assert.Assert(t, evalMatrixSummaries(matrix, nil, false) == nil)
+
+	// summaries are calculated from original values, not from downsampled points.
+	summaries := evalMatrixSummaries(matrix, nil, true)
+	results := createGroupQueryResultsFromMatrix(
+		downsampleMatrix(matrix, 5),
+		summaries,
+		map[string]metadata.LabelInfo{},
+		&metadata.RuntimeSettings{},
+		time.UTC,
+	)
+	assert.Equal(t, 5, len(results[0][metadata.ValuesKey].([]map[string]any)))
+	assert.Equal(t, 20, results[0][metadata.SummaryKey].(map[string]any)[metadata.SummaryFieldCount])
+
+	summaries = evalMatrixSummaries(matrix, []ColumnOrder{
+		{Name: metadata.SummaryKey, Field: metadata.SummaryFieldMax},
+	}, false)
+	assert.Equal(t, 6.0, summaries[matrix[0]].Max)
+}
+
+func TestIsSummarySelected(t *testing.T) {
+	assert.Assert(t, isSummarySelected(nil))
+	assert.Assert(t, !isSummarySelected(schema.QueryFields{
+		"value": schema.NewColumnField(metadata.ValueKey).Encode(),
+	}))
+	assert.Assert(t, isSummarySelected(schema.QueryFields{
+		"stats": schema.NewColumnField(metadata.SummaryKey).Encode(),
+	}))
+
+	assert.Assert(t, !isNestedSummarySelected(schema.NewNestedArray(schema.NewNestedObject(map[string]schema.FieldEncoder{
+		"value": schema.NewColumnField(metadata.ValueKey),
+	})).Encode()))
+	assert.Assert(t, isNestedSummarySelected(nil))
+}
@@ -0,0 +1,180 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/hasura/ndc-prometheus/connector/metadata"
+	"github.com/hasura/ndc-sdk-go/schema"
+	"github.com/hasura/ndc-sdk-go/utils"
+	"github.com/prometheus/common/model"
+)
+
+// TimestampBucket represents the calendar-aware bucket of the timestamp grouping dimension.
+type TimestampBucket struct {
+	Granularity metadata.TimestampBucketGranularity
+	Location    *time.Location
+}
+
+type timestampBucketInput struct {
+	Granularity string  `mapstructure:"granularity"`
+	Timezone    *string `mapstructure:"timezone"`
+}
+
+// decodeTimestampBucket decodes and validates the timestamp_bucket argument.
+func decodeTimestampBucket(value any) (*TimestampBucket, error) {
+	if utils.IsNil(value) {
+		return nil, nil
+	}
+
+	var input timestampBucketInput
+	if err := mapstructure.Decode(value, &input); err != nil {
+		return nil, fmt.Errorf("invalid timestamp_bucket argument: %w", err)
+	}
+
+	granularity := metadata.TimestampBucketGranularity(input.Granularity)
+	if !slices.Contains(metadata.TimestampBucketGranularities, granularity) {
+		return nil, fmt.Errorf(
+			"invalid timestamp_bucket granularity; expected one of %v, got: %s",
+			metadata.TimestampBucketGranularities,
+			input.Granularity,
+		)
+	}
+
+	result := &TimestampBucket{
+		Granularity: granularity,
+	}
+
+	if input.Timezone != nil && *input.Timezone != "" {
+		loc, err := time.LoadLocation(*input.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp_bucket timezone: %w", err)
+		}
+
+		result.Location = loc
+	}
+
+	return result, nil
+}
+
+// Truncate returns the start time of the bucket that the timestamp belongs to.
+// Days, weeks, months and years start at the local midnight of the time zone, which may be
+// shifted by DST transitions. Weeks start on Monday.
+func (tb TimestampBucket) Truncate(t time.Time) time.Time {
+	t = t.In(tb.Location)
+
+	switch tb.Granularity {
+	case metadata.TimestampBucketMinute:
+		// subtract the elapsed duration instead of rebuilding the local time,
+		// so buckets in the repeated hour of a DST transition aren't merged.
+		return t.Add(-time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+	case metadata.TimestampBucketHour:
+		return t.Add(-time.Duration(t.Minute())*time.Minute -
+			time.Duration(t.Second())*time.Second -
+			time.Duration(t.Nanosecond()))
+	case metadata.TimestampBucketDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, tb.Location)
+	case metadata.TimestampBucketWeek:
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+
+		return time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, tb.Location)
+	case metadata.TimestampBucketMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, tb.Location)
+	case metadata.TimestampBucketYear:
+		return time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, tb.Location)
+	default:
+		return t
+	}
+}
+
+// MaxStep returns the maximum step that keeps some samples in every bucket.
+func (tb TimestampBucket) MaxStep() time.Duration {
+	switch tb.Granularity {
+	case metadata.TimestampBucketMinute:
+		return time.Minute
+	case metadata.TimestampBucketHour, metadata.TimestampBucketDay:
+		return time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
+
+// validateTimestampBucketAggregate checks if the aggregate can be combined across samples of a bucket.
+func validateTimestampBucketAggregate(aggregate schema.Aggregate) error {
+	function := getAggregateFunctionName(aggregate)
+
+	switch metadata.PromQLFunctionName(function) {
+	case metadata.Sum, metadata.Min, metadata.Max, metadata.Avg, metadata.Count:
+		return nil
+	default:
+		return fmt.Errorf(
+			"the %s aggregate can not be combined in timestamp buckets; supported functions: sum, min, max, avg, count",
+			function,
+		)
+	}
+}
+
+func getAggregateFunctionName(aggregate schema.Aggregate) string {
+	switch agg := aggregate.Interface().(type) {
+	case *schema.AggregateStarCount, *schema.AggregateColumnCount:
+		return string(metadata.Count)
+	case *schema.AggregateSingleColumn:
+		return agg.Function
+	default:
+		return ""
+	}
+}
+
+// bucketAccumulator combines sample values of a bucket with the aggregate function.
+type bucketAccumulator struct {
+	function metadata.PromQLFunctionName
+	sum      float64
+	min      float64
+	max      float64
+	count    int
+}
+
+func (ba *bucketAccumulator) Add(value model.SampleValue) {
+	v := float64(value)
+	if math.IsNaN(v) {
+		return
+	}
+
+	if ba.count == 0 {
+		ba.min = v
+		ba.max = v
+	} else {
+		ba.min = math.Min(ba.min, v)
+		ba.max = math.Max(ba.max, v)
+	}
+
+	ba.sum += v
+	ba.count++
+}
+
+// Value returns the combined value of the bucket.
+func (ba bucketAccumulator) Value() model.SampleValue {
+	if ba.count == 0 {
+		return model.SampleValue(math.NaN())
+	}
+
+	switch ba.function {
+	case metadata.Sum:
+		return model.SampleValue(ba.sum)
+	case metadata.Min:
+		return model.SampleValue(ba.min)
+	case metadata.Max:
+		return model.SampleValue(ba.max)
+	case metadata.Avg:
+		return model.SampleValue(ba.sum / float64(ba.count))
+	case metadata.Count:
+		// the number of series is counted at each step,
+		// so the count of the bucket is the maximum number of series.
+		return model.SampleValue(ba.max)
+	default:
+		return model.SampleValue(math.NaN())
+	}
+}
@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/hasura/ndc-prometheus/connector/metadata"
+	"github.com/hasura/ndc-sdk-go/schema"
+	"github.com/prometheus/common/model"
+	"gotest.tools/v3/assert"
+)
+
+func TestTimestampBucketTruncate(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.NilError(t, err)
+
+	testCases := []struct {
+		Name        string
+		Granularity metadata.TimestampBucketGranularity
+		Location    *time.Location
+		Input       string
+		Expected    string
+	}{
+		{
+			Name:        "minute",
+			Granularity: metadata.TimestampBucketMinute,
+			Location:    time.UTC,
+			Input:       "2024-10-27T10:15:45Z",
+			Expected:    "2024-10-27T10:15:00Z",
+		},
+		{
+			Name:        "hour_dst_repeated",
+			Granularity: metadata.TimestampBucketHour,
+			Location:    berlin,
+			Input:       "2024-10-27T01:30:00Z",
+			Expected:    "2024-10-27T01:00:00Z",
+		},
+		{
+			Name:        "day_timezone",
+			Granularity: metadata.TimestampBucketDay,
+			Location:    berlin,
+			Input:       "2024-10-26T22:30:00Z",
+			Expected:    "2024-10-26T22:00:00Z",
+		},
+		{
+			Name:        "day_dst_end",
+			Granularity: metadata.TimestampBucketDay,
+			Location:    berlin,
+			Input:       "2024-10-27T20:00:00Z",
+			Expected:    "2024-10-26T22:00:00Z",
+		},
+		{
+			Name:        "week",
+			Granularity: metadata.TimestampBucketWeek,
+			Location:    time.UTC,
+			Input:       "2024-10-27T20:00:00Z",
+			Expected:    "2024-10-21T00:00:00Z",
+		},
+		{
+			Name:        "month_leap_year",
+			Granularity: metadata.TimestampBucketMonth,
+			Location:    berlin,
+			Input:       "2024-02-29T23:30:00Z",
+			Expected:    "2024-02-29T23:00:00Z",
+		},
+		{
+			Name:        "year",
+			Granularity: metadata.TimestampBucketYear,
+			Location:    time.UTC,
+			Input:       "2024-10-27T20:00:00Z",
+			Expected:    "2024-01-01T00:00:00Z",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			input, err := time.Parse(time.RFC3339, tc.Input)
+			assert.NilError(t, err)
+
+			bucket := TimestampBucket{Granularity: tc.Granularity, Location: tc.Location}
+			assert.Equal(t, bucket.Truncate(input).UTC().Format(time.RFC3339), tc.Expected)
+		})
+	}
+}
+
+func TestDecodeTimestampBucket(t *testing.T) {
+	result, err := decodeTimestampBucket(nil)
+	assert.NilError(t, err)
+	assert.Assert(t, result == nil)
+
+	result, err = decodeTimestampBucket(map[string]any{
+		"granularity": "day",
+		"timezone":    "Asia/Ho_Chi_Minh",
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, result.Granularity, metadata.TimestampBucketDay)
+	assert.Equal(t, result.Location.String(), "Asia/Ho_Chi_Minh")
+
+	_, err = decodeTimestampBucket(map[string]any{"granularity": "decade"})
+	assert.ErrorContains(t, err, "invalid timestamp_bucket granularity")
+
+	_, err = decodeTimestampBucket(map[string]any{"granularity": "day", "timezone": "Mars/Base"})
+	assert.ErrorContains(t, err, "invalid timestamp_bucket timezone")
+
+	assert.ErrorContains(
+		t,
+		validateTimestampBucketAggregate(schema.NewAggregateSingleColumn("value", "stddev").Encode()),
+		"can not be combined",
+	)
+}
+
+func TestMergeMatrixToBucketGroups(t *testing.T) {
+	start := model.TimeFromUnixNano(time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC).UnixNano())
+	series := &model.SampleStream{
+		Metric: model.Metric{"job": "node"},
+	}
+
+	// 48 hourly points in 2 days.
+	for i := range 48 {
+		series.Values = append(series.Values, model.SamplePair{
+			Timestamp: start.Add(time.Duration(i) * time.Hour),
+			Value:     model.SampleValue(i),
+		})
+	}
+
+	series.Values[5].Value = model.SampleValue(math.NaN())
+
+	runtime := &metadata.RuntimeSettings{
+		Format: metadata.RuntimeFormatSettings{
+			Timestamp: metadata.TimestampRFC3339,
+			Value:     metadata.ValueFloat64,
+		},
+	}
+	results := NewGroupResults(runtime, nil, time.UTC, &TimestampBucket{
+		Granularity: metadata.TimestampBucketDay,
+		Location:    time.UTC,
+	})
+	dimensions := []string{"job", metadata.TimestampKey}
+
+	results.MergeMatrixToAggregateGroups(model.Matrix{series}, "sum", "sum", dimensions)
+	results.MergeMatrixToAggregateGroups(model.Matrix{series}, "max", "max", dimensions)
+	results.MergeMatrixToAggregateGroups(model.Matrix{series}, "avg", "avg", dimensions)
+
+	assert.Equal(t, len(results.groups), 2)
+	assert.DeepEqual(t, results.groups[0].Dimensions, []any{
+		"node",
+		"2024-10-01T00:00:00Z",
+	})
+	assert.DeepEqual(t, results.groups[0].Aggregates, schema.GroupAggregates{
+		"sum": float64(276 - 5),
+		"max": float64(23),
+		"avg": float64(276-5) / 23,
+	})
+	assert.DeepEqual(t, results.groups[1].Aggregates, schema.GroupAggregates{
+		"sum": float64(852),
+		"max": float64(47),
+		"avg": float64(852) / 24,
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"reflect"
 	"slices"
 	"strconv"
 	"time"
@@ -18,12 +19,13 @@ func createQueryResultsFromVector(
 	vector model.Vector,
 	labels map[string]metadata.LabelInfo,
 	runtime *metadata.RuntimeSettings,
+	location *time.Location,
 	flat bool,
 ) []map[string]any {
 	results := make([]map[string]any, len(vector))
 
 	for i, item := range vector {
-		ts := formatTimestamp(item.Timestamp, runtime.Format.Timestamp)
+		ts := formatTimestamp(item.Timestamp, runtime.Format.Timestamp, location)
 		value := formatValue(item.Value, runtime.Format)
 		r := map[string]any{
 			metadata.TimestampKey: ts,
@@ -31,17 +33,23 @@ func createQueryResultsFromVector(
 			metadata.LabelsKey:    item.Metric,
 		}
 
-		for label := range labels {
-			r[label] = string(item.Metric[model.LabelName(label)])
+		for label, info := range labels {
+			r[label] = formatLabelValue(item.Metric[model.LabelName(label)], info, runtime.Format)
 		}
 
-		if !flat {
+		if flat {
+			r[metadata.SummaryKey] = nil
+		} else {
 			r[metadata.ValuesKey] = []map[string]any{
 				{
 					metadata.TimestampKey: ts,
 					metadata.ValueKey:     value,
 				},
 			}
+			r[metadata.SummaryKey] = formatSeriesSummary(
+				NewSeriesSummary([]model.SamplePair{{Timestamp: item.Timestamp, Value: item.Value}}),
+				runtime.Format,
+			)
 		}
 
 		results[i] = r
@@ -52,21 +60,25 @@ func createQueryResultsFromVector(
 
 func createQueryResultsFromMatrix(
 	matrix model.Matrix,
+	summaries map[*model.SampleStream]*SeriesSummary,
 	labels map[string]metadata.LabelInfo,
 	runtime *metadata.RuntimeSettings,
+	location *time.Location,
 	flat bool,
 ) []map[string]any {
 	if flat {
-		return createFlatQueryResultsFromMatrix(matrix, labels, runtime)
+		return createFlatQueryResultsFromMatrix(matrix, labels, runtime, location)
 	}
 
-	return createGroupQueryResultsFromMatrix(matrix, labels, runtime)
+	return createGroupQueryResultsFromMatrix(matrix, summaries, labels, runtime, location)
 }
 
 func createGroupQueryResultsFromMatrix(
 	matrix model.Matrix,
+	summaries map[*model.SampleStream]*SeriesSummary,
 	labels map[string]metadata.LabelInfo,
 	runtime *metadata.RuntimeSettings,
+	location *time.Location,
 ) []map[string]any {
 	results := make([]map[string]any, len(matrix))
 
@@ -75,15 +87,15 @@ func createGroupQueryResultsFromMatrix(
 			metadata.LabelsKey: item.Metric,
 		}
 
-		for label := range labels {
-			r[label] = string(item.Metric[model.LabelName(label)])
+		for label, info := range labels {
+			r[label] = formatLabelValue(item.Metric[model.LabelName(label)], info, runtime.Format)
 		}
 
 		valuesLen := len(item.Values)
 		values := make([]map[string]any, valuesLen)
 
 		for i, value := range item.Values {
-			ts := formatTimestamp(value.Timestamp, runtime.Format.Timestamp)
+			ts := formatTimestamp(value.Timestamp, runtime.Format.Timestamp, location)
 			v := formatValue(value.Value, runtime.Format)
 			values[i] = map[string]any{
 				metadata.TimestampKey: ts,
@@ -97,6 +109,7 @@ func createGroupQueryResultsFromMatrix(
 		}
 
 		r[metadata.ValuesKey] = values
+		r[metadata.SummaryKey] = formatSeriesSummary(summaries[item], runtime.Format)
 		results[i] = r
 	}
 
@@ -107,22 +120,28 @@ func createFlatQueryResultsFromMatrix(
 	matrix model.Matrix,
 	labels map[string]metadata.LabelInfo,
 	runtime *metadata.RuntimeSettings,
+	location *time.Location,
 ) []map[string]any {
 	results := []map[string]any{}
 
 	for _, item := range matrix {
 		for _, value := range item.Values {
-			ts := formatTimestamp(value.Timestamp, runtime.Format.Timestamp)
+			ts := formatTimestamp(value.Timestamp, runtime.Format.Timestamp, location)
 			v := formatValue(value.Value, runtime.Format)
 			r := map[string]any{
 				metadata.LabelsKey:    item.Metric,
 				metadata.TimestampKey: ts,
 				metadata.ValueKey:     v,
 				metadata.ValuesKey:    nil,
+				metadata.SummaryKey:   nil,
 			}
 
-			for label := range labels {
-				r[label] = string(item.Metric[model.LabelName(label)])
+			for label, info := range labels {
+				r[label] = formatLabelValue(
+					item.Metric[model.LabelName(label)],
+					info,
+					runtime.Format,
+				)
 			}
 
 			results = append(results, r)
@@ -132,7 +151,9 @@ func createFlatQueryResultsFromMatrix(
 	return results
 }
 
-func formatTimestamp(ts model.Time, format metadata.TimestampFormat) any {
+// formatTimestamp formats the timestamp. RFC3339 strings are formatted in the location,
+// or UTC if the location is nil.
+func formatTimestamp(ts model.Time, format metadata.TimestampFormat, location *time.Location) any {
 	switch format {
 	case metadata.TimestampUnix:
 		return ts.Unix()
@@ -143,7 +164,11 @@ func formatTimestamp(ts model.Time, format metadata.TimestampFormat) any {
 	case metadata.TimestampUnixNano:
 		return strconv.FormatInt(ts.UnixNano(), 10)
 	default:
-		return ts.Time().Format(time.RFC3339)
+		if location == nil {
+			location = time.UTC
+		}
+
+		return ts.Time().In(location).Format(time.RFC3339)
 	}
 }
 
@@ -191,6 +216,26 @@ func decodeStringSlice(value any) ([]string, error) {
 	return sliceValue, nil
 }
 
+// decodeArraySlice decodes elements of any slice value.
+func decodeArraySlice(value any) ([]any, error) {
+	if values, ok := value.([]any); ok {
+		return values, nil
+	}
+
+	reflectValue := reflect.ValueOf(value)
+	if reflectValue.Kind() != reflect.Slice && reflectValue.Kind() != reflect.Array {
+		return nil, fmt.Errorf("expected an array, got %v", value)
+	}
+
+	results := make([]any, reflectValue.Len())
+
+	for i := range results {
+		results[i] = reflectValue.Index(i).Interface()
+	}
+
+	return results, nil
+}
+
 func intersection[T comparable](sliceA []T, sliceB []T) []T {
 	var result []T
 
@@ -282,3 +327,13 @@ func equalSlice(as, bs []any) bool {
 
 	return true
 }
+
+// decodeLocation decodes the timezone argument. Use the runtime setting if the value is null.
+func decodeLocation(value any, runtime *metadata.RuntimeSettings) (*time.Location, error) {
+	timezone, err := utils.DecodeNullableString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone argument: %w", err)
+	}
+
+	return runtime.GetLocation(timezone)
+}
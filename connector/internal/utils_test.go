@@ -0,0 +1,22 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hasura/ndc-prometheus/connector/metadata"
+	"github.com/prometheus/common/model"
+	"gotest.tools/v3/assert"
+)
+
+func TestFormatTimestamp(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.NilError(t, err)
+
+	ts := model.TimeFromUnix(time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC).Unix())
+
+	assert.Equal(t, formatTimestamp(ts, metadata.TimestampRFC3339, nil), "2024-10-27T00:30:00Z")
+	assert.Equal(t, formatTimestamp(ts, metadata.TimestampRFC3339, berlin), "2024-10-27T02:30:00+02:00")
+	assert.Equal(t, formatTimestamp(ts.Add(2*time.Hour), metadata.TimestampRFC3339, berlin), "2024-10-27T03:30:00+01:00")
+	assert.Equal(t, formatTimestamp(ts, metadata.TimestampUnix, berlin), ts.Unix())
+}
@@ -1,11 +1,14 @@
 package metadata
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/hasura/ndc-prometheus/connector/client"
 	"github.com/hasura/ndc-sdk-go/utils"
+	"github.com/prometheus/common/model"
 	"gopkg.in/yaml.v3"
 )
 
@@ -32,17 +35,42 @@ const (
 // MetricsGeneratorSettings contain settings for the metrics generation.
 type MetricsGeneratorSettings struct {
 	// Enable the metrics generation
-	Enabled  bool                      `json:"enabled"        yaml:"enabled"`
-	Behavior MetricsGenerationBehavior `json:"behavior"       yaml:"behavior"       jsonschema:"enum=merge,enum=replace"`
+	Enabled  bool                      `json:"enabled"                         yaml:"enabled"`
+	Behavior MetricsGenerationBehavior `json:"behavior"                        yaml:"behavior"                        jsonschema:"enum=merge,enum=replace"`
 	// Include metrics with regular expression matching. Include all metrics by default
-	Include []string `json:"include"        yaml:"include"`
+	Include []string `json:"include"                         yaml:"include"`
 	// Exclude metrics with regular expression matching.
 	// Note: exclude is higher priority than include
-	Exclude []string `json:"exclude"        yaml:"exclude"`
+	Exclude []string `json:"exclude"                         yaml:"exclude"`
 	// Exclude unnecessary labels
-	ExcludeLabels []ExcludeLabelsSetting `json:"exclude_labels" yaml:"exclude_labels"`
+	ExcludeLabels []ExcludeLabelsSetting `json:"exclude_labels"                  yaml:"exclude_labels"`
 	// The minimum timestamp that the plugin uses to query metadata
-	StartAt time.Time `json:"start_at"       yaml:"start_at"`
+	StartAt time.Time `json:"start_at"                        yaml:"start_at"`
+	// The maximum number of distinct values of a label to be introspected as an enum.
+	// Labels with more values are string labels. Disabled if zero
+	EnumLabelMaxValues int `json:"enum_label_max_values,omitempty" yaml:"enum_label_max_values,omitempty" jsonschema:"min=0"`
+	// Discover metrics that don't have metadata
+	Discovery MetricsDiscoverySettings `json:"discovery,omitempty"             yaml:"discovery,omitempty"`
+}
+
+// MetricsDiscoverySettings contain settings to discover metrics without metadata,
+// such as outputs of recording rules and remote-written series.
+type MetricsDiscoverySettings struct {
+	// Discover metrics from values of the __name__ label
+	Series bool `json:"series,omitempty"          yaml:"series,omitempty"`
+	// Discover outputs of recording rules from the rules API
+	RecordingRules bool `json:"recording_rules,omitempty" yaml:"recording_rules,omitempty"`
+	// Types of discovered metrics by regular expression matching of metric names.
+	// Metrics are typed by naming convention if no pattern matches
+	Types []MetricTypeSetting `json:"types,omitempty"           yaml:"types,omitempty"`
+}
+
+// MetricTypeSetting the setting to type discovered metrics.
+type MetricTypeSetting struct {
+	// The regular expression pattern of metric names
+	Pattern string `json:"pattern" yaml:"pattern"`
+	// The metric type
+	Type model.MetricType `json:"type"    yaml:"type"    jsonschema:"enum=counter,enum=gauge,enum=info,enum=stateset,enum=unknown"`
 }
 
 // ExcludeLabelsSetting the setting to exclude labels.
@@ -99,25 +127,47 @@ type RuntimeFormatSettings struct {
 // RuntimeSettings contain settings for the runtime engine.
 type RuntimeSettings struct {
 	// Enable PromptQL-compatible mode.
-	PromptQL bool `json:"promptql"                         yaml:"promptql"`
+	PromptQL bool `json:"promptql"                                yaml:"promptql"`
 	// Disable native Prometheus APIs.
-	DisablePrometheusAPI bool `json:"disable_prometheus_api,omitempty" yaml:"disable_prometheus_api,omitempty"`
+	DisablePrometheusAPI bool `json:"disable_prometheus_api,omitempty"        yaml:"disable_prometheus_api,omitempty"`
 	// Flatten value points to the root array.
 	// If the PromptQL mode is on the result is always flat.
-	Flat bool `json:"flat"                             yaml:"flat"`
+	Flat bool `json:"flat"                                    yaml:"flat"`
 	// The default unit for unix timestamp.
-	UnixTimeUnit UnixTimeUnit `json:"unix_time_unit"                   yaml:"unix_time_unit"                   jsonschema:"enum=s,enum=ms,enum=us,enum=ns,default=s"`
+	UnixTimeUnit UnixTimeUnit `json:"unix_time_unit"                          yaml:"unix_time_unit"                          jsonschema:"enum=s,enum=ms,enum=us,enum=ns,default=s"`
 	// The serialization format for response fields.
-	Format RuntimeFormatSettings `json:"format"                           yaml:"format"`
+	Format RuntimeFormatSettings `json:"format"                                  yaml:"format"`
+	// The IANA time zone name, e.g. Europe/Berlin, that RFC3339 timestamps are formatted in
+	// and time functions are shifted to. The default time zone is UTC.
+	Timezone string `json:"timezone,omitempty"                      yaml:"timezone,omitempty"`
 	// The concurrency limit of queries if there are many variables in a single query.
-	ConcurrencyLimit int `json:"concurrency_limit,omitempty"      yaml:"concurrency_limit,omitempty"      jsonschema:"min=0"`
+	ConcurrencyLimit int `json:"concurrency_limit,omitempty"             yaml:"concurrency_limit,omitempty"             jsonschema:"min=0"`
+	// The scrape interval of Prometheus that built-in variables of native queries,
+	// e.g. $__rate_interval, are evaluated with. The default value is 15s.
+	ScrapeInterval *model.Duration `json:"scrape_interval,omitempty"               yaml:"scrape_interval,omitempty"`
+	// Allow experimental PromQL functions and aggregations, e.g. limitk and sort_by_label, in native queries.
+	// The Prometheus server must enable the promql-experimental-functions feature flag. The default value is true.
+	PromQLExperimentalFunctions *bool `json:"promql_experimental_functions,omitempty" yaml:"promql_experimental_functions,omitempty"`
 }
 
 // Validate checks if the settings is valid.
 func (rs RuntimeSettings) Validate() error {
+	if _, err := rs.GetLocation(nil); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// GetScrapeInterval gets the scrape interval setting or the default value.
+func (rs RuntimeSettings) GetScrapeInterval() time.Duration {
+	if rs.ScrapeInterval == nil || *rs.ScrapeInterval <= 0 {
+		return DefaultScrapeInterval
+	}
+
+	return time.Duration(*rs.ScrapeInterval)
+}
+
 // IsFlat gets the flat setting.
 func (rs RuntimeSettings) IsFlat(flat *bool) bool {
 	if rs.PromptQL {
@@ -131,6 +181,26 @@ func (rs RuntimeSettings) IsFlat(flat *bool) bool {
 	return rs.Flat
 }
 
+// GetLocation gets the time zone location from the argument or the runtime setting.
+// The default location is UTC.
+func (rs RuntimeSettings) GetLocation(timezone *string) (*time.Location, error) {
+	name := rs.Timezone
+	if timezone != nil && *timezone != "" {
+		name = *timezone
+	}
+
+	if name == "" {
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone: %w", err)
+	}
+
+	return loc, nil
+}
+
 // GetUnixTimeUnit gets the unix time unit setting.
 func (rs RuntimeSettings) GetUnixTimeUnit() UnixTimeUnit {
 	if rs.UnixTimeUnit == "" {
@@ -140,6 +210,11 @@ func (rs RuntimeSettings) GetUnixTimeUnit() UnixTimeUnit {
 	return rs.UnixTimeUnit
 }
 
+// IsPromQLExperimentalFunctionsEnabled checks if native queries may use experimental PromQL functions.
+func (rs RuntimeSettings) IsPromQLExperimentalFunctionsEnabled() bool {
+	return rs.PromQLExperimentalFunctions == nil || *rs.PromQLExperimentalFunctions
+}
+
 // ParseTimestamp parses timestamp from an unknown value.
 func (rs RuntimeSettings) ParseTimestamp(s any) (*time.Time, error) {
 	return utils.DecodeNullableDateTime(s, utils.WithBaseUnix(rs.GetUnixTimeUnit().Duration()))
@@ -168,5 +243,43 @@ func ReadConfiguration(configurationDir string) (*Configuration, error) {
 		return nil, err
 	}
 
+	if err := config.readNativeQueryFiles(configurationDir); err != nil {
+		return nil, err
+	}
+
 	return &config, nil
 }
+
+// readNativeQueryFiles merges native queries of .promql files in the native queries directory.
+func (c *Configuration) readNativeQueryFiles(configurationDir string) error {
+	dir := c.Metadata.NativeOperations.Directory
+	if dir == "" {
+		return nil
+	}
+
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(configurationDir, dir)
+	}
+
+	queries, err := ReadNativeQueryFiles(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read native queries of the directory %s: %w", dir, err)
+	}
+
+	if c.Metadata.NativeOperations.Queries == nil {
+		c.Metadata.NativeOperations.Queries = make(map[string]NativeQuery)
+	}
+
+	for name, nq := range queries {
+		if _, ok := c.Metadata.NativeOperations.Queries[name]; ok {
+			return fmt.Errorf(
+				"duplicated native query name `%s`. That name exists in the configuration file",
+				name,
+			)
+		}
+
+		c.Metadata.NativeOperations.Queries[name] = nq
+	}
+
+	return nil
+}
@@ -17,6 +17,8 @@ const (
 	ScalarLabelSet  ScalarName = "LabelSet"
 	ScalarDuration  ScalarName = "Duration"
 	ScalarJSON      ScalarName = "JSON"
+
+	ScalarTimestampBucketGranularity ScalarName = "TimestampBucketGranularity"
 )
 
 const (
@@ -40,13 +42,28 @@ const (
 	EndsWithInsensitive   = "_iends_with"
 	Contains              = "_contains"
 	ContainsInsensitive   = "_icontains"
+	ContainsKey           = "_contains_key"
 )
 
 var defaultScalars = map[string]schema.ScalarType{
 	string(ScalarBoolean): {
+		AggregateFunctions: schema.ScalarTypeAggregateFunctions{},
+		ComparisonOperators: map[string]schema.ComparisonOperatorDefinition{
+			Equal: schema.NewComparisonOperatorEqual().Encode(),
+			NotEqual: schema.NewComparisonOperatorCustom(schema.NewNamedType(string(ScalarBoolean))).
+				Encode(),
+		},
+		Representation: schema.NewTypeRepresentationBoolean().Encode(),
+	},
+	string(ScalarInt64): {
 		AggregateFunctions:  schema.ScalarTypeAggregateFunctions{},
-		ComparisonOperators: map[string]schema.ComparisonOperatorDefinition{},
-		Representation:      schema.NewTypeRepresentationBoolean().Encode(),
+		ComparisonOperators: createNumericComparisonOperators(ScalarInt64),
+		Representation:      schema.NewTypeRepresentationInt64().Encode(),
+	},
+	string(ScalarFloat64): {
+		AggregateFunctions:  schema.ScalarTypeAggregateFunctions{},
+		ComparisonOperators: createNumericComparisonOperators(ScalarFloat64),
+		Representation:      schema.NewTypeRepresentationFloat64().Encode(),
 	},
 	string(ScalarString): {
 		AggregateFunctions: schema.ScalarTypeAggregateFunctions{},
@@ -121,9 +138,22 @@ var defaultScalars = map[string]schema.ScalarType{
 		Representation: schema.NewTypeRepresentationTimestampTZ().Encode(),
 	},
 	string(ScalarLabelSet): {
-		AggregateFunctions:  schema.ScalarTypeAggregateFunctions{},
-		ComparisonOperators: map[string]schema.ComparisonOperatorDefinition{},
-		Representation:      schema.NewTypeRepresentationJSON().Encode(),
+		AggregateFunctions: schema.ScalarTypeAggregateFunctions{},
+		// The object arguments are maps of label names and values,
+		// so labels that aren't introspected can be filtered.
+		ComparisonOperators: map[string]schema.ComparisonOperatorDefinition{
+			ContainsKey: schema.NewComparisonOperatorCustom(schema.NewNamedType(string(ScalarString))).
+				Encode(),
+			Equal: schema.NewComparisonOperatorCustom(schema.NewNamedType(string(ScalarLabelSet))).
+				Encode(),
+			NotEqual: schema.NewComparisonOperatorCustom(schema.NewNamedType(string(ScalarLabelSet))).
+				Encode(),
+			Regex: schema.NewComparisonOperatorCustom(schema.NewNamedType(string(ScalarLabelSet))).
+				Encode(),
+			NotRegex: schema.NewComparisonOperatorCustom(schema.NewNamedType(string(ScalarLabelSet))).
+				Encode(),
+		},
+		Representation: schema.NewTypeRepresentationJSON().Encode(),
 	},
 }
 
@@ -132,8 +162,66 @@ const (
 	ValueKey     = "value"
 	ValuesKey    = "values"
 	LabelsKey    = "labels"
+	SummaryKey   = "summary"
+
+	// HistogramBucketLabel the label of upper bounds of histogram buckets.
+	HistogramBucketLabel = "le"
+	// SummaryQuantileLabel the label of quantiles of summary series.
+	SummaryQuantileLabel = "quantile"
+)
+
+// Field names of the statistical summary of series values.
+const (
+	SummaryFieldMin    = "min"
+	SummaryFieldMax    = "max"
+	SummaryFieldAvg    = "avg"
+	SummaryFieldSum    = "sum"
+	SummaryFieldCount  = "count"
+	SummaryFieldFirst  = "first"
+	SummaryFieldLast   = "last"
+	SummaryFieldStddev = "stddev"
+	SummaryFieldP50    = "p50"
+	SummaryFieldP90    = "p90"
+	SummaryFieldP99    = "p99"
+)
+
+// SummaryFields the list of available summary fields.
+var SummaryFields = []string{
+	SummaryFieldMin,
+	SummaryFieldMax,
+	SummaryFieldAvg,
+	SummaryFieldSum,
+	SummaryFieldCount,
+	SummaryFieldFirst,
+	SummaryFieldLast,
+	SummaryFieldStddev,
+	SummaryFieldP50,
+	SummaryFieldP90,
+	SummaryFieldP99,
+}
+
+// TimestampBucketGranularity represents the calendar unit of timestamp buckets.
+type TimestampBucketGranularity string
+
+const (
+	TimestampBucketMinute TimestampBucketGranularity = "minute"
+	TimestampBucketHour   TimestampBucketGranularity = "hour"
+	TimestampBucketDay    TimestampBucketGranularity = "day"
+	TimestampBucketWeek   TimestampBucketGranularity = "week"
+	TimestampBucketMonth  TimestampBucketGranularity = "month"
+	TimestampBucketYear   TimestampBucketGranularity = "year"
 )
 
+// TimestampBucketGranularities the list of supported timestamp bucket granularities.
+var TimestampBucketGranularities = []TimestampBucketGranularity{
+	TimestampBucketMinute,
+	TimestampBucketHour,
+	TimestampBucketDay,
+	TimestampBucketWeek,
+	TimestampBucketMonth,
+	TimestampBucketYear,
+}
+
 type PromQLFunctionName string
 
 const (
@@ -231,10 +319,12 @@ const (
 	objectName_QueryResultValue           = "QueryResultValue"
 	objectName_QueryResultValueWithLabels = "QueryResultValueWithLabels"
 	objectName_QueryResultValues          = "QueryResultValues"
+	objectName_QueryResultSummary         = "QueryResultSummary"
 	objectName_ValueBoundaryInput         = "ValueBoundaryInput"
 	objectName_HoltWintersInput           = "HoltWintersInput"
 	objectName_PredictLinearInput         = "PredictLinearInput"
 	objectName_QuantileOverTimeInput      = "QuantileOverTimeInput"
+	objectName_TimestampBucketInput       = "TimestampBucketInput"
 )
 
 var defaultObjectTypes = map[string]schema.ObjectType{
@@ -248,6 +338,13 @@ var defaultObjectTypes = map[string]schema.ObjectType{
 		Fields:      createQueryResultValuesObjectFields(),
 		ForeignKeys: schema.ObjectTypeForeignKeys{},
 	},
+	objectName_QueryResultSummary: {
+		Description: utils.ToPtr(
+			"The statistical summary of series values. NaN values are ignored",
+		),
+		Fields:      createQueryResultSummaryObjectFields(),
+		ForeignKeys: schema.ObjectTypeForeignKeys{},
+	},
 }
 
 var defaultFunctionObjectTypes = map[string]schema.ObjectType{
@@ -301,6 +398,23 @@ var defaultFunctionObjectTypes = map[string]schema.ObjectType{
 		},
 		ForeignKeys: schema.ObjectTypeForeignKeys{},
 	},
+	objectName_TimestampBucketInput: {
+		Description: utils.ToPtr("Calendar-aware bucket of the timestamp grouping dimension"),
+		Fields: schema.ObjectTypeFields{
+			"granularity": schema.ObjectField{
+				Description: utils.ToPtr("The calendar unit of buckets"),
+				Type: schema.NewNamedType(string(ScalarTimestampBucketGranularity)).
+					Encode(),
+			},
+			"timezone": schema.ObjectField{
+				Description: utils.ToPtr(
+					"The IANA time zone name that buckets are aligned to, e.g. Europe/Berlin. Use the timezone argument if the value is null",
+				),
+				Type: schema.NewNullableNamedType(string(ScalarString)).Encode(),
+			},
+		},
+		ForeignKeys: schema.ObjectTypeForeignKeys{},
+	},
 	objectName_QuantileOverTimeInput: {
 		Description: utils.ToPtr("Input arguments for the quantile_over_time function"),
 		Fields: schema.ObjectTypeFields{
@@ -328,6 +442,10 @@ const (
 	ArgumentKeyQuery     = "query"
 	ArgumentKeyQuantile  = "quantile"
 	ArgumentKeyFunctions = "fn"
+	ArgumentKeyMaxPoints = "max_points"
+
+	ArgumentKeyTimestampBucket = "timestamp_bucket"
+	ArgumentKeyTimezone        = "timezone"
 )
 
 var defaultArgumentInfos = map[string]schema.ArgumentInfo{
@@ -375,6 +493,36 @@ var defaultArgumentInfos = map[string]schema.ArgumentInfo{
 		),
 		Type: schema.NewNamedType(string(ScalarDecimal)).Encode(),
 	},
+	ArgumentKeyMaxPoints: {
+		Description: utils.ToPtr(
+			"The maximum number of points per series of range queries. Values are downsampled with the Largest-Triangle-Three-Buckets algorithm to preserve the visual shape if the series has more points",
+		),
+		Type: schema.NewNullableNamedType(string(ScalarInt64)).Encode(),
+	},
+	ArgumentKeyTimezone: {
+		Description: utils.ToPtr(
+			"The IANA time zone name, e.g. Europe/Berlin, that RFC3339 timestamps are formatted in and time functions are shifted to. Use the runtime setting if the value is null",
+		),
+		Type: schema.NewNullableNamedType(string(ScalarString)).Encode(),
+	},
+	ArgumentKeyTimestampBucket: {
+		Description: utils.ToPtr(
+			"Group the timestamp dimension by calendar buckets in a time zone. Aggregates combine range samples in each bucket",
+		),
+		Type: schema.NewNullableNamedType(objectName_TimestampBucketInput).Encode(),
+	},
+}
+
+// TimeFunctions the list of functions that evaluate calendar fields of unix timestamp values.
+var TimeFunctions = []PromQLFunctionName{
+	Minute,
+	Hour,
+	DayOfMonth,
+	DayOfWeek,
+	DayOfYear,
+	DaysInMonth,
+	Month,
+	Year,
 }
 
 var (
@@ -413,3 +561,37 @@ func createMetricObjectType(promptql bool) schema.ObjectType {
 
 	return objectType
 }
+
+func createNumericComparisonOperators(
+	scalarName ScalarName,
+) map[string]schema.ComparisonOperatorDefinition {
+	return map[string]schema.ComparisonOperatorDefinition{
+		Equal: schema.NewComparisonOperatorEqual().Encode(),
+		NotEqual: schema.NewComparisonOperatorCustom(schema.NewNamedType(string(scalarName))).
+			Encode(),
+		In: schema.NewComparisonOperatorCustom(schema.NewArrayType(schema.NewNamedType(string(scalarName)))).
+			Encode(),
+		NotIn: schema.NewComparisonOperatorCustom(schema.NewArrayType(schema.NewNamedType(string(scalarName)))).
+			Encode(),
+		Least:          schema.NewComparisonOperatorLessThan().Encode(),
+		LeastOrEqual:   schema.NewComparisonOperatorLessThanOrEqual().Encode(),
+		Greater:        schema.NewComparisonOperatorGreaterThan().Encode(),
+		GreaterOrEqual: schema.NewComparisonOperatorGreaterThanOrEqual().Encode(),
+	}
+}
+
+func createEnumLabelScalarType(scalarName string, values []string) schema.ScalarType {
+	scalarType := schema.NewScalarType()
+	scalarType.Representation = schema.NewTypeRepresentationEnum(values).Encode()
+	scalarType.ComparisonOperators = map[string]schema.ComparisonOperatorDefinition{
+		Equal: schema.NewComparisonOperatorEqual().Encode(),
+		NotEqual: schema.NewComparisonOperatorCustom(schema.NewNamedType(scalarName)).
+			Encode(),
+		In: schema.NewComparisonOperatorCustom(schema.NewArrayType(schema.NewNamedType(scalarName))).
+			Encode(),
+		NotIn: schema.NewComparisonOperatorCustom(schema.NewArrayType(schema.NewNamedType(scalarName))).
+			Encode(),
+	}
+
+	return *scalarType
+}
@@ -1,6 +1,10 @@
 package metadata
 
 import (
+	"errors"
+	"fmt"
+	"slices"
+
 	"github.com/hasura/ndc-prometheus/connector/client"
 	"github.com/prometheus/common/model"
 	"go.opentelemetry.io/otel/trace"
@@ -21,15 +25,72 @@ type Metadata struct {
 // MetricInfo the metadata information of a metric.
 type MetricInfo struct {
 	// A metric type
-	Type model.MetricType `json:"type"                  yaml:"type"`
+	Type model.MetricType `json:"type"                                 yaml:"type"`
 	// Description of the metric
-	Description *string `json:"description,omitempty" yaml:"description,omitempty"`
+	Description *string `json:"description,omitempty"                yaml:"description,omitempty"`
 	// Labels returned by the metric
-	Labels map[string]LabelInfo `json:"labels"                yaml:"labels"`
+	Labels map[string]LabelInfo `json:"labels"                               yaml:"labels"`
+	// The scrape interval of targets that expose the metric. Auto ranges of range functions are evaluated with it.
+	// The runtime scrape interval is used if empty
+	ScrapeInterval *model.Duration `json:"scrape_interval,omitempty"            yaml:"scrape_interval,omitempty"`
+	// The scrape interval is discovered by the update command and refreshed on every update
+	ScrapeIntervalDiscovered bool `json:"scrape_interval_discovered,omitempty" yaml:"scrape_interval_discovered,omitempty"`
+}
+
+// LabelType the data type of Prometheus label values.
+type LabelType string
+
+const (
+	LabelTypeString  LabelType = "string"
+	LabelTypeInt64   LabelType = "int64"
+	LabelTypeFloat64 LabelType = "float64"
+	LabelTypeBoolean LabelType = "boolean"
+	LabelTypeEnum    LabelType = "enum"
+)
+
+var enumLabelTypes = []LabelType{
+	LabelTypeString,
+	LabelTypeInt64,
+	LabelTypeFloat64,
+	LabelTypeBoolean,
+	LabelTypeEnum,
 }
 
 // LabelInfo the information of a Prometheus label.
 type LabelInfo struct {
 	// Description of the label
-	Description *string `json:"description,omitempty" yaml:"description,omitempty"`
+	Description *string `json:"description,omitempty"  yaml:"description,omitempty"`
+	// The data type of label values. The default type is string
+	Type LabelType `json:"type,omitempty"         yaml:"type,omitempty"         jsonschema:"enum=string,enum=int64,enum=float64,enum=boolean,enum=enum"`
+	// Allowed values of the enum label
+	Values []string `json:"values,omitempty"       yaml:"values,omitempty"`
+	// The enum type and values are introspected by the update command and refreshed on every update
+	Introspected bool `json:"introspected,omitempty" yaml:"introspected,omitempty"`
+}
+
+// GetType gets the data type of the label.
+func (li LabelInfo) GetType() LabelType {
+	if li.Type == "" {
+		return LabelTypeString
+	}
+
+	return li.Type
+}
+
+// Validate checks if the label information is valid.
+func (li LabelInfo) Validate() error {
+	labelType := li.GetType()
+	if !slices.Contains(enumLabelTypes, labelType) {
+		return fmt.Errorf(
+			"invalid label type; expected one of %v, got: %s",
+			enumLabelTypes,
+			li.Type,
+		)
+	}
+
+	if labelType == LabelTypeEnum && len(li.Values) == 0 {
+		return errors.New("values of the enum label must not be empty")
+	}
+
+	return nil
 }
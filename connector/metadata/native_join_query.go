@@ -0,0 +1,146 @@
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/hasura/ndc-sdk-go/schema"
+	"github.com/hasura/ndc-sdk-go/utils"
+)
+
+// reserved field names of joined results that can't be used as names of expressions.
+var joinQueryReservedFields = []string{TimestampKey, LabelsKey, ValueKey, ValuesKey, SummaryKey}
+
+// IsJoinQuery checks if the native query evaluates many expressions that are joined on labels.
+func (nq NativeQuery) IsJoinQuery() bool {
+	return len(nq.Expressions) > 0
+}
+
+// ExpressionQueries returns native queries of named expressions.
+// Expression queries share arguments and labels of the native query.
+func (nq NativeQuery) ExpressionQueries() map[string]NativeQuery {
+	results := make(map[string]NativeQuery, len(nq.Expressions))
+
+	for name, expr := range nq.Expressions {
+		query := NativeQuery{
+			Query:      expr,
+			Arguments:  nq.Arguments,
+			Labels:     nq.Labels,
+			ResultType: NativeQueryResultVector,
+		}
+
+		// keep the expanded and validated template of the expression.
+		if cached, ok := nq.expressions[name]; ok && cached.Query == expr {
+			query.expansion = cached.expansion
+			query.template = cached.template
+		}
+
+		results[name] = query
+	}
+
+	return results
+}
+
+func (nq *NativeQuery) expandExpressionFragments(fragments map[string]NativeQueryFragment) error {
+	expressions := nq.ExpressionQueries()
+
+	for _, name := range utils.GetSortedKeys(expressions) {
+		query := expressions[name]
+
+		if err := query.ExpandFragments(fragments); err != nil {
+			return fmt.Errorf("expression `%s`: %w", name, err)
+		}
+
+		expressions[name] = query
+	}
+
+	nq.expressions = expressions
+
+	return nil
+}
+
+// validateJoinQuery validates expressions and join labels of the native query.
+// Expressions must return instant vectors.
+func (nq *NativeQuery) validateJoinQuery() error {
+	if nq.Query != "" {
+		return errors.New("the query must be empty if expressions are defined")
+	}
+
+	if len(nq.JoinLabels) == 0 {
+		return errors.New("join labels are required if expressions are defined")
+	}
+
+	if nq.ResultType != "" && nq.ResultType != NativeQueryResultVector {
+		return fmt.Errorf("invalid result type `%s` of expressions", nq.ResultType)
+	}
+
+	expressions := nq.ExpressionQueries()
+
+	for _, name := range utils.GetSortedKeys(expressions) {
+		if !fragmentNameRegex.MatchString(name) {
+			return fmt.Errorf("invalid expression name `%s`", name)
+		}
+
+		if slices.Contains(joinQueryReservedFields, name) || slices.Contains(nq.JoinLabels, name) {
+			return fmt.Errorf("the expression name `%s` is reserved or used by join labels", name)
+		}
+
+		query := expressions[name]
+
+		if err := query.Validate(); err != nil {
+			return fmt.Errorf("expression `%s`: %w", name, err)
+		}
+
+		expressions[name] = query
+	}
+
+	nq.expressions = expressions
+
+	return nil
+}
+
+// buildNativeJoinQueryObjectType builds the row type of joined expressions.
+// Rows have join labels, the timestamp and a value column of each expression.
+func (scb *connectorSchemaBuilder) buildNativeJoinQueryObjectType(
+	name string,
+	objectName string,
+	query *NativeQuery,
+) (*schema.ObjectType, error) {
+	objectType := &schema.ObjectType{
+		Fields: schema.ObjectTypeFields{
+			TimestampKey: schema.ObjectField{
+				Description: utils.ToPtr(
+					"An instant timestamp or the timestamp of a range query step",
+				),
+				Type: schema.NewNamedType(string(ScalarTimestamp)).Encode(),
+			},
+		},
+		ForeignKeys: schema.ObjectTypeForeignKeys{},
+	}
+
+	for _, key := range query.JoinLabels {
+		label := query.Labels[key]
+
+		fieldType, err := scb.buildLabelFieldType(objectName, key, label)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		objectType.Fields[key] = schema.ObjectField{
+			Description: label.Description,
+			Type:        fieldType,
+		}
+	}
+
+	for key := range query.Expressions {
+		objectType.Fields[key] = schema.ObjectField{
+			Description: utils.ToPtr(
+				"The value of the expression. Null if the expression doesn't have a series of the join labels",
+			),
+			Type: schema.NewNullableNamedType(string(ScalarDecimal)).Encode(),
+		}
+	}
+
+	return objectType, nil
+}
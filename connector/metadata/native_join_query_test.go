@@ -0,0 +1,169 @@
+package metadata
+
+import (
+	"testing"
+
+	"github.com/hasura/ndc-sdk-go/schema"
+	"gotest.tools/v3/assert"
+)
+
+func TestNativeQueryValidateExpressions(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Query    NativeQuery
+		ErrorMsg string
+	}{
+		{
+			Name: "valid",
+			Query: NativeQuery{
+				Expressions: map[string]string{
+					"requests": `sum by (job) (rate(http_requests_total{job="${job}"}[5m]))`,
+					"errors":   `sum by (job) (rate(http_requests_total{job="${job}",code=~"5.."}[5m]))`,
+				},
+				JoinLabels: []string{"job"},
+				Arguments: map[string]NativeQueryArgumentInfo{
+					"job": {Type: string(ScalarString)},
+				},
+			},
+		},
+		{
+			Name: "query_and_expressions",
+			Query: NativeQuery{
+				Query:       `up`,
+				Expressions: map[string]string{"up": `up`},
+				JoinLabels:  []string{"job"},
+			},
+			ErrorMsg: "the query must be empty if expressions are defined",
+		},
+		{
+			Name: "missing_join_labels",
+			Query: NativeQuery{
+				Expressions: map[string]string{"up": `up`},
+			},
+			ErrorMsg: "join labels are required if expressions are defined",
+		},
+		{
+			Name: "reserved_name",
+			Query: NativeQuery{
+				Expressions: map[string]string{"timestamp": `up`},
+				JoinLabels:  []string{"job"},
+			},
+			ErrorMsg: "the expression name `timestamp` is reserved or used by join labels",
+		},
+		{
+			Name: "join_label_name",
+			Query: NativeQuery{
+				Expressions: map[string]string{"job": `up`},
+				JoinLabels:  []string{"job"},
+			},
+			ErrorMsg: "the expression name `job` is reserved or used by join labels",
+		},
+		{
+			Name: "scalar_expression",
+			Query: NativeQuery{
+				Expressions: map[string]string{"total": `scalar(count(up))`},
+				JoinLabels:  []string{"job"},
+			},
+			ErrorMsg: "expression `total`: the query returns a scalar result, but the result type is vector",
+		},
+		{
+			Name: "invalid_expression",
+			Query: NativeQuery{
+				Expressions: map[string]string{"up": `sum(up[5m])`},
+				JoinLabels:  []string{"job"},
+			},
+			ErrorMsg: "expression `up`: 1:5: parse error",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := tc.Query.Validate()
+			if tc.ErrorMsg == "" {
+				assert.NilError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.ErrorMsg)
+			}
+		})
+	}
+}
+
+func TestNativeQueryExpandExpressionFragments(t *testing.T) {
+	nq := NativeQuery{
+		Expressions: map[string]string{
+			"errors": `{{ job_rate("5..") }}`,
+		},
+		JoinLabels: []string{"job"},
+	}
+
+	err := nq.ExpandFragments(map[string]NativeQueryFragment{
+		"job_rate": {
+			Query:      `sum by (job) (rate(http_requests_total{code=~{{ code }}}[5m]))`,
+			Parameters: []string{"code"},
+		},
+	})
+	assert.NilError(t, err)
+	assert.NilError(t, nq.Validate())
+	assert.Equal(
+		t,
+		`sum by (job) (rate(http_requests_total{code=~"5.."}[5m]))`,
+		nq.ExpressionQueries()["errors"].ExpandedQuery(),
+	)
+}
+
+func TestBuildConnectorSchemaNativeJoinQuery(t *testing.T) {
+	config := &Configuration{
+		Metadata: Metadata{
+			NativeOperations: NativeOperations{
+				Queries: map[string]NativeQuery{
+					"service_health": {
+						Expressions: map[string]string{
+							"requests": `sum by (job) (rate(http_requests_total[5m]))`,
+							"errors":   `sum by (job) (rate(http_requests_total{code=~"5.."}[5m]))`,
+						},
+						JoinLabels: []string{"job"},
+						Labels: map[string]LabelInfo{
+							"job": {},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	result, err := BuildConnectorSchema(config)
+	assert.NilError(t, err)
+
+	for _, collection := range result.Collections {
+		if collection.Name != "service_health" {
+			continue
+		}
+
+		_, ok := collection.Arguments[ArgumentKeyFunctions]
+		assert.Assert(t, !ok)
+
+		_, ok = collection.Arguments[ArgumentKeyMaxPoints]
+		assert.Assert(t, !ok)
+	}
+
+	objectType := result.ObjectTypes["ServiceHealth"]
+	assert.Equal(t, 4, len(objectType.Fields))
+	assert.DeepEqual(
+		t,
+		schema.NewNamedType(string(ScalarTimestamp)).Encode(),
+		objectType.Fields[TimestampKey].Type,
+	)
+	assert.DeepEqual(
+		t,
+		schema.NewNamedType(string(ScalarString)).Encode(),
+		objectType.Fields["job"].Type,
+	)
+
+	for _, key := range []string{"requests", "errors"} {
+		assert.DeepEqual(
+			t,
+			schema.NewNullableNamedType(string(ScalarDecimal)).Encode(),
+			objectType.Fields[key].Type,
+		)
+	}
+}
@@ -2,6 +2,8 @@ package metadata
 
 import (
 	"fmt"
+	"maps"
+	"reflect"
 	"regexp"
 	"slices"
 	"strings"
@@ -25,7 +27,12 @@ var (
 // NativeOperations the list of native query and mutation definitions.
 type NativeOperations struct {
 	// The definition map of native queries
-	Queries map[string]NativeQuery `json:"queries" yaml:"queries"`
+	Queries map[string]NativeQuery `json:"queries"             yaml:"queries"`
+	// The definition map of reusable PromQL fragments that native queries reference with the {{ name }} syntax
+	Fragments map[string]NativeQueryFragment `json:"fragments,omitempty" yaml:"fragments,omitempty"`
+	// The directory of standalone .promql files of native queries, relative to the configuration directory.
+	// Names of native queries are file names without the extension
+	Directory string `json:"directory,omitempty" yaml:"directory,omitempty"`
 }
 
 // NativeQueryArgumentInfo the input argument.
@@ -33,6 +40,127 @@ type NativeQueryArgumentInfo struct {
 	// Description of the argument
 	Description *string `json:"description,omitempty" yaml:"description,omitempty"`
 	Type        string  `json:"type"                  yaml:"type"                  jsonschema:"enum=Int64,enum=Float64,enum=String,enum=Duration"`
+	// The argument accepts an array of values that are expanded into a regex alternation in regex label matchers,
+	// or a comma-separated list in label lists, e.g. sum by (${labels}).
+	Array bool `json:"array,omitempty"       yaml:"array,omitempty"`
+	// Allowed values of the String argument
+	Enum []string `json:"enum,omitempty"        yaml:"enum,omitempty"`
+	// The default value if the argument is null or missing
+	Default any `json:"default,omitempty"     yaml:"default,omitempty"`
+	// The argument is optional. Optional blocks [[ ]] of the template that use the null argument are dropped
+	Nullable bool `json:"nullable,omitempty"    yaml:"nullable,omitempty"`
+	// An example value that is used to run the query when the update command discovers labels of the result
+	Example any `json:"example,omitempty"     yaml:"example,omitempty"`
+}
+
+// Validate checks if the argument information is valid.
+func (arg NativeQueryArgumentInfo) Validate() error {
+	if arg.Type != "" && !slices.Contains(allowedNativeQueryScalars, ScalarName(arg.Type)) {
+		return fmt.Errorf("unsupported native query argument type %s", arg.Type)
+	}
+
+	if len(arg.Enum) > 0 && arg.Type != "" && arg.Type != string(ScalarString) {
+		return fmt.Errorf("enum values are supported by the String type only, got %s", arg.Type)
+	}
+
+	if arg.Default == nil {
+		return nil
+	}
+
+	defaultValues := []any{arg.Default}
+
+	if arg.Array {
+		reflectValue := reflect.ValueOf(arg.Default)
+		if reflectValue.Kind() != reflect.Slice && reflectValue.Kind() != reflect.Array {
+			return fmt.Errorf(
+				"the default value of the array argument must be an array, got %v",
+				arg.Default,
+			)
+		}
+
+		defaultValues = make([]any, reflectValue.Len())
+		for i := range defaultValues {
+			defaultValues[i] = reflectValue.Index(i).Interface()
+		}
+	}
+
+	for _, value := range defaultValues {
+		if err := arg.validateDefaultValue(value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateDefaultValue checks if the default value can be decoded as a value of the argument type.
+func (arg NativeQueryArgumentInfo) validateDefaultValue(value any) error {
+	var err error
+
+	switch ScalarName(arg.Type) {
+	case ScalarInt64:
+		_, err = utils.DecodeInt[int64](value)
+	case ScalarFloat64:
+		_, err = utils.DecodeFloat[float64](value)
+	case ScalarDuration:
+		var duration *RangeResolution
+
+		duration, err = ParseRangeResolution(value, UnixTimeSecond)
+		if err == nil && (duration == nil || duration.Auto) {
+			err = fmt.Errorf("expected a duration, got %v", value)
+		}
+	default:
+		var str string
+
+		str, err = utils.DecodeString(value)
+		if err == nil && len(arg.Enum) > 0 && !slices.Contains(arg.Enum, str) {
+			return fmt.Errorf("the default value %v is not in the enum %v", value, arg.Enum)
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid default value %v: %w", value, err)
+	}
+
+	return nil
+}
+
+// IsOptional checks if the argument can be omitted in requests.
+func (arg NativeQueryArgumentInfo) IsOptional() bool {
+	return arg.Nullable || arg.Default != nil
+}
+
+// CanBeNull checks if the value of the argument is null if it's omitted.
+func (arg NativeQueryArgumentInfo) CanBeNull() bool {
+	return arg.Nullable && arg.Default == nil
+}
+
+// NativeQueryResultType represents the type of the top-level result of the native query.
+type NativeQueryResultType string
+
+const (
+	NativeQueryResultVector NativeQueryResultType = "vector"
+	NativeQueryResultMatrix NativeQueryResultType = "matrix"
+	NativeQueryResultScalar NativeQueryResultType = "scalar"
+	NativeQueryResultString NativeQueryResultType = "string"
+)
+
+var nativeQueryResultTypes = []NativeQueryResultType{
+	NativeQueryResultVector,
+	NativeQueryResultMatrix,
+	NativeQueryResultScalar,
+	NativeQueryResultString,
+}
+
+// IsSeries checks if the result is a set of labeled series.
+func (rt NativeQueryResultType) IsSeries() bool {
+	return rt == NativeQueryResultVector || rt == NativeQueryResultMatrix
+}
+
+// SupportsRangeQuery checks if the query can be evaluated over a range of time.
+// Range queries of scalar expressions return a series without labels.
+func (rt NativeQueryResultType) SupportsRangeQuery() bool {
+	return rt == NativeQueryResultVector || rt == NativeQueryResultScalar
 }
 
 // NativeQuery contains the information a native query.
@@ -40,13 +168,44 @@ type NativeQuery struct {
 	// The PromQL query string to use for the Native Query.
 	// We can interpolate values using `${<varname>}` syntax,
 	// such as http_requests_total{job=~"${<varname>}"}
-	Query string `json:"query"                 yaml:"query"`
+	Query string `json:"query,omitempty"       yaml:"query,omitempty"`
 	// Description of the query
 	Description *string `json:"description,omitempty" yaml:"description,omitempty"`
 	// Labels returned by the native query
 	Labels map[string]LabelInfo `json:"labels"                yaml:"labels"`
 	// Information of input arguments
 	Arguments map[string]NativeQueryArgumentInfo `json:"arguments"             yaml:"arguments"`
+	// The type of the top-level result. Scalar and string results are returned as timestamp and value rows.
+	// The default type is vector
+	ResultType NativeQueryResultType `json:"result_type,omitempty" yaml:"result_type,omitempty" jsonschema:"enum=vector,enum=matrix,enum=scalar,enum=string"`
+	// Named PromQL expressions that are evaluated together instead of the query and joined on join labels.
+	// Each expression is a value column of the result
+	Expressions map[string]string `json:"expressions,omitempty" yaml:"expressions,omitempty"`
+	// Labels that results of expressions are joined on
+	JoinLabels []string `json:"join_labels,omitempty" yaml:"join_labels,omitempty"`
+
+	// The scanned template that is evaluated when the configuration is validated.
+	template *NativeQueryTemplate
+	// The source file if the query is loaded from the native queries directory.
+	file *NativeQueryFile
+	// The query that fragments are expanded.
+	expansion *nativeQueryExpansion
+	// Validated queries of expressions.
+	expressions map[string]NativeQuery
+}
+
+type nativeQueryExpansion struct {
+	Query         string
+	ExpandedQuery string
+}
+
+// GetResultType gets the result type of the native query. The default type is vector.
+func (nq NativeQuery) GetResultType() NativeQueryResultType {
+	if nq.ResultType == "" {
+		return NativeQueryResultVector
+	}
+
+	return nq.ResultType
 }
 
 func (scb *connectorSchemaBuilder) buildNativeQueries() error {
@@ -65,49 +224,74 @@ func (scb *connectorSchemaBuilder) buildNativeQueries() error {
 
 func (scb *connectorSchemaBuilder) buildNativeQuery(name string, query *NativeQuery) error {
 	arguments := createCollectionArguments(scb.Configuration.Runtime.PromptQL)
+	objectName := xstrings.ToPascalCase(strings.ReplaceAll(name, ":", " "))
+
+	if _, ok := scb.ObjectTypes[objectName]; ok {
+		objectName += "Result"
+	}
+
+	resultType := query.GetResultType()
+
+	// series of expressions are joined on timestamps that downsampling doesn't keep.
+	if query.IsJoinQuery() {
+		delete(arguments, ArgumentKeyMaxPoints)
+	}
+
+	// the native query is evaluated as a subexpression of PromQL functions and aggregations.
+	if !scb.Configuration.Runtime.PromptQL && resultType == NativeQueryResultVector &&
+		!query.IsJoinQuery() {
+		arguments[ArgumentKeyTimestampBucket] = defaultArgumentInfos[ArgumentKeyTimestampBucket]
+		arguments[ArgumentKeyFunctions] = scb.buildPromQLFunctionsArgument(
+			name,
+			objectName,
+			slices.Collect(maps.Keys(query.Labels)),
+		)
+	}
 
 	for key, arg := range query.Arguments {
 		if _, ok := arguments[key]; ok {
 			return fmt.Errorf("argument `%s` is already used by the function", key)
 		}
 
-		scalarName := arg.Type
+		if err := arg.Validate(); err != nil {
+			return fmt.Errorf("%s: %w; argument: %s", name, err, key)
+		}
 
-		if arg.Type != "" {
-			if !slices.Contains(allowedNativeQueryScalars, ScalarName(arg.Type)) {
-				return fmt.Errorf(
-					"%s: unsupported native query argument type %s; argument: %s ",
-					name,
-					scalarName,
-					key,
-				)
-			}
-		} else {
+		scalarName := arg.Type
+		if scalarName == "" {
 			scalarName = string(ScalarString)
 		}
 
-		arguments[key] = schema.ArgumentInfo{
-			Description: arg.Description,
-			Type:        schema.NewNamedType(scalarName).Encode(),
+		if len(arg.Enum) > 0 {
+			// the suffix is distinct from enum labels of the result type that may have the same name.
+			scalarName = objectName + xstrings.ToPascalCase(key) + "ArgumentEnum"
+			scalarType := schema.NewScalarType()
+			scalarType.Representation = schema.NewTypeRepresentationEnum(arg.Enum).Encode()
+			scb.ScalarTypes[scalarName] = *scalarType
 		}
-	}
 
-	resultType := createMetricObjectType(scb.Configuration.Runtime.PromptQL)
+		var argType schema.TypeEncoder = schema.NewNamedType(scalarName)
 
-	for key, label := range query.Labels {
-		resultType.Fields[key] = schema.ObjectField{
-			Description: label.Description,
-			Type:        schema.NewNamedType(string(ScalarString)).Encode(),
+		if arg.Array {
+			argType = schema.NewArrayType(argType)
 		}
-	}
 
-	objectName := xstrings.ToPascalCase(strings.ReplaceAll(name, ":", " "))
+		if arg.IsOptional() {
+			argType = schema.NewNullableType(argType)
+		}
 
-	if _, ok := scb.ObjectTypes[objectName]; ok {
-		objectName += "Result"
+		arguments[key] = schema.ArgumentInfo{
+			Description: arg.Description,
+			Type:        argType.Encode(),
+		}
 	}
 
-	scb.ObjectTypes[objectName] = resultType
+	objectType, err := scb.buildNativeQueryObjectType(name, objectName, query)
+	if err != nil {
+		return err
+	}
+
+	scb.ObjectTypes[objectName] = *objectType
 	collection := schema.CollectionInfo{
 		Name:                  name,
 		Type:                  objectName,
@@ -121,27 +305,72 @@ func (scb *connectorSchemaBuilder) buildNativeQuery(name string, query *NativeQu
 	return nil
 }
 
+// buildNativeQueryObjectType builds the row type of the native query.
+// Scalar and string results are rows of timestamps and values without labels.
+func (scb *connectorSchemaBuilder) buildNativeQueryObjectType(
+	name string,
+	objectName string,
+	query *NativeQuery,
+) (*schema.ObjectType, error) {
+	if query.IsJoinQuery() {
+		return scb.buildNativeJoinQueryObjectType(name, objectName, query)
+	}
+
+	switch query.GetResultType() {
+	case NativeQueryResultScalar:
+		return &schema.ObjectType{
+			Fields:      createQueryResultValueObjectFields(),
+			ForeignKeys: schema.ObjectTypeForeignKeys{},
+		}, nil
+	case NativeQueryResultString:
+		fields := createQueryResultValueObjectFields()
+		fields[ValueKey] = schema.ObjectField{
+			Description: utils.ToPtr("The string value"),
+			Type:        schema.NewNamedType(string(ScalarString)).Encode(),
+		}
+
+		return &schema.ObjectType{
+			Fields:      fields,
+			ForeignKeys: schema.ObjectTypeForeignKeys{},
+		}, nil
+	}
+
+	objectType := createMetricObjectType(scb.Configuration.Runtime.PromptQL)
+
+	for key, label := range query.Labels {
+		fieldType, err := scb.buildLabelFieldType(objectName, key, label)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		objectType.Fields[key] = schema.ObjectField{
+			Description: label.Description,
+			Type:        fieldType,
+		}
+	}
+
+	return &objectType, nil
+}
+
 // FindNativeQueryVariableNames find possible variables in the native query.
+// Built-in variables are excluded.
 func FindNativeQueryVariableNames(query string) []string {
 	matches := promQLVariableRegex.FindAllStringSubmatch(query, -1)
-	results := make([]string, len(matches))
+	results := make([]string, 0, len(matches))
 
-	for i, m := range matches {
-		results[i] = m[1]
+	for _, m := range matches {
+		if !IsBuiltinVariable(m[1]) {
+			results = append(results, m[1])
+		}
 	}
 
 	return results
 }
 
-// ReplaceNativeQueryVariable replaces the native query with variable.
-func ReplaceNativeQueryVariable(query string, name string, value string) string {
-	return strings.ReplaceAll(query, fmt.Sprintf("${%s}", name), value)
-}
-
 func createPromQLQueryArguments() schema.FunctionInfoArguments {
 	arguments := schema.FunctionInfoArguments{}
 
-	for _, key := range []string{ArgumentKeyStart, ArgumentKeyEnd, ArgumentKeyStep, ArgumentKeyTime, ArgumentKeyTimeout, ArgumentKeyFlat} {
+	for _, key := range []string{ArgumentKeyStart, ArgumentKeyEnd, ArgumentKeyStep, ArgumentKeyTime, ArgumentKeyTimeout, ArgumentKeyFlat, ArgumentKeyMaxPoints, ArgumentKeyTimezone} {
 		arguments[key] = defaultArgumentInfos[key]
 	}
 
@@ -15,6 +15,10 @@ func TestFindNativeQueryVariableNames(t *testing.T) {
 			Input:    `rate(http_requests_total{job=~"${job}"}[${_rate_interval}])`,
 			Expected: []string{"job", "_rate_interval"},
 		},
+		{
+			Input:    `rate(http_requests_total{job=~"${job}"}[${__rate_interval}])`,
+			Expected: []string{"job"},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -24,3 +28,82 @@ func TestFindNativeQueryVariableNames(t *testing.T) {
 		})
 	}
 }
+
+func TestNativeQueryArgumentInfoValidate(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Argument NativeQueryArgumentInfo
+		Error    string
+	}{
+		{
+			Name:     "enum_default",
+			Argument: NativeQueryArgumentInfo{Enum: []string{"dev", "prod"}, Default: "prod"},
+		},
+		{
+			Name: "array_enum_default",
+			Argument: NativeQueryArgumentInfo{
+				Array:   true,
+				Enum:    []string{"dev", "prod"},
+				Default: []any{"dev", "prod"},
+			},
+		},
+		{
+			Name:     "int64_default",
+			Argument: NativeQueryArgumentInfo{Type: string(ScalarInt64), Default: 5},
+		},
+		{
+			Name:     "duration_default",
+			Argument: NativeQueryArgumentInfo{Type: string(ScalarDuration), Default: "5m"},
+		},
+		{
+			Name:     "enum_not_string",
+			Argument: NativeQueryArgumentInfo{Type: string(ScalarInt64), Enum: []string{"1"}},
+			Error:    "enum values are supported by the String type only, got Int64",
+		},
+		{
+			Name:     "default_not_in_enum",
+			Argument: NativeQueryArgumentInfo{Enum: []string{"dev", "prod"}, Default: "test"},
+			Error:    "the default value test is not in the enum [dev prod]",
+		},
+		{
+			Name: "array_default_not_in_enum",
+			Argument: NativeQueryArgumentInfo{
+				Array:   true,
+				Enum:    []string{"dev", "prod"},
+				Default: []any{"dev", "test"},
+			},
+			Error: "the default value test is not in the enum [dev prod]",
+		},
+		{
+			Name:     "array_default_not_array",
+			Argument: NativeQueryArgumentInfo{Array: true, Default: "dev"},
+			Error:    "the default value of the array argument must be an array, got dev",
+		},
+		{
+			Name:     "default_array_not_array_argument",
+			Argument: NativeQueryArgumentInfo{Default: []any{"dev"}},
+			Error:    "invalid default value [dev]",
+		},
+		{
+			Name:     "invalid_int64_default",
+			Argument: NativeQueryArgumentInfo{Type: string(ScalarInt64), Default: "five"},
+			Error:    "invalid default value five",
+		},
+		{
+			Name:     "auto_duration_default",
+			Argument: NativeQueryArgumentInfo{Type: string(ScalarDuration), Default: "auto"},
+			Error:    "invalid default value auto: expected a duration, got auto",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := tc.Argument.Validate()
+			if tc.Error == "" {
+				assert.NilError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package metadata
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// Grafana-compatible built-in variables of native queries.
+// Built-in variables can be written without braces, e.g. $__rate_interval.
+const (
+	// The step of the range query, or the scrape interval of instant queries.
+	BuiltinVariableInterval = "__interval"
+	// The interval that rate functions are guaranteed to have at least 4 samples.
+	BuiltinVariableRateInterval = "__rate_interval"
+	// The duration of the time range.
+	BuiltinVariableRange = "__range"
+	// The duration of the time range in seconds.
+	BuiltinVariableRangeSeconds = "__range_s"
+)
+
+// DefaultScrapeInterval is the default scrape interval of Prometheus.
+const DefaultScrapeInterval = 15 * time.Second
+
+var (
+	builtinVariableNames = []string{
+		BuiltinVariableInterval,
+		BuiltinVariableRateInterval,
+		BuiltinVariableRange,
+		BuiltinVariableRangeSeconds,
+	}
+	builtinVariableRegex = regexp.MustCompile(`^\$(__\w+)`)
+)
+
+// IsBuiltinVariable checks if the name is a built-in variable.
+func IsBuiltinVariable(name string) bool {
+	return slices.Contains(builtinVariableNames, name)
+}
+
+// EvalBuiltinVariables evaluates values of built-in variables from the time range of the request.
+// The range variables are missing if the request doesn't have both start and end of the range.
+func EvalBuiltinVariables(queryRange *v1.Range, scrapeInterval time.Duration) map[string]string {
+	if scrapeInterval <= 0 {
+		scrapeInterval = DefaultScrapeInterval
+	}
+
+	interval := scrapeInterval
+	results := map[string]string{}
+
+	if queryRange != nil {
+		interval = max(queryRange.Step, scrapeInterval)
+
+		if !queryRange.Start.IsZero() && queryRange.End.After(queryRange.Start) {
+			rangeDuration := queryRange.End.Sub(queryRange.Start).Truncate(time.Second)
+			results[BuiltinVariableRange] = model.Duration(rangeDuration).String()
+			results[BuiltinVariableRangeSeconds] = strconv.FormatInt(
+				int64(rangeDuration/time.Second),
+				10,
+			)
+		}
+	}
+
+	results[BuiltinVariableInterval] = model.Duration(interval).String()
+	results[BuiltinVariableRateInterval] = model.Duration(
+		EvalRateInterval(interval, scrapeInterval),
+	).String()
+
+	return results
+}
+
+// EvalRateInterval evaluates the range of rate functions that is guaranteed to have at least 4 samples,
+// max(interval + scrape interval, 4 * scrape interval).
+func EvalRateInterval(interval time.Duration, scrapeInterval time.Duration) time.Duration {
+	if scrapeInterval <= 0 {
+		scrapeInterval = DefaultScrapeInterval
+	}
+
+	return max(interval+scrapeInterval, 4*scrapeInterval)
+}
+
+// matchTemplateVariable matches the variable at the start of the query, e.g. ${job} or $__interval.
+// Returns the name and the length of the variable, or 0 if the query doesn't start with a variable.
+func matchTemplateVariable(query string) (string, int) {
+	if match := promQLVariableRegex.FindStringSubmatchIndex(query); len(match) > 0 &&
+		match[0] == 0 {
+		return query[match[2]:match[3]], match[1]
+	}
+
+	if match := builtinVariableRegex.FindStringSubmatchIndex(query); len(match) > 0 &&
+		IsBuiltinVariable(query[match[2]:match[3]]) {
+		return query[match[2]:match[3]], match[1]
+	}
+
+	return "", 0
+}
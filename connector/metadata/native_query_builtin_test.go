@@ -0,0 +1,90 @@
+package metadata
+
+import (
+	"testing"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"gotest.tools/v3/assert"
+)
+
+func TestEvalBuiltinVariables(t *testing.T) {
+	end := time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		Name           string
+		Range          *v1.Range
+		ScrapeInterval time.Duration
+		Expected       map[string]string
+	}{
+		{
+			Name: "instant",
+			Expected: map[string]string{
+				BuiltinVariableInterval:     "15s",
+				BuiltinVariableRateInterval: "1m",
+			},
+		},
+		{
+			Name: "range",
+			Range: &v1.Range{
+				Start: end.Add(-time.Hour),
+				End:   end,
+				Step:  time.Minute,
+			},
+			Expected: map[string]string{
+				BuiltinVariableInterval:     "1m",
+				BuiltinVariableRateInterval: "1m15s",
+				BuiltinVariableRange:        "1h",
+				BuiltinVariableRangeSeconds: "3600",
+			},
+		},
+		{
+			Name: "step_less_than_scrape_interval",
+			Range: &v1.Range{
+				Start: end.Add(-5 * time.Minute),
+				End:   end,
+				Step:  time.Second,
+			},
+			ScrapeInterval: 30 * time.Second,
+			Expected: map[string]string{
+				BuiltinVariableInterval:     "30s",
+				BuiltinVariableRateInterval: "2m",
+				BuiltinVariableRange:        "5m",
+				BuiltinVariableRangeSeconds: "300",
+			},
+		},
+		{
+			Name: "open_range",
+			Range: &v1.Range{
+				End:  end,
+				Step: time.Minute,
+			},
+			Expected: map[string]string{
+				BuiltinVariableInterval:     "1m",
+				BuiltinVariableRateInterval: "1m15s",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert.DeepEqual(t, tc.Expected, EvalBuiltinVariables(tc.Range, tc.ScrapeInterval))
+		})
+	}
+}
+
+func TestNativeQueryValidateBuiltinVariables(t *testing.T) {
+	nq := NativeQuery{
+		Query: `sum(rate(up[$__rate_interval])) / ${__range_s}`,
+	}
+	assert.NilError(t, nq.Validate())
+
+	nq.Arguments = map[string]NativeQueryArgumentInfo{
+		BuiltinVariableInterval: {Type: string(ScalarDuration)},
+	}
+	assert.ErrorContains(
+		t,
+		nq.Validate(),
+		"the argument `__interval` conflicts with the built-in variable",
+	)
+}
@@ -0,0 +1,199 @@
+package metadata
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// NativeQueryFileExtension is the file extension of standalone native queries.
+const NativeQueryFileExtension = ".promql"
+
+const frontMatterDelimiter = "---"
+
+var nativeQuerySidecarExtensions = []string{".yaml", ".yml"}
+
+// NativeQueryFile represents the source file of a native query that is loaded from the native queries directory.
+type NativeQueryFile struct {
+	// The path of the .promql file
+	Path string
+	// The path of the YAML sidecar file. Metadata is in the front-matter of the .promql file if empty
+	SidecarPath string
+}
+
+// nativeQueryFileMetadata represents metadata of the native query in the front-matter or sidecar file.
+type nativeQueryFileMetadata struct {
+	Description *string                            `yaml:"description,omitempty"`
+	ResultType  NativeQueryResultType              `yaml:"result_type,omitempty"`
+	Labels      map[string]LabelInfo               `yaml:"labels"`
+	Arguments   map[string]NativeQueryArgumentInfo `yaml:"arguments"`
+}
+
+// File returns the source file of the native query. Returns nil if the query is defined in the configuration file.
+func (nq NativeQuery) File() *NativeQueryFile {
+	return nq.file
+}
+
+// ReadNativeQueryFiles reads native queries from .promql files of the directory.
+// Names of native queries are file names without the extension.
+func ReadNativeQueryFiles(dir string) (map[string]NativeQuery, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]NativeQuery)
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != NativeQueryFileExtension {
+			continue
+		}
+
+		name := strings.TrimSuffix(entry.Name(), NativeQueryFileExtension)
+
+		nq, err := ReadNativeQueryFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the native query %s: %w", name, err)
+		}
+
+		results[name] = *nq
+	}
+
+	return results, nil
+}
+
+// ReadNativeQueryFile reads the native query from the .promql file.
+// Metadata of the query is read from the YAML front-matter, or the sidecar file of the same name,
+// e.g. service_up.yaml of service_up.promql.
+func ReadNativeQueryFile(path string) (*NativeQuery, error) {
+	rawBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &NativeQueryFile{Path: path}
+
+	frontMatter, query, err := splitNativeQueryFrontMatter(string(rawBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	sidecarPath, err := findNativeQuerySidecar(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if sidecarPath != "" {
+		if frontMatter != "" {
+			return nil, fmt.Errorf(
+				"metadata must be either in the front-matter or the sidecar file %s",
+				sidecarPath,
+			)
+		}
+
+		sidecarBytes, err := os.ReadFile(sidecarPath)
+		if err != nil {
+			return nil, err
+		}
+
+		frontMatter = string(sidecarBytes)
+		file.SidecarPath = sidecarPath
+	}
+
+	var meta nativeQueryFileMetadata
+
+	if err := yaml.Unmarshal([]byte(frontMatter), &meta); err != nil {
+		return nil, fmt.Errorf("invalid metadata: %w", err)
+	}
+
+	return &NativeQuery{
+		Query:       query,
+		Description: meta.Description,
+		ResultType:  meta.ResultType,
+		Labels:      meta.Labels,
+		Arguments:   meta.Arguments,
+		file:        file,
+	}, nil
+}
+
+// WriteNativeQueryFile writes the native query back to its source file.
+// Metadata is written to the sidecar file if the query was read with a sidecar file.
+func WriteNativeQueryFile(nq NativeQuery) error {
+	if nq.file == nil {
+		return errors.New("the native query doesn't have a source file")
+	}
+
+	var buf bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(nativeQueryFileMetadata{
+		Description: nq.Description,
+		ResultType:  nq.ResultType,
+		Labels:      nq.Labels,
+		Arguments:   nq.Arguments,
+	}); err != nil {
+		return fmt.Errorf("failed to encode metadata of the native query: %w", err)
+	}
+
+	query := strings.TrimSpace(nq.Query) + "\n"
+
+	if nq.file.SidecarPath != "" {
+		if err := os.WriteFile(nq.file.SidecarPath, buf.Bytes(), 0o644); err != nil {
+			return err
+		}
+
+		return os.WriteFile(nq.file.Path, []byte(query), 0o644)
+	}
+
+	content := frontMatterDelimiter + "\n" + buf.String() + frontMatterDelimiter + "\n" + query
+
+	return os.WriteFile(nq.file.Path, []byte(content), 0o644)
+}
+
+// splitNativeQueryFrontMatter splits the YAML front-matter that is delimited by --- lines and the query.
+func splitNativeQueryFrontMatter(content string) (string, string, error) {
+	if strings.TrimRight(strings.SplitN(content, "\n", 2)[0], " \r") != frontMatterDelimiter {
+		return "", strings.TrimSpace(content), nil
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimRight(lines[i], " \r\n") == frontMatterDelimiter {
+			return strings.Join(
+					lines[1:i],
+					"",
+				), strings.TrimSpace(
+					strings.Join(lines[i+1:], ""),
+				), nil
+		}
+	}
+
+	return "", "", errors.New("the front-matter isn't closed by ---")
+}
+
+func findNativeQuerySidecar(path string) (string, error) {
+	basePath := strings.TrimSuffix(path, NativeQueryFileExtension)
+
+	for _, ext := range nativeQuerySidecarExtensions {
+		sidecarPath := basePath + ext
+
+		_, err := os.Stat(sidecarPath)
+		if err == nil {
+			return sidecarPath, nil
+		}
+
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+
+	return "", nil
+}
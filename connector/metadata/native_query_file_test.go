@@ -0,0 +1,165 @@
+package metadata
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hasura/ndc-sdk-go/utils"
+	"gotest.tools/v3/assert"
+)
+
+func TestReadConfigurationNativeQueryFiles(t *testing.T) {
+	dir := t.TempDir()
+	queryDir := filepath.Join(dir, "native_queries")
+	assert.NilError(t, os.Mkdir(queryDir, 0o755))
+
+	writeTestFile(t, filepath.Join(dir, "configuration.yaml"), `metadata:
+  metrics: {}
+  native_operations:
+    directory: native_queries
+    queries:
+      inline_up:
+        query: up
+        labels: {}
+        arguments: {}
+`)
+	writeTestFile(t, filepath.Join(queryDir, "service_up.promql"), `---
+description: Service status
+labels:
+  job: {}
+arguments:
+  job:
+    type: String
+---
+# the status of the service
+up{job="${job}"}
+`)
+	writeTestFile(t, filepath.Join(queryDir, "request_rate.promql"), "sum by (job) (rate(http_requests_total[${range}]))\n")
+	writeTestFile(t, filepath.Join(queryDir, "request_rate.yaml"), `arguments:
+  range:
+    type: Duration
+`)
+	writeTestFile(t, filepath.Join(queryDir, "up_count.promql"), `---
+result_type: scalar
+---
+scalar(count(up))
+`)
+	writeTestFile(t, filepath.Join(queryDir, "README.md"), "not a query")
+
+	config, err := ReadConfiguration(dir)
+	assert.NilError(t, err)
+
+	queries := config.Metadata.NativeOperations.Queries
+	assert.Equal(t, 4, len(queries))
+	assert.Assert(t, queries["inline_up"].File() == nil)
+
+	serviceUp := queries["service_up"]
+	assert.Equal(t, "# the status of the service\nup{job=\"${job}\"}", serviceUp.Query)
+	assert.DeepEqual(t, utils.ToPtr("Service status"), serviceUp.Description)
+	assert.DeepEqual(t, map[string]LabelInfo{"job": {}}, serviceUp.Labels)
+	assert.Equal(t, "", serviceUp.File().SidecarPath)
+	assert.NilError(t, serviceUp.Validate())
+
+	requestRate := queries["request_rate"]
+	assert.Equal(t, "sum by (job) (rate(http_requests_total[${range}]))", requestRate.Query)
+	assert.Equal(t, string(ScalarDuration), requestRate.Arguments["range"].Type)
+	assert.Equal(t, filepath.Join(queryDir, "request_rate.yaml"), requestRate.File().SidecarPath)
+
+	upCount := queries["up_count"]
+	assert.Equal(t, NativeQueryResultScalar, upCount.ResultType)
+	assert.NilError(t, upCount.Validate())
+
+	// write normalized queries back to their files.
+	serviceUp.Labels["instance"] = LabelInfo{}
+	assert.NilError(t, WriteNativeQueryFile(serviceUp))
+
+	requestRate.Description = utils.ToPtr("Request rate")
+	requestRate.ResultType = NativeQueryResultVector
+	assert.NilError(t, WriteNativeQueryFile(requestRate))
+
+	upCount.Description = utils.ToPtr("The number of targets")
+	assert.NilError(t, WriteNativeQueryFile(upCount))
+
+	config, err = ReadConfiguration(dir)
+	assert.NilError(t, err)
+
+	queries = config.Metadata.NativeOperations.Queries
+	assert.DeepEqual(t, map[string]LabelInfo{"instance": {}, "job": {}}, queries["service_up"].Labels)
+	assert.Equal(t, serviceUp.Query, queries["service_up"].Query)
+	assert.DeepEqual(t, utils.ToPtr("Request rate"), queries["request_rate"].Description)
+	assert.Equal(t, requestRate.Query, queries["request_rate"].Query)
+	assert.Equal(t, NativeQueryResultVector, queries["request_rate"].ResultType)
+	assert.Equal(t, NativeQueryResultScalar, queries["up_count"].ResultType)
+	assert.DeepEqual(t, utils.ToPtr("The number of targets"), queries["up_count"].Description)
+
+	queryBytes, err := os.ReadFile(filepath.Join(queryDir, "request_rate.promql"))
+	assert.NilError(t, err)
+	assert.Equal(t, "sum by (job) (rate(http_requests_total[${range}]))\n", string(queryBytes))
+}
+
+func TestReadNativeQueryFileErrors(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Files    map[string]string
+		ErrorMsg string
+	}{
+		{
+			Name: "unclosed_front_matter",
+			Files: map[string]string{
+				"up.promql": "---\ndescription: up\nup",
+			},
+			ErrorMsg: "the front-matter isn't closed by ---",
+		},
+		{
+			Name: "front_matter_and_sidecar",
+			Files: map[string]string{
+				"up.promql": "---\ndescription: up\n---\nup",
+				"up.yml":    "description: up",
+			},
+			ErrorMsg: "metadata must be either in the front-matter or the sidecar file",
+		},
+		{
+			Name: "invalid_metadata",
+			Files: map[string]string{
+				"up.promql": "---\nlabels: []\n---\nup",
+			},
+			ErrorMsg: "invalid metadata",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			for name, content := range tc.Files {
+				writeTestFile(t, filepath.Join(dir, name), content)
+			}
+
+			_, err := ReadNativeQueryFiles(dir)
+			assert.ErrorContains(t, err, tc.ErrorMsg)
+		})
+	}
+}
+
+func TestReadConfigurationDuplicatedNativeQueryFile(t *testing.T) {
+	dir := t.TempDir()
+
+	writeTestFile(t, filepath.Join(dir, "configuration.yaml"), `metadata:
+  native_operations:
+    directory: .
+    queries:
+      up:
+        query: up
+`)
+	writeTestFile(t, filepath.Join(dir, "up.promql"), "up")
+
+	_, err := ReadConfiguration(dir)
+	assert.ErrorContains(t, err, "duplicated native query name `up`")
+}
+
+func writeTestFile(t *testing.T, path string, content string) {
+	t.Helper()
+
+	assert.NilError(t, os.WriteFile(path, []byte(content), 0o644))
+}
@@ -0,0 +1,230 @@
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var fragmentNameRegex = regexp.MustCompile(`^[a-zA-Z_]\w*$`)
+
+// NativeQueryFragment represents a reusable PromQL expression that native queries reference
+// with the {{ name }} or {{ name(arg1, arg2) }} syntax.
+type NativeQueryFragment struct {
+	// The PromQL expression of the fragment. Parameters are referenced with the {{ param }} syntax.
+	// Fragments are expanded as text, so binary expressions should be wrapped in parentheses
+	Query string `json:"query"                 yaml:"query"`
+	// Description of the fragment
+	Description *string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Names of parameters that are replaced with arguments of the reference in order
+	Parameters []string `json:"parameters,omitempty"  yaml:"parameters,omitempty"`
+}
+
+// ExpandNativeQueryFragments replaces fragment references of the query with expressions of fragments.
+// Arguments of references are expanded before they are passed to the fragment,
+// fragments can reference other fragments but not themselves.
+func ExpandNativeQueryFragments(
+	query string,
+	fragments map[string]NativeQueryFragment,
+) (string, error) {
+	if !strings.Contains(query, "{{") {
+		return query, nil
+	}
+
+	return expandNativeQueryFragments(query, fragments, nil, nil)
+}
+
+func expandNativeQueryFragments(
+	query string,
+	fragments map[string]NativeQueryFragment,
+	params map[string]string,
+	stack []string,
+) (string, error) {
+	var sb strings.Builder
+
+	for {
+		start := strings.Index(query, "{{")
+		if start < 0 {
+			sb.WriteString(query)
+
+			return sb.String(), nil
+		}
+
+		end, err := findFragmentReferenceEnd(query, start+2)
+		if err != nil {
+			return "", err
+		}
+
+		value, err := expandFragmentReference(query[start+2:end], fragments, params, stack)
+		if err != nil {
+			return "", err
+		}
+
+		sb.WriteString(query[:start])
+		sb.WriteString(value)
+
+		query = query[end+2:]
+	}
+}
+
+// expandFragmentReference expands the reference, e.g. error_ratio(http_requests_total, "5.."),
+// with the parameter of the enclosing fragment or the expression of the fragment.
+func expandFragmentReference(
+	reference string,
+	fragments map[string]NativeQueryFragment,
+	params map[string]string,
+	stack []string,
+) (string, error) {
+	reference = strings.TrimSpace(reference)
+	name := reference
+
+	var args []string
+
+	hasArgs := false
+
+	if openIndex := strings.IndexByte(reference, '('); openIndex >= 0 {
+		if !strings.HasSuffix(reference, ")") {
+			return "", fmt.Errorf("invalid fragment reference {{ %s }}", reference)
+		}
+
+		name = strings.TrimSpace(reference[:openIndex])
+		args = splitFragmentArguments(reference[openIndex+1 : len(reference)-1])
+		hasArgs = true
+	}
+
+	if !fragmentNameRegex.MatchString(name) {
+		return "", fmt.Errorf("invalid fragment name `%s`", name)
+	}
+
+	if value, ok := params[name]; ok && !hasArgs {
+		return value, nil
+	}
+
+	fragment, ok := fragments[name]
+	if !ok {
+		return "", fmt.Errorf("fragment `%s` does not exist", name)
+	}
+
+	for _, parent := range stack {
+		if parent == name {
+			return "", fmt.Errorf(
+				"cyclic fragment references: %s -> %s",
+				strings.Join(stack, " -> "),
+				name,
+			)
+		}
+	}
+
+	if len(args) != len(fragment.Parameters) {
+		return "", fmt.Errorf(
+			"fragment `%s` expects %d arguments, got %d",
+			name,
+			len(fragment.Parameters),
+			len(args),
+		)
+	}
+
+	fragmentParams := make(map[string]string)
+
+	for i, arg := range args {
+		value, err := expandNativeQueryFragments(arg, fragments, params, stack)
+		if err != nil {
+			return "", err
+		}
+
+		fragmentParams[fragment.Parameters[i]] = value
+	}
+
+	return expandNativeQueryFragments(
+		fragment.Query,
+		fragments,
+		fragmentParams,
+		append(stack, name),
+	)
+}
+
+// findFragmentReferenceEnd returns the position of the closing }} of the reference.
+// Braces and parentheses of arguments are skipped, e.g. {{ ratio(up{job="node"}) }}.
+func findFragmentReferenceEnd(query string, start int) (int, error) {
+	var quote byte
+
+	depth := 0
+
+	for i := start; i < len(query); i++ {
+		c := query[i]
+
+		if quote != 0 {
+			switch {
+			case c == '\\' && quote != '`':
+				i++
+			case c == quote:
+				quote = 0
+			}
+
+			continue
+		}
+
+		switch c {
+		case '"', '\'', '`':
+			quote = c
+		case '(', '{', '[':
+			depth++
+		case ')', ']':
+			depth--
+		case '}':
+			if depth == 0 && i+1 < len(query) && query[i+1] == '}' {
+				return i, nil
+			}
+
+			depth--
+		}
+	}
+
+	return 0, errors.New("the fragment reference isn't closed by }}")
+}
+
+// splitFragmentArguments splits arguments of the reference by top-level commas.
+func splitFragmentArguments(input string) []string {
+	if strings.TrimSpace(input) == "" {
+		return nil
+	}
+
+	var results []string
+
+	var quote byte
+
+	depth := 0
+	start := 0
+
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+
+		if quote != 0 {
+			switch {
+			case c == '\\' && quote != '`':
+				i++
+			case c == quote:
+				quote = 0
+			}
+
+			continue
+		}
+
+		switch c {
+		case '"', '\'', '`':
+			quote = c
+		case '(', '{', '[':
+			depth++
+		case ')', '}', ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				results = append(results, strings.TrimSpace(input[start:i]))
+				start = i + 1
+			}
+		}
+	}
+
+	return append(results, strings.TrimSpace(input[start:]))
+}
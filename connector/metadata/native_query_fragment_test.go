@@ -0,0 +1,130 @@
+package metadata
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestExpandNativeQueryFragments(t *testing.T) {
+	fragments := map[string]NativeQueryFragment{
+		"error_ratio": {
+			Query:      `(sum by (job) (rate({{ metric }}{code=~"{{ code }}"}[${range}])) / sum by (job) (rate({{ metric }}[${range}])))`,
+			Parameters: []string{"metric", "code"},
+		},
+		"server_errors": {
+			Query: `{{ error_ratio(http_requests_total, 5..) }}`,
+		},
+		"job_matcher": {
+			Query: `job="${job}"`,
+		},
+		"ratio": {
+			Query:      `({{ numerator }} / {{ denominator }})`,
+			Parameters: []string{"numerator", "denominator"},
+		},
+		"cycle_a": {
+			Query: `{{ cycle_b }}`,
+		},
+		"cycle_b": {
+			Query:      `{{ cycle_a() }}`,
+			Parameters: []string{},
+		},
+	}
+
+	testCases := []struct {
+		Name     string
+		Query    string
+		Expected string
+		ErrorMsg string
+	}{
+		{
+			Name:     "no_fragments",
+			Query:    `up{job="${job}"}`,
+			Expected: `up{job="${job}"}`,
+		},
+		{
+			Name:     "nested",
+			Query:    `{{ server_errors }} > ${threshold}`,
+			Expected: `(sum by (job) (rate(http_requests_total{code=~"5.."}[${range}])) / sum by (job) (rate(http_requests_total[${range}]))) > ${threshold}`,
+		},
+		{
+			Name:     "label_matcher",
+			Query:    `up{ {{job_matcher}}, env="prod"}`,
+			Expected: `up{ job="${job}", env="prod"}`,
+		},
+		{
+			Name:     "arguments",
+			Query:    `{{ ratio(sum(up{job=~"a,b"}), count(up{ {{ job_matcher }} })) }}`,
+			Expected: `(sum(up{job=~"a,b"}) / count(up{ job="${job}" }))`,
+		},
+		{
+			Name:     "unknown",
+			Query:    `{{ unknown }}`,
+			ErrorMsg: "fragment `unknown` does not exist",
+		},
+		{
+			Name:     "cycle",
+			Query:    `{{ cycle_a }}`,
+			ErrorMsg: "cyclic fragment references: cycle_a -> cycle_b -> cycle_a",
+		},
+		{
+			Name:     "arity",
+			Query:    `{{ ratio(up) }}`,
+			ErrorMsg: "fragment `ratio` expects 2 arguments, got 1",
+		},
+		{
+			Name:     "parameter_outside_fragment",
+			Query:    `{{ metric }}`,
+			ErrorMsg: "fragment `metric` does not exist",
+		},
+		{
+			Name:     "unclosed",
+			Query:    `{{ server_errors`,
+			ErrorMsg: "the fragment reference isn't closed by }}",
+		},
+		{
+			Name:     "invalid_name",
+			Query:    `{{ 1 + 1 }}`,
+			ErrorMsg: "invalid fragment name `1 + 1`",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			result, err := ExpandNativeQueryFragments(tc.Query, fragments)
+			if tc.ErrorMsg != "" {
+				assert.ErrorContains(t, err, tc.ErrorMsg)
+
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Equal(t, tc.Expected, result)
+		})
+	}
+}
+
+func TestNativeQueryExpandFragments(t *testing.T) {
+	nq := NativeQuery{
+		Query: `{{ up_by_job }} > ${value}`,
+		Arguments: map[string]NativeQueryArgumentInfo{
+			"job":   {Type: string(ScalarString)},
+			"value": {Type: string(ScalarFloat64)},
+		},
+	}
+
+	assert.ErrorContains(t, nq.Validate(), "parse error")
+	assert.NilError(t, nq.ExpandFragments(map[string]NativeQueryFragment{
+		"up_by_job": {Query: `sum by (job) (up{job="${job}"})`},
+	}))
+	assert.NilError(t, nq.Validate())
+
+	template, err := nq.Template()
+	assert.NilError(t, err)
+	assert.Equal(t, `sum by (job) (up{job="${job}"}) > ${value}`, template.Query)
+	assert.Equal(t, 2, len(template.Variables))
+
+	// the expansion is stale if the query is changed.
+	nq.Query = `up`
+	assert.Equal(t, `up`, nq.ExpandedQuery())
+}
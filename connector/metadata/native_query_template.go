@@ -0,0 +1,422 @@
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/prometheus/prometheus/promql/parser/posrange"
+)
+
+// NativeQueryVariableContext represents the syntactic context of a variable in the native query.
+type NativeQueryVariableContext string
+
+const (
+	// The variable is a part of a string literal, e.g. label_replace(up, "dst", "${value}", "src", "(.*)").
+	VariableContextString NativeQueryVariableContext = "string"
+	// The variable is a part of a label matcher value, e.g. up{job="${job}"}.
+	VariableContextLabelValue NativeQueryVariableContext = "label_value"
+	// The variable is a duration of range vector selectors, subqueries or offset modifiers, e.g. up[${range}].
+	VariableContextDuration NativeQueryVariableContext = "duration"
+	// The variable is a number literal, e.g. up > ${value}.
+	VariableContextNumber NativeQueryVariableContext = "number"
+	// The variable is a part of the label list of grouping or vector matching, e.g. sum by (${labels}).
+	VariableContextLabelList NativeQueryVariableContext = "label_list"
+	// The variable is a part of the metric name of vector selectors, e.g. ${metric}{job="x"}.
+	VariableContextMetricName NativeQueryVariableContext = "metric_name"
+	// The variable is a part of the label name of label matchers, e.g. up{${label}="x"}.
+	VariableContextLabelName NativeQueryVariableContext = "label_name"
+)
+
+// keywords that are followed by label lists.
+var labelListKeywords = []string{"by", "without", "on", "ignoring", "group_left", "group_right"}
+
+// NativeQueryVariable represents a variable occurrence in the native query template.
+type NativeQueryVariable struct {
+	Name string
+	// The byte offset of the variable in the query.
+	Start int
+	End   int
+	// The syntactic context that decides how the value is substituted.
+	Context NativeQueryVariableContext
+	// The quote character of the string literal that contains the variable.
+	Quote rune
+	// The matching operator if the variable is a label matcher value, e.g. =~.
+	MatchOperator string
+	// The index of the optional block that contains the variable, -1 if the variable is required.
+	Block int
+}
+
+// IsRegex checks if the variable is a value of the regex label matcher.
+func (nqv NativeQueryVariable) IsRegex() bool {
+	return nqv.MatchOperator == "=~" || nqv.MatchOperator == "!~"
+}
+
+// NativeQueryTemplateBlock represents an optional section of the template, e.g. [[, namespace="${namespace}"]].
+// The section is dropped if any argument in the section is null.
+type NativeQueryTemplateBlock struct {
+	// The byte offset of the opening [[ and after the closing ]].
+	Start int
+	End   int
+}
+
+// NativeQueryTemplate represents the scanned template of a native query.
+type NativeQueryTemplate struct {
+	Query     string
+	Variables []NativeQueryVariable
+	Blocks    []NativeQueryTemplateBlock
+}
+
+// ScanNativeQueryTemplate scans variables and optional blocks of the native query
+// and evaluates syntactic contexts of variables by the PromQL grammar.
+func ScanNativeQueryTemplate(
+	query string,
+) (*NativeQueryTemplate, error) { //nolint:gocognit,cyclop,funlen
+	result := &NativeQueryTemplate{
+		Query: query,
+	}
+
+	var quote rune
+
+	var quoteStart, braceDepth, bracketDepth, parenDepth, labelListDepth int
+
+	var lastWord string
+
+	wordStart := -1
+	block := -1
+	blockBracketDepth := 0
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		if c == '$' {
+			if name, length := matchTemplateVariable(query[i:]); length > 0 {
+				variable := NativeQueryVariable{
+					Name:  name,
+					Start: i,
+					End:   i + length,
+					Block: block,
+				}
+
+				switch {
+				case quote != 0 && braceDepth > 0:
+					variable.Context = VariableContextLabelValue
+					variable.Quote = quote
+					variable.MatchOperator = findMatchOperator(query[:quoteStart])
+				case quote != 0:
+					variable.Context = VariableContextString
+					variable.Quote = quote
+				case labelListDepth > 0:
+					variable.Context = VariableContextLabelList
+				case braceDepth > 0:
+					variable.Context = VariableContextLabelName
+				case bracketDepth > 0 || lastWord == "offset":
+					variable.Context = VariableContextDuration
+				case (wordStart >= 0 && !isDigit(query[wordStart])) ||
+					isMetricNamePosition(query[variable.End:]):
+					variable.Context = VariableContextMetricName
+				default:
+					variable.Context = VariableContextNumber
+				}
+
+				result.Variables = append(result.Variables, variable)
+				i = variable.End - 1
+				lastWord = ""
+
+				continue
+			}
+		}
+
+		if quote != 0 {
+			switch {
+			case c == '\\' && quote != '`':
+				i++
+			case rune(c) == quote:
+				quote = 0
+			}
+
+			continue
+		}
+
+		if isIdentifierChar(c) {
+			if wordStart < 0 {
+				wordStart = i
+			}
+
+			continue
+		}
+
+		if wordStart >= 0 {
+			lastWord = query[wordStart:i]
+			wordStart = -1
+		}
+
+		prevWord := lastWord
+
+		if !unicode.IsSpace(rune(c)) {
+			lastWord = ""
+		}
+
+		switch {
+		case c == '[' && strings.HasPrefix(query[i:], "[["):
+			if block >= 0 {
+				return nil, fmt.Errorf("nested optional blocks at position %d are not supported", i)
+			}
+
+			block = len(result.Blocks)
+			blockBracketDepth = bracketDepth
+			result.Blocks = append(result.Blocks, NativeQueryTemplateBlock{Start: i})
+			i++
+		case c == ']' && block >= 0 && bracketDepth == blockBracketDepth &&
+			strings.HasPrefix(query[i:], "]]"):
+			result.Blocks[block].End = i + 2
+			block = -1
+			i++
+		case c == '#':
+			// skip the comment to the end of the line.
+			if end := strings.IndexByte(query[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(query)
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = rune(c)
+			quoteStart = i
+		case c == '{':
+			braceDepth++
+		case c == '}':
+			braceDepth--
+		case c == '[':
+			bracketDepth++
+		case c == ']':
+			bracketDepth--
+		case c == '(':
+			parenDepth++
+
+			if labelListDepth == 0 &&
+				slices.Contains(labelListKeywords, strings.ToLower(prevWord)) {
+				labelListDepth = parenDepth
+			}
+		case c == ')':
+			if parenDepth == labelListDepth {
+				labelListDepth = 0
+			}
+
+			parenDepth--
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unclosed string literal at position %d", quoteStart)
+	}
+
+	if block >= 0 {
+		return nil, fmt.Errorf("unclosed optional block at position %d", result.Blocks[block].Start)
+	}
+
+	return result, nil
+}
+
+// Render replaces variables of the template with values that are formatted by the callback function.
+// The callback returns false if the value is null, so optional blocks of the variable are dropped.
+func (nqt NativeQueryTemplate) Render(
+	format func(variable NativeQueryVariable) (string, bool, error),
+) (string, error) {
+	replacements, err := nqt.evalReplacements(format)
+	if err != nil {
+		return "", err
+	}
+
+	query, _ := applyTemplateReplacements(nqt.Query, replacements)
+
+	return query, nil
+}
+
+// templateReplacement represents a text range of the template that is replaced.
+type templateReplacement struct {
+	Start int
+	End   int
+	Text  string
+}
+
+func (nqt NativeQueryTemplate) evalReplacements(
+	format func(variable NativeQueryVariable) (string, bool, error),
+) ([]templateReplacement, error) {
+	droppedBlocks := make([]bool, len(nqt.Blocks))
+	values := make([]string, len(nqt.Variables))
+
+	for i, variable := range nqt.Variables {
+		if variable.Block >= 0 && droppedBlocks[variable.Block] {
+			continue
+		}
+
+		value, ok, err := format(variable)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			if variable.Block < 0 {
+				return nil, fmt.Errorf("argument `%s` is required", variable.Name)
+			}
+
+			droppedBlocks[variable.Block] = true
+
+			continue
+		}
+
+		values[i] = value
+	}
+
+	results := make([]templateReplacement, 0, len(nqt.Variables)+2*len(nqt.Blocks))
+
+	for i, block := range nqt.Blocks {
+		if droppedBlocks[i] {
+			results = append(results, templateReplacement{Start: block.Start, End: block.End})
+
+			continue
+		}
+
+		results = append(
+			results,
+			templateReplacement{Start: block.Start, End: block.Start + 2},
+			templateReplacement{Start: block.End - 2, End: block.End},
+		)
+	}
+
+	for i, variable := range nqt.Variables {
+		if variable.Block >= 0 && droppedBlocks[variable.Block] {
+			continue
+		}
+
+		results = append(results, templateReplacement{
+			Start: variable.Start,
+			End:   variable.End,
+			Text:  values[i],
+		})
+	}
+
+	slices.SortFunc(results, func(a, b templateReplacement) int {
+		return a.Start - b.Start
+	})
+
+	return results, nil
+}
+
+// applyTemplateReplacements replaces text ranges of the query
+// and returns the mapping of positions to the original query.
+func applyTemplateReplacements(
+	query string,
+	replacements []templateReplacement,
+) (string, placeholderMapping) {
+	mapping := make(placeholderMapping, 0, len(replacements))
+
+	var sb strings.Builder
+
+	var lastIndex int
+
+	for _, replacement := range replacements {
+		sb.WriteString(query[lastIndex:replacement.Start])
+		mapping = append(mapping, placeholderPosition{
+			Position:       posrange.Pos(sb.Len()),
+			Length:         posrange.Pos(len(replacement.Text)),
+			Original:       posrange.Pos(replacement.Start),
+			OriginalLength: posrange.Pos(replacement.End - replacement.Start),
+		})
+		sb.WriteString(replacement.Text)
+		lastIndex = replacement.End
+	}
+
+	sb.WriteString(query[lastIndex:])
+
+	return sb.String(), mapping
+}
+
+// EscapePromQLString escapes the value to be inserted into the string literal with the quote character.
+// Raw strings can't escape backticks, so the value must not contain them.
+func EscapePromQLString(value string, quote rune) (string, error) {
+	if quote == '`' {
+		if strings.ContainsRune(value, '`') {
+			return "", errors.New("raw strings must not contain backticks")
+		}
+
+		return value, nil
+	}
+
+	var sb strings.Builder
+
+	for _, r := range value {
+		switch {
+		case r == quote || r == '\\':
+			sb.WriteRune('\\')
+			sb.WriteRune(r)
+		case r == '"' || r == '\'' || strconv.IsPrint(r):
+			sb.WriteRune(r)
+		default:
+			quoted := strconv.QuoteRune(r)
+			sb.WriteString(quoted[1 : len(quoted)-1])
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// ValidateLabelMatcherRegex checks if the value is a valid regular expression of label matchers.
+func ValidateLabelMatcherRegex(value string) error {
+	// Prometheus anchors label matcher regular expressions.
+	_, err := regexp.Compile("^(?:" + value + ")$")
+
+	return err
+}
+
+// findMatchOperator returns the label matching operator before the string literal.
+func findMatchOperator(query string) string {
+	query = strings.TrimRightFunc(query, unicode.IsSpace)
+
+	for _, op := range []string{"=~", "!~", "!=", "="} {
+		if strings.HasSuffix(query, op) {
+			return op
+		}
+	}
+
+	return ""
+}
+
+// isMetricNamePosition checks if the text after the variable continues a vector selector,
+// i.e. the variable is a part of an identifier or followed by label matchers, a range, or modifiers.
+func isMetricNamePosition(query string) bool {
+	suffix := strings.TrimLeftFunc(query, func(r rune) bool {
+		return r < unicode.MaxASCII && isIdentifierChar(byte(r))
+	})
+	if strings.ContainsFunc(query[:len(query)-len(suffix)], func(r rune) bool {
+		return !isDigit(byte(r))
+	}) {
+		return true
+	}
+
+	query = strings.TrimLeftFunc(query, unicode.IsSpace)
+	if query == "" {
+		return false
+	}
+
+	switch query[0] {
+	case '{', '[', '@':
+		return true
+	}
+
+	return strings.HasPrefix(strings.ToLower(query), "offset") &&
+		(len(query) == len("offset") || !isIdentifierChar(query[len("offset")]))
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentifierChar(c byte) bool {
+	return c == '_' || c == ':' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9')
+}
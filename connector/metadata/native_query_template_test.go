@@ -0,0 +1,150 @@
+package metadata
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestScanNativeQueryTemplate(t *testing.T) {
+	testCases := []struct {
+		Query    string
+		Expected []NativeQueryVariable
+		ErrorMsg string
+	}{
+		{
+			Query: `up{job=~"${job}"} offset ${offset} > ${value}`,
+			Expected: []NativeQueryVariable{
+				{
+					Name:          "job",
+					Start:         9,
+					End:           15,
+					Context:       VariableContextLabelValue,
+					Quote:         '"',
+					MatchOperator: "=~",
+					Block:         -1,
+				},
+				{Name: "offset", Start: 25, End: 34, Context: VariableContextDuration, Block: -1},
+				{Name: "value", Start: 37, End: 45, Context: VariableContextNumber, Block: -1},
+			},
+		},
+		{
+			Query: `rate(up[$__rate_interval]) / ${__range_s} > $__other`,
+			Expected: []NativeQueryVariable{
+				{
+					Name:    "__rate_interval",
+					Start:   8,
+					End:     24,
+					Context: VariableContextDuration,
+					Block:   -1,
+				},
+				{Name: "__range_s", Start: 29, End: 41, Context: VariableContextNumber, Block: -1},
+			},
+		},
+		{
+			Query: "rate(up[${range}]) # ${comment}\n" + `+ label_join(up, "d", '${sep}', "a", "b")`,
+			Expected: []NativeQueryVariable{
+				{Name: "range", Start: 8, End: 16, Context: VariableContextDuration, Block: -1},
+				{
+					Name:    "sep",
+					Start:   55,
+					End:     61,
+					Context: VariableContextString,
+					Quote:   '\'',
+					Block:   -1,
+				},
+			},
+		},
+		{
+			Query: `up{job="\"${job}"}`,
+			Expected: []NativeQueryVariable{
+				{
+					Name:          "job",
+					Start:         10,
+					End:           16,
+					Context:       VariableContextLabelValue,
+					Quote:         '"',
+					MatchOperator: "=",
+					Block:         -1,
+				},
+			},
+		},
+		{
+			Query: `sum by (job, ${labels}) (up{job="x"[[, namespace=~"${namespace}"]]}) > ${value}`,
+			Expected: []NativeQueryVariable{
+				{Name: "labels", Start: 13, End: 22, Context: VariableContextLabelList, Block: -1},
+				{
+					Name:          "namespace",
+					Start:         51,
+					End:           63,
+					Context:       VariableContextLabelValue,
+					Quote:         '"',
+					MatchOperator: "=~",
+					Block:         0,
+				},
+				{Name: "value", Start: 71, End: 79, Context: VariableContextNumber, Block: -1},
+			},
+		},
+		{
+			Query: `rate(${metric}{${label}="x"}[5m]) + node_${name}_total offset 1h + ${other} > ${value}0`,
+			Expected: []NativeQueryVariable{
+				{Name: "metric", Start: 5, End: 14, Context: VariableContextMetricName, Block: -1},
+				{Name: "label", Start: 15, End: 23, Context: VariableContextLabelName, Block: -1},
+				{Name: "name", Start: 41, End: 48, Context: VariableContextMetricName, Block: -1},
+				{Name: "other", Start: 67, End: 75, Context: VariableContextNumber, Block: -1},
+				{Name: "value", Start: 78, End: 86, Context: VariableContextNumber, Block: -1},
+			},
+		},
+		{
+			Query:    `up{job="${job}}`,
+			ErrorMsg: "unclosed string literal at position 7",
+		},
+		{
+			Query:    `up{job="x"[[, namespace="${namespace}"}`,
+			ErrorMsg: "unclosed optional block at position 10",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Query, func(t *testing.T) {
+			result, err := ScanNativeQueryTemplate(tc.Query)
+			if tc.ErrorMsg != "" {
+				assert.Error(t, err, tc.ErrorMsg)
+
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.DeepEqual(t, tc.Expected, result.Variables)
+		})
+	}
+}
+
+func TestEscapePromQLString(t *testing.T) {
+	testCases := []struct {
+		Value    string
+		Quote    rune
+		Expected string
+		ErrorMsg string
+	}{
+		{Value: `a"b'c\d`, Quote: '"', Expected: `a\"b'c\\d`},
+		{Value: `a"b'c\d`, Quote: '\'', Expected: `a"b\'c\\d`},
+		{Value: "a\nb\tc", Quote: '"', Expected: `a\nb\tc`},
+		{Value: `a"b\c`, Quote: '`', Expected: `a"b\c`},
+		{Value: "a`b", Quote: '`', ErrorMsg: "raw strings must not contain backticks"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Value, func(t *testing.T) {
+			result, err := EscapePromQLString(tc.Value, tc.Quote)
+			if tc.ErrorMsg != "" {
+				assert.Error(t, err, tc.ErrorMsg)
+
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Equal(t, tc.Expected, result)
+		})
+	}
+}
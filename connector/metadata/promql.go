@@ -431,5 +431,53 @@ func createPromQLFunctionObjectFields( //nolint:funlen,maintidx
 			Description: utils.ToPtr("Converts degrees to radians for all elements in v"),
 			Type:        schema.NewNullableNamedType(string(ScalarBoolean)).Encode(),
 		},
+		string(Minute): schema.ObjectField{
+			Description: utils.ToPtr(
+				"Returns the minute of the hour for each of the given times, in 0 to 59. Sample values must be unix timestamps in seconds, e.g. the result of the timestamp function. Calendar fields are evaluated in the requested time zone",
+			),
+			Type: schema.NewNullableNamedType(string(ScalarBoolean)).Encode(),
+		},
+		string(Hour): schema.ObjectField{
+			Description: utils.ToPtr(
+				"Returns the hour of the day for each of the given times, in 0 to 23. Sample values must be unix timestamps in seconds, e.g. the result of the timestamp function. Calendar fields are evaluated in the requested time zone",
+			),
+			Type: schema.NewNullableNamedType(string(ScalarBoolean)).Encode(),
+		},
+		string(DayOfMonth): schema.ObjectField{
+			Description: utils.ToPtr(
+				"Returns the day of the month for each of the given times, in 1 to 31. Sample values must be unix timestamps in seconds, e.g. the result of the timestamp function. Calendar fields are evaluated in the requested time zone",
+			),
+			Type: schema.NewNullableNamedType(string(ScalarBoolean)).Encode(),
+		},
+		string(DayOfWeek): schema.ObjectField{
+			Description: utils.ToPtr(
+				"Returns the day of the week for each of the given times, in 0 to 6, where 0 means Sunday. Sample values must be unix timestamps in seconds, e.g. the result of the timestamp function. Calendar fields are evaluated in the requested time zone",
+			),
+			Type: schema.NewNullableNamedType(string(ScalarBoolean)).Encode(),
+		},
+		string(DayOfYear): schema.ObjectField{
+			Description: utils.ToPtr(
+				"Returns the day of the year for each of the given times, in 1 to 365 for non-leap years, and 1 to 366 in leap years. Sample values must be unix timestamps in seconds, e.g. the result of the timestamp function. Calendar fields are evaluated in the requested time zone",
+			),
+			Type: schema.NewNullableNamedType(string(ScalarBoolean)).Encode(),
+		},
+		string(DaysInMonth): schema.ObjectField{
+			Description: utils.ToPtr(
+				"Returns number of days in the month for each of the given times, in 28 to 31. Sample values must be unix timestamps in seconds, e.g. the result of the timestamp function. Calendar fields are evaluated in the requested time zone",
+			),
+			Type: schema.NewNullableNamedType(string(ScalarBoolean)).Encode(),
+		},
+		string(Month): schema.ObjectField{
+			Description: utils.ToPtr(
+				"Returns the month of the year for each of the given times, in 1 to 12. Sample values must be unix timestamps in seconds, e.g. the result of the timestamp function. Calendar fields are evaluated in the requested time zone",
+			),
+			Type: schema.NewNullableNamedType(string(ScalarBoolean)).Encode(),
+		},
+		string(Year): schema.ObjectField{
+			Description: utils.ToPtr(
+				"Returns the year for each of the given times. Sample values must be unix timestamps in seconds, e.g. the result of the timestamp function. Calendar fields are evaluated in the requested time zone",
+			),
+			Type: schema.NewNullableNamedType(string(ScalarBoolean)).Encode(),
+		},
 	}
 }
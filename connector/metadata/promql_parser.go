@@ -0,0 +1,570 @@
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/hasura/ndc-sdk-go/utils"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/promql/parser/posrange"
+)
+
+// placeholderLabel is the placeholder of string variables when the native query is parsed.
+// It's a valid label name, so the placeholder can be found in inferred labels.
+const placeholderLabel = "__placeholder__"
+
+func init() {
+	// the parser checks experimental aggregations with the process-wide flag only, so the flag is always enabled
+	// and experimental functions of native queries are rejected by ValidateExperimentalFunctions instead.
+	parser.EnableExperimentalFunctions = true
+}
+
+// ParsePromQL parses and type-checks the PromQL expression without a Prometheus server.
+// Experimental functions and aggregations are allowed, the Prometheus server decides if they are enabled.
+// Errors contain the line and column position of the expression.
+func ParsePromQL(query string) (parser.Expr, error) {
+	return parser.ParseExpr(query)
+}
+
+// FindPromQLExperimentalFunction returns the name of the first experimental function or aggregation of the expression.
+func FindPromQLExperimentalFunction(expr parser.Expr) string {
+	var result string
+
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		if result != "" {
+			return nil
+		}
+
+		switch n := node.(type) {
+		case *parser.Call:
+			if n.Func != nil && n.Func.Experimental {
+				result = n.Func.Name
+			}
+		case *parser.AggregateExpr:
+			if n.Op.IsExperimentalAggregator() {
+				result = n.Op.String()
+			}
+		}
+
+		return nil
+	})
+
+	return result
+}
+
+// ValidateExperimentalFunctions checks if the validated native query doesn't use experimental functions and aggregations,
+// e.g. limitk and sort_by_label.
+func (nq NativeQuery) ValidateExperimentalFunctions() error {
+	if nq.IsJoinQuery() {
+		expressions := nq.expressions
+		if expressions == nil {
+			expressions = nq.ExpressionQueries()
+		}
+
+		for _, name := range utils.GetSortedKeys(expressions) {
+			if err := expressions[name].ValidateExperimentalFunctions(); err != nil {
+				return fmt.Errorf("expression `%s`: %w", name, err)
+			}
+		}
+
+		return nil
+	}
+
+	template, err := nq.Template()
+	if err != nil {
+		return err
+	}
+
+	expr, err := nq.validatePlaceholderQuery(template, false)
+	if err != nil {
+		return err
+	}
+
+	if name := FindPromQLExperimentalFunction(expr); name != "" {
+		return fmt.Errorf(
+			"%s() is experimental and must be enabled with the promql_experimental_functions setting",
+			name,
+		)
+	}
+
+	return nil
+}
+
+// Validate checks arguments, the syntax and types of the native query.
+// Variables are replaced with placeholder values of their syntactic contexts before parsing,
+// positions of errors refer to the original query though.
+// If the query has optional blocks, the query without blocks of nullable arguments is also validated.
+// Fragments must be expanded by ExpandFragments before, errors of queries with fragments refer to the expanded query.
+// The scanned template is kept to substitute arguments of requests.
+func (nq *NativeQuery) Validate() error {
+	if nq.IsJoinQuery() {
+		return nq.validateJoinQuery()
+	}
+
+	template, err := nq.scanTemplate(nq.ExpandedQuery())
+	if err != nil {
+		return err
+	}
+
+	if nq.ResultType != "" && !slices.Contains(nativeQueryResultTypes, nq.ResultType) {
+		return fmt.Errorf("invalid result type `%s`", nq.ResultType)
+	}
+
+	for name, arg := range nq.Arguments {
+		if IsBuiltinVariable(name) {
+			return fmt.Errorf("the argument `%s` conflicts with the built-in variable", name)
+		}
+
+		if err := arg.Validate(); err != nil {
+			return fmt.Errorf("argument `%s`: %w", name, err)
+		}
+	}
+
+	for _, variable := range template.Variables {
+		if err := nq.validateVariable(variable); err != nil {
+			return err
+		}
+	}
+
+	expr, err := nq.validatePlaceholderQuery(template, false)
+	if err != nil {
+		return err
+	}
+
+	if resultType := EvalPromQLResultType(expr); nq.ResultType != "" &&
+		nq.ResultType != resultType {
+		return fmt.Errorf(
+			"the query returns a %s result, but the result type is %s",
+			resultType,
+			nq.ResultType,
+		)
+	}
+
+	if len(template.Blocks) > 0 {
+		if _, err := nq.validatePlaceholderQuery(template, true); err != nil {
+			return fmt.Errorf("optional blocks are dropped: %w", err)
+		}
+	}
+
+	nq.template = template
+
+	return nil
+}
+
+// Template returns the scanned template of the native query that fragments are expanded.
+func (nq NativeQuery) Template() (*NativeQueryTemplate, error) {
+	query := nq.ExpandedQuery()
+	if nq.template != nil && nq.template.Query == query {
+		return nq.template, nil
+	}
+
+	return nq.scanTemplate(query)
+}
+
+// scanTemplate scans the template of the query and classifies unquoted variables by types of arguments.
+func (nq NativeQuery) scanTemplate(query string) (*NativeQueryTemplate, error) {
+	template, err := ScanNativeQueryTemplate(query)
+	if err != nil {
+		return nil, err
+	}
+
+	nq.classifyIdentifierVariables(template)
+
+	return template, nil
+}
+
+// classifyIdentifierVariables changes unquoted variables that the scanner can't tell from numbers to metric names.
+// String arguments are always metric names. Untyped arguments are metric names
+// if a number literal isn't allowed in the position, e.g. sum(${metric}).
+func (nq NativeQuery) classifyIdentifierVariables(template *NativeQueryTemplate) {
+	isUntypedNumber := func(variable NativeQueryVariable) bool {
+		return variable.Context == VariableContextNumber && nq.Arguments[variable.Name].Type == ""
+	}
+
+	for i, variable := range template.Variables {
+		if variable.Context == VariableContextNumber &&
+			ScalarName(nq.Arguments[variable.Name].Type) == ScalarString {
+			template.Variables[i].Context = VariableContextMetricName
+		}
+	}
+
+	if !slices.ContainsFunc(template.Variables, isUntypedNumber) {
+		return
+	}
+
+	// the parser reports the first type error only, so variables are changed one by one.
+	for range template.Variables {
+		query, mapping, err := nq.renderPlaceholderQuery(template, false)
+		if err != nil {
+			return
+		}
+
+		var parseErrs parser.ParseErrors
+		if _, err := ParsePromQL(query); !errors.As(err, &parseErrs) || len(parseErrs) == 0 {
+			return
+		}
+
+		start := int(mapping.OriginalPosition(parseErrs[0].PositionRange.Start))
+		end := int(mapping.OriginalPosition(parseErrs[0].PositionRange.End-1)) + 1
+
+		index := slices.IndexFunc(template.Variables, func(variable NativeQueryVariable) bool {
+			return isUntypedNumber(variable) && variable.Start == start && variable.End >= end
+		})
+		if index < 0 {
+			return
+		}
+
+		template.Variables[index].Context = VariableContextMetricName
+	}
+}
+
+// ExpandFragments expands fragment references of the query.
+// The expanded query is validated and substituted with arguments instead of the original query.
+func (nq *NativeQuery) ExpandFragments(fragments map[string]NativeQueryFragment) error {
+	if nq.IsJoinQuery() {
+		return nq.expandExpressionFragments(fragments)
+	}
+
+	expandedQuery, err := ExpandNativeQueryFragments(nq.Query, fragments)
+	if err != nil {
+		return err
+	}
+
+	nq.expansion = &nativeQueryExpansion{
+		Query:         nq.Query,
+		ExpandedQuery: expandedQuery,
+	}
+
+	return nil
+}
+
+// ExpandedQuery returns the query that fragments are expanded.
+// Returns the original query if fragments aren't expanded or the query is changed after the expansion.
+func (nq NativeQuery) ExpandedQuery() string {
+	if nq.expansion != nil && nq.expansion.Query == nq.Query {
+		return nq.expansion.ExpandedQuery
+	}
+
+	return nq.Query
+}
+
+// validateVariable checks if the argument can be substituted in the context of the variable.
+func (nq NativeQuery) validateVariable(variable NativeQueryVariable) error {
+	arg := nq.Arguments[variable.Name]
+
+	argType := ScalarName(arg.Type)
+	if argType != "" && !slices.Contains(allowedNativeQueryScalars, argType) {
+		return fmt.Errorf("invalid type `%s` of the argument `%s`", argType, variable.Name)
+	}
+
+	if arg.Array && variable.Context != VariableContextLabelList &&
+		(variable.Context != VariableContextLabelValue || !variable.IsRegex()) {
+		return fmt.Errorf(
+			"the array argument `%s` must be used in regex label matchers or label lists",
+			variable.Name,
+		)
+	}
+
+	if arg.CanBeNull() && variable.Block < 0 {
+		return fmt.Errorf(
+			"the nullable argument `%s` must be used in optional blocks [[ ]]",
+			variable.Name,
+		)
+	}
+
+	return nil
+}
+
+func (nq NativeQuery) validatePlaceholderQuery(
+	template *NativeQueryTemplate,
+	dropOptionalBlocks bool,
+) (parser.Expr, error) {
+	query, mapping, err := nq.renderPlaceholderQuery(template, dropOptionalBlocks)
+	if err != nil {
+		return nil, err
+	}
+
+	expr, err := ParsePromQL(query)
+	if err == nil {
+		return expr, nil
+	}
+
+	var parseErrs parser.ParseErrors
+	if !errors.As(err, &parseErrs) {
+		return nil, err
+	}
+
+	for i, parseErr := range parseErrs {
+		parseErr.Query = template.Query
+		parseErr.PositionRange = posrange.PositionRange{
+			Start: mapping.OriginalPosition(parseErr.PositionRange.Start),
+			End:   mapping.OriginalPosition(parseErr.PositionRange.End),
+		}
+		parseErrs[i] = parseErr
+	}
+
+	return nil, parseErrs
+}
+
+// renderPlaceholderQuery replaces variables with placeholder values of their syntactic contexts.
+func (nq NativeQuery) renderPlaceholderQuery(
+	template *NativeQueryTemplate,
+	dropOptionalBlocks bool,
+) (string, placeholderMapping, error) {
+	replacements, err := template.evalReplacements(
+		func(variable NativeQueryVariable) (string, bool, error) {
+			if dropOptionalBlocks && nq.Arguments[variable.Name].CanBeNull() {
+				return "", false, nil
+			}
+
+			switch variable.Context {
+			case VariableContextString, VariableContextLabelValue, VariableContextLabelList,
+				VariableContextMetricName, VariableContextLabelName:
+				return placeholderLabel, true, nil
+			case VariableContextDuration:
+				return "1m", true, nil
+			default:
+				return "1", true, nil
+			}
+		},
+	)
+	if err != nil {
+		return "", nil, err
+	}
+
+	query, mapping := applyTemplateReplacements(template.Query, replacements)
+
+	return query, mapping, nil
+}
+
+// InferResultType infers the result type from the PromQL expression of the native query.
+func (nq NativeQuery) InferResultType() (NativeQueryResultType, error) {
+	template, err := nq.Template()
+	if err != nil {
+		return "", err
+	}
+
+	expr, err := nq.validatePlaceholderQuery(template, false)
+	if err != nil {
+		return "", err
+	}
+
+	return EvalPromQLResultType(expr), nil
+}
+
+// EvalPromQLResultType evaluates the result type of the parsed PromQL expression.
+func EvalPromQLResultType(expr parser.Expr) NativeQueryResultType {
+	switch expr.Type() {
+	case parser.ValueTypeMatrix:
+		return NativeQueryResultMatrix
+	case parser.ValueTypeScalar:
+		return NativeQueryResultScalar
+	case parser.ValueTypeString:
+		return NativeQueryResultString
+	default:
+		return NativeQueryResultVector
+	}
+}
+
+// InferLabels infers labels of the result from the PromQL expression of the native query.
+// Labels that depend on arguments, e.g. sum by (${labels}), are unknown.
+func (nq NativeQuery) InferLabels() (PromQLLabelSet, error) {
+	template, err := nq.Template()
+	if err != nil {
+		return PromQLLabelSet{}, err
+	}
+
+	query, _, err := nq.renderPlaceholderQuery(template, false)
+	if err != nil {
+		return PromQLLabelSet{}, err
+	}
+
+	expr, err := ParsePromQL(query)
+	if err != nil {
+		return PromQLLabelSet{}, err
+	}
+
+	result := InferPromQLLabels(expr)
+	if slices.Contains(result.Labels, placeholderLabel) {
+		result = result.remove(placeholderLabel)
+		result.Exact = false
+	}
+
+	result.Removed = slices.DeleteFunc(result.Removed, func(name string) bool {
+		return name == placeholderLabel
+	})
+
+	return result, nil
+}
+
+type placeholderPosition struct {
+	Position       posrange.Pos
+	Length         posrange.Pos
+	Original       posrange.Pos
+	OriginalLength posrange.Pos
+}
+
+// placeholderMapping maps positions of the placeholder query to the original query.
+type placeholderMapping []placeholderPosition
+
+// OriginalPosition returns the position of the original query.
+func (pm placeholderMapping) OriginalPosition(pos posrange.Pos) posrange.Pos {
+	var delta posrange.Pos
+
+	for _, p := range pm {
+		if pos < p.Position {
+			break
+		}
+
+		if pos < p.Position+p.Length {
+			return p.Original
+		}
+
+		delta = p.Original + p.OriginalLength - p.Position - p.Length
+	}
+
+	return pos + delta
+}
+
+// PromQLLabelSet represents label names of series that are returned by the PromQL expression.
+type PromQLLabelSet struct {
+	// Labels that are known to be returned.
+	Labels []string
+	// Labels that are known to be removed, e.g. by `without` aggregations.
+	Removed []string
+	// The result has the known labels only, e.g. `by` aggregations.
+	Exact bool
+}
+
+// InferPromQLLabels infers label names of the result from the parsed PromQL expression without running the query.
+func InferPromQLLabels(expr parser.Expr) PromQLLabelSet { //nolint:cyclop
+	switch e := expr.(type) {
+	case *parser.ParenExpr:
+		return InferPromQLLabels(e.Expr)
+	case *parser.SubqueryExpr:
+		return InferPromQLLabels(e.Expr)
+	case *parser.MatrixSelector:
+		return InferPromQLLabels(e.VectorSelector)
+	case *parser.StepInvariantExpr:
+		return InferPromQLLabels(e.Expr)
+	case *parser.NumberLiteral, *parser.StringLiteral:
+		return PromQLLabelSet{Exact: true}
+	case *parser.AggregateExpr:
+		return inferAggregateLabels(e)
+	case *parser.Call:
+		return inferFunctionCallLabels(e)
+	case *parser.BinaryExpr:
+		return inferBinaryExprLabels(e)
+	default:
+		return PromQLLabelSet{}
+	}
+}
+
+func inferAggregateLabels(expr *parser.AggregateExpr) PromQLLabelSet {
+	var result PromQLLabelSet
+
+	switch {
+	// these aggregations return series of the input.
+	case expr.Op == parser.TOPK || expr.Op == parser.BOTTOMK ||
+		expr.Op == parser.LIMITK || expr.Op == parser.LIMIT_RATIO:
+		return InferPromQLLabels(expr.Expr)
+	case expr.Without:
+		result = InferPromQLLabels(expr.Expr).remove(expr.Grouping...)
+	default:
+		result = PromQLLabelSet{Labels: mergePromQLLabels(expr.Grouping, nil), Exact: true}
+	}
+
+	if expr.Op == parser.COUNT_VALUES {
+		if param, ok := unwrapPromQLExpr(expr.Param).(*parser.StringLiteral); ok {
+			result = result.add(param.Val)
+		}
+	}
+
+	return result
+}
+
+func inferFunctionCallLabels(call *parser.Call) PromQLLabelSet {
+	switch call.Func.Name {
+	case string(LabelReplace), string(LabelJoin):
+		result := InferPromQLLabels(call.Args[0])
+
+		if dst, ok := unwrapPromQLExpr(call.Args[1]).(*parser.StringLiteral); ok {
+			result = result.add(dst.Val)
+		}
+
+		return result
+	case string(HistogramQuantile), string(HistogramFraction):
+		return InferPromQLLabels(call.Args[len(call.Args)-1]).remove(HistogramBucketLabel)
+	}
+
+	for _, arg := range call.Args {
+		if arg.Type() == parser.ValueTypeVector || arg.Type() == parser.ValueTypeMatrix {
+			return InferPromQLLabels(arg)
+		}
+	}
+
+	// functions without series arguments, e.g. vector(1) and time().
+	return PromQLLabelSet{Exact: true}
+}
+
+func inferBinaryExprLabels(expr *parser.BinaryExpr) PromQLLabelSet {
+	lhs := InferPromQLLabels(expr.LHS)
+	rhs := InferPromQLLabels(expr.RHS)
+
+	switch {
+	case expr.LHS.Type() != parser.ValueTypeVector:
+		return rhs
+	case expr.RHS.Type() != parser.ValueTypeVector:
+		return lhs
+	case expr.Op == parser.LOR:
+		return PromQLLabelSet{
+			Labels: mergePromQLLabels(lhs.Labels, rhs.Labels),
+			Exact:  lhs.Exact && rhs.Exact,
+		}
+	case expr.VectorMatching != nil && expr.VectorMatching.Card == parser.CardOneToMany:
+		return rhs.add(expr.VectorMatching.Include...)
+	case expr.VectorMatching != nil && expr.VectorMatching.Card == parser.CardManyToOne:
+		return lhs.add(expr.VectorMatching.Include...)
+	default:
+		return lhs
+	}
+}
+
+func (pls PromQLLabelSet) add(names ...string) PromQLLabelSet {
+	return PromQLLabelSet{
+		Labels: mergePromQLLabels(pls.Labels, names),
+		Removed: slices.DeleteFunc(slices.Clone(pls.Removed), func(name string) bool {
+			return slices.Contains(names, name)
+		}),
+		Exact: pls.Exact,
+	}
+}
+
+func (pls PromQLLabelSet) remove(names ...string) PromQLLabelSet {
+	return PromQLLabelSet{
+		Labels: slices.DeleteFunc(slices.Clone(pls.Labels), func(name string) bool {
+			return slices.Contains(names, name)
+		}),
+		Removed: mergePromQLLabels(pls.Removed, names),
+		Exact:   pls.Exact,
+	}
+}
+
+func mergePromQLLabels(a, b []string) []string {
+	result := slices.Concat(a, b)
+	slices.Sort(result)
+
+	return slices.Compact(result)
+}
+
+func unwrapPromQLExpr(expr parser.Expr) parser.Expr {
+	for {
+		paren, ok := expr.(*parser.ParenExpr)
+		if !ok {
+			return expr
+		}
+
+		expr = paren.Expr
+	}
+}
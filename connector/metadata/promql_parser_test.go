@@ -0,0 +1,373 @@
+package metadata
+
+import (
+	"fmt"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestParsePromQL(t *testing.T) {
+	testCases := []struct {
+		Query    string
+		ErrorMsg string
+	}{
+		{Query: `sum by (job) (rate(http_requests_total{job="node"}[5m]))`},
+		{Query: `sum(up`, ErrorMsg: "1:7: parse error: unclosed left parenthesis"},
+		{
+			Query:    `rate(up)`,
+			ErrorMsg: "1:6: parse error: expected type range vector in call to function \"rate\", got instant vector",
+		},
+		{
+			Query:    `sum(up[5m])`,
+			ErrorMsg: "1:5: parse error: expected type instant vector in aggregation expression, got range vector",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Query, func(t *testing.T) {
+			_, err := ParsePromQL(tc.Query)
+			if tc.ErrorMsg != "" {
+				assert.Error(t, err, tc.ErrorMsg)
+
+				return
+			}
+
+			assert.NilError(t, err)
+		})
+	}
+}
+
+func TestParsePromQLExperimentalFunctions(t *testing.T) {
+	expr, err := ParsePromQL(`sort_desc(limitk(2, up))`)
+	assert.NilError(t, err)
+	assert.Equal(t, "limitk", FindPromQLExperimentalFunction(expr))
+
+	expr, err = ParsePromQL(`sort_by_label(up, "job")`)
+	assert.NilError(t, err)
+	assert.Equal(t, "sort_by_label", FindPromQLExperimentalFunction(expr))
+
+	expr, err = ParsePromQL(`sort_desc(topk(2, up))`)
+	assert.NilError(t, err)
+	assert.Equal(t, "", FindPromQLExperimentalFunction(expr))
+
+	nativeQuery := NativeQuery{
+		Query: `limitk(${limit}, up{job="${job}"})`,
+		Arguments: map[string]NativeQueryArgumentInfo{
+			"limit": {Type: string(ScalarInt64)},
+			"job":   {Type: string(ScalarString)},
+		},
+	}
+	assert.NilError(t, nativeQuery.Validate())
+	assert.ErrorContains(
+		t,
+		nativeQuery.ValidateExperimentalFunctions(),
+		"limitk() is experimental and must be enabled with the promql_experimental_functions setting",
+	)
+}
+
+func TestNativeQueryValidate(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Query    NativeQuery
+		ErrorMsg string
+	}{
+		{
+			Name: "valid",
+			Query: NativeQuery{
+				Query: `rate(http_requests_total{job="${job}"}[${range}]) > ${value}`,
+				Arguments: map[string]NativeQueryArgumentInfo{
+					"job":   {Type: string(ScalarString)},
+					"range": {Type: string(ScalarDuration)},
+					"value": {Type: string(ScalarFloat64)},
+				},
+			},
+		},
+		{
+			Name: "range_vector",
+			Query: NativeQuery{
+				Query: `sum(http_requests_total{job="${job}"}[${range}])`,
+				Arguments: map[string]NativeQueryArgumentInfo{
+					"job":   {Type: string(ScalarString)},
+					"range": {Type: string(ScalarDuration)},
+				},
+			},
+			ErrorMsg: "1:5: parse error: expected type instant vector in aggregation expression, got range vector",
+		},
+		{
+			Name: "position_after_variables",
+			Query: NativeQuery{
+				Query: `up{job="${job}"} + on(instance) ${value} +`,
+				Arguments: map[string]NativeQueryArgumentInfo{
+					"value": {Type: string(ScalarInt64)},
+				},
+			},
+			ErrorMsg: "1:43: parse error: unexpected end of input",
+		},
+		{
+			Name: "invalid_type",
+			Query: NativeQuery{
+				Query: `up > ${value}`,
+				Arguments: map[string]NativeQueryArgumentInfo{
+					"value": {Type: "Boolean"},
+				},
+			},
+			ErrorMsg: "argument `value`: unsupported native query argument type Boolean",
+		},
+		{
+			Name: "optional_blocks",
+			Query: NativeQuery{
+				Query: `sum by (${labels}) (up{job=~"${job}"[[, namespace="${namespace}"]]})`,
+				Arguments: map[string]NativeQueryArgumentInfo{
+					"labels":    {Type: string(ScalarString), Array: true},
+					"job":       {Type: string(ScalarString), Array: true},
+					"namespace": {Type: string(ScalarString), Nullable: true},
+				},
+			},
+		},
+		{
+			Name: "array_equal_matcher",
+			Query: NativeQuery{
+				Query: `up{job="${job}"}`,
+				Arguments: map[string]NativeQueryArgumentInfo{
+					"job": {Type: string(ScalarString), Array: true},
+				},
+			},
+			ErrorMsg: "the array argument `job` must be used in regex label matchers or label lists",
+		},
+		{
+			Name: "nullable_required",
+			Query: NativeQuery{
+				Query: `up{job="${job}"}`,
+				Arguments: map[string]NativeQueryArgumentInfo{
+					"job": {Type: string(ScalarString), Nullable: true},
+				},
+			},
+			ErrorMsg: "the nullable argument `job` must be used in optional blocks [[ ]]",
+		},
+		{
+			Name: "dropped_block",
+			Query: NativeQuery{
+				Query: `up[[ > ${value}]] + 1`,
+				Arguments: map[string]NativeQueryArgumentInfo{
+					"value": {Type: string(ScalarFloat64), Nullable: true},
+				},
+			},
+		},
+		{
+			Name: "invalid_dropped_block",
+			Query: NativeQuery{
+				Query: `up >[[ ${value}]]`,
+				Arguments: map[string]NativeQueryArgumentInfo{
+					"value": {Type: string(ScalarFloat64), Nullable: true},
+				},
+			},
+			ErrorMsg: "optional blocks are dropped: 1:18: parse error: unexpected end of input",
+		},
+		{
+			Name: "invalid_enum_default",
+			Query: NativeQuery{
+				Query: `up{job="${job}"}`,
+				Arguments: map[string]NativeQueryArgumentInfo{
+					"job": {Enum: []string{"node"}, Default: "prometheus"},
+				},
+			},
+			ErrorMsg: "argument `job`: the default value prometheus is not in the enum [node]",
+		},
+		{
+			Name: "scalar_result",
+			Query: NativeQuery{
+				Query:      `scalar(sum(up{job="${job}"}))`,
+				ResultType: NativeQueryResultScalar,
+			},
+		},
+		{
+			Name: "result_type_mismatch",
+			Query: NativeQuery{
+				Query:      `sum(up)`,
+				ResultType: NativeQueryResultScalar,
+			},
+			ErrorMsg: "the query returns a vector result, but the result type is scalar",
+		},
+		{
+			Name: "invalid_result_type",
+			Query: NativeQuery{
+				Query:      `up`,
+				ResultType: "histogram",
+			},
+			ErrorMsg: "invalid result type `histogram`",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := tc.Query.Validate()
+			if tc.ErrorMsg != "" {
+				assert.Error(t, err, tc.ErrorMsg)
+
+				return
+			}
+
+			assert.NilError(t, err)
+		})
+	}
+}
+
+func TestNativeQueryInferLabels(t *testing.T) {
+	testCases := []struct {
+		Query     string
+		Arguments map[string]NativeQueryArgumentInfo
+		Expected  PromQLLabelSet
+	}{
+		{
+			Query:    `up{job="${job}"}`,
+			Expected: PromQLLabelSet{},
+		},
+		{
+			Query:    `sum by (job, instance) (rate(http_requests_total[${range}]))`,
+			Expected: PromQLLabelSet{Labels: []string{"instance", "job"}, Exact: true},
+		},
+		{
+			Query:    `sum without (instance) (up) > ${value}`,
+			Expected: PromQLLabelSet{Removed: []string{"instance"}},
+		},
+		{
+			Query:    `topk(5, max by (job) (up))`,
+			Expected: PromQLLabelSet{Labels: []string{"job"}, Exact: true},
+		},
+		{
+			Query:    `count_values by (job) ("version", build_info)`,
+			Expected: PromQLLabelSet{Labels: []string{"job", "version"}, Exact: true},
+		},
+		{
+			Query:    `label_replace(sum by (job) (up), "service", "$1", "job", "(.*)")`,
+			Expected: PromQLLabelSet{Labels: []string{"job", "service"}, Exact: true},
+		},
+		{
+			Query: `histogram_quantile(0.9, sum by (le, job) (rate(http_request_duration_seconds_bucket[5m])))`,
+			Expected: PromQLLabelSet{
+				Labels:  []string{"job"},
+				Removed: []string{"le"},
+				Exact:   true,
+			},
+		},
+		{
+			Query: `sum by (job) (up) * on (job) group_left (team) max by (job, team) (team_info)`,
+			Expected: PromQLLabelSet{
+				Labels: []string{"job", "team"},
+				Exact:  true,
+			},
+		},
+		{
+			Query:    `sum by (job) (up) or vector(0)`,
+			Expected: PromQLLabelSet{Labels: []string{"job"}, Exact: true},
+		},
+		{
+			Query: `sum by (${labels}) (up)`,
+			Arguments: map[string]NativeQueryArgumentInfo{
+				"labels": {Array: true},
+			},
+			Expected: PromQLLabelSet{},
+		},
+		{
+			Query:    `label_replace(up, "${dst}", "$1", "job", "(.*)")`,
+			Expected: PromQLLabelSet{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Query, func(t *testing.T) {
+			nq := NativeQuery{
+				Query:     tc.Query,
+				Arguments: tc.Arguments,
+			}
+			assert.NilError(t, nq.Validate())
+
+			result, err := nq.InferLabels()
+			assert.NilError(t, err)
+			assert.Equal(t, tc.Expected.Exact, result.Exact)
+			assert.Equal(t, fmt.Sprint(tc.Expected.Labels), fmt.Sprint(result.Labels))
+			assert.Equal(t, fmt.Sprint(tc.Expected.Removed), fmt.Sprint(result.Removed))
+		})
+	}
+}
+
+func TestNativeQueryInferResultType(t *testing.T) {
+	testCases := map[string]NativeQueryResultType{
+		`sum by (job) (up{job="${job}"})`: NativeQueryResultVector,
+		`up{job="${job}"}[${range}]`:      NativeQueryResultMatrix,
+		`scalar(sum(up{job="${job}"}))`:   NativeQueryResultScalar,
+		`count(up) / ${value}`:            NativeQueryResultVector,
+		`${value} * 2`:                    NativeQueryResultScalar,
+		`"${job}"`:                        NativeQueryResultString,
+	}
+
+	for query, expected := range testCases {
+		t.Run(query, func(t *testing.T) {
+			resultType, err := NativeQuery{Query: query}.InferResultType()
+			assert.NilError(t, err)
+			assert.Equal(t, expected, resultType)
+		})
+	}
+}
+
+func TestNativeQueryIdentifierVariables(t *testing.T) {
+	testCases := []struct {
+		Query      string
+		Arguments  map[string]NativeQueryArgumentInfo
+		Contexts   []NativeQueryVariableContext
+		ResultType NativeQueryResultType
+	}{
+		{
+			Query:      `sum(${metric})`,
+			Arguments:  map[string]NativeQueryArgumentInfo{"metric": {}},
+			Contexts:   []NativeQueryVariableContext{VariableContextMetricName},
+			ResultType: NativeQueryResultVector,
+		},
+		{
+			Query:      `sum(${metric}) / sum(${other}) > ${value}`,
+			Arguments:  map[string]NativeQueryArgumentInfo{"metric": {}, "other": {}, "value": {}},
+			Contexts:   []NativeQueryVariableContext{VariableContextMetricName, VariableContextMetricName, VariableContextNumber},
+			ResultType: NativeQueryResultVector,
+		},
+		{
+			Query:      `${metric}`,
+			Arguments:  map[string]NativeQueryArgumentInfo{"metric": {Type: string(ScalarString)}},
+			Contexts:   []NativeQueryVariableContext{VariableContextMetricName},
+			ResultType: NativeQueryResultVector,
+		},
+		{
+			Query:      `${value}`,
+			Arguments:  map[string]NativeQueryArgumentInfo{"value": {}},
+			Contexts:   []NativeQueryVariableContext{VariableContextNumber},
+			ResultType: NativeQueryResultScalar,
+		},
+		{
+			Query:      `up > ${value}`,
+			Arguments:  map[string]NativeQueryArgumentInfo{"value": {Type: string(ScalarFloat64)}},
+			Contexts:   []NativeQueryVariableContext{VariableContextNumber},
+			ResultType: NativeQueryResultVector,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Query, func(t *testing.T) {
+			nq := NativeQuery{Query: tc.Query, Arguments: tc.Arguments}
+			assert.NilError(t, nq.Validate())
+
+			template, err := nq.Template()
+			assert.NilError(t, err)
+
+			contexts := make([]NativeQueryVariableContext, len(template.Variables))
+			for i, variable := range template.Variables {
+				contexts[i] = variable.Context
+			}
+
+			assert.DeepEqual(t, tc.Contexts, contexts)
+
+			resultType, err := nq.InferResultType()
+			assert.NilError(t, err)
+			assert.Equal(t, tc.ResultType, resultType)
+		})
+	}
+}
@@ -88,9 +88,46 @@ func createQueryResultValuesObjectFields() schema.ObjectTypeFields {
 			Type: schema.NewArrayType(schema.NewNamedType(objectName_QueryResultValue)).
 				Encode(),
 		},
+		SummaryKey: schema.ObjectField{
+			Description: utils.ToPtr(
+				"The statistical summary of values in the series. Null if the result is flat",
+			),
+			Type: schema.NewNullableNamedType(objectName_QueryResultSummary).Encode(),
+		},
 	}
 }
 
+func createQueryResultSummaryObjectFields() schema.ObjectTypeFields {
+	descriptions := map[string]string{
+		SummaryFieldMin:    "The minimum value",
+		SummaryFieldMax:    "The maximum value",
+		SummaryFieldAvg:    "The average value",
+		SummaryFieldSum:    "The sum of values",
+		SummaryFieldFirst:  "The first value",
+		SummaryFieldLast:   "The last value",
+		SummaryFieldStddev: "The population standard deviation of values",
+		SummaryFieldP50:    "The 50th percentile (median) of values",
+		SummaryFieldP90:    "The 90th percentile of values",
+		SummaryFieldP99:    "The 99th percentile of values",
+	}
+
+	fields := schema.ObjectTypeFields{
+		SummaryFieldCount: schema.ObjectField{
+			Description: utils.ToPtr("The number of values"),
+			Type:        schema.NewNamedType(string(ScalarInt64)).Encode(),
+		},
+	}
+
+	for key, description := range descriptions {
+		fields[key] = schema.ObjectField{
+			Description: utils.ToPtr(description),
+			Type:        schema.NewNullableNamedType(string(ScalarDecimal)).Encode(),
+		}
+	}
+
+	return fields
+}
+
 func createCollectionArguments(promptql bool) schema.CollectionInfoArguments {
 	arguments := schema.CollectionInfoArguments{}
 	// PromptQL does not work well with arguments.
@@ -99,7 +136,14 @@ func createCollectionArguments(promptql bool) schema.CollectionInfoArguments {
 		return arguments
 	}
 
-	keys := []string{ArgumentKeyStep, ArgumentKeyTimeout, ArgumentKeyOffset, ArgumentKeyFlat}
+	keys := []string{
+		ArgumentKeyStep,
+		ArgumentKeyTimeout,
+		ArgumentKeyOffset,
+		ArgumentKeyFlat,
+		ArgumentKeyMaxPoints,
+		ArgumentKeyTimezone,
+	}
 
 	for _, key := range keys {
 		arguments[key] = defaultArgumentInfos[key]
@@ -48,6 +48,16 @@ func BuildConnectorSchema(config *Configuration) (*schema.SchemaResponse, error)
 			Encode()
 	} else {
 		maps.Copy(builder.ObjectTypes, defaultFunctionObjectTypes)
+
+		granularityEnums := make([]string, len(TimestampBucketGranularities))
+		for i, granularity := range TimestampBucketGranularities {
+			granularityEnums[i] = string(granularity)
+		}
+
+		granularityScalar := schema.NewScalarType()
+		granularityScalar.Representation = schema.NewTypeRepresentationEnum(granularityEnums).
+			Encode()
+		builder.ScalarTypes[string(ScalarTimestampBucketGranularity)] = *granularityScalar
 	}
 
 	if err := builder.buildMetrics(); err != nil {
@@ -89,6 +99,14 @@ func (scb *connectorSchemaBuilder) buildMetrics() error {
 			if err := scb.buildHistogramMetrics(name, info); err != nil {
 				return err
 			}
+		case model.MetricTypeSummary:
+			if err := scb.buildSummaryMetrics(name, info); err != nil {
+				return err
+			}
+		case model.MetricTypeInfo:
+			if err := scb.buildInfoMetrics(name, info); err != nil {
+				return err
+			}
 		case model.MetricTypeCounter:
 			if err := scb.buildCounterMetrics(name, info, info.Labels); err != nil {
 				return err
@@ -143,7 +161,7 @@ func (scb *connectorSchemaBuilder) buildHistogramMetrics(
 		labels := info.Labels
 
 		if suffix == "bucket" {
-			labels["le"] = LabelInfo{}
+			labels = HistogramBucketLabels(info.Labels)
 		}
 
 		coll, err := scb.buildMetricsItem(metricName, info, labels)
@@ -176,6 +194,74 @@ func (scb *connectorSchemaBuilder) buildHistogramMetrics(
 	return nil
 }
 
+// buildSummaryMetrics builds collections of quantiles, sum and count series of the summary metric.
+func (scb *connectorSchemaBuilder) buildSummaryMetrics(
+	name string,
+	info MetricInfo,
+) error {
+	if _, err := scb.buildMetricsItem(name, info, SummaryQuantileLabels(info.Labels)); err != nil {
+		return err
+	}
+
+	for _, suffix := range []string{"sum", "count"} {
+		if _, err := scb.buildMetricsItem(name+"_"+suffix, info, info.Labels); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// buildInfoMetrics builds a label-only collection of the info metric.
+// Values of info series are always 1, so only labels are exposed.
+func (scb *connectorSchemaBuilder) buildInfoMetrics(
+	name string,
+	info MetricInfo,
+) error {
+	if err := scb.checkDuplicatedOperation(name); err != nil {
+		return err
+	}
+
+	objectName := xstrings.ToPascalCase(strings.ReplaceAll(name, ":", " "))
+	objectType := schema.ObjectType{
+		Fields:      schema.ObjectTypeFields{},
+		ForeignKeys: schema.ObjectTypeForeignKeys{},
+	}
+
+	for key, label := range info.Labels {
+		fieldType, err := scb.buildLabelFieldType(objectName, key, label)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		objectType.Fields[key] = schema.ObjectField{
+			Description: label.Description,
+			Type:        fieldType,
+		}
+	}
+
+	arguments := schema.CollectionInfoArguments{}
+
+	if !scb.Configuration.Runtime.PromptQL {
+		objectType.Fields[LabelsKey] = createQueryResultValuesObjectFields()[LabelsKey]
+
+		for _, key := range []string{ArgumentKeyTimeout, ArgumentKeyOffset} {
+			arguments[key] = defaultArgumentInfos[key]
+		}
+	}
+
+	scb.ObjectTypes[objectName] = objectType
+	scb.Collections[name] = schema.CollectionInfo{
+		Name:                  name,
+		Type:                  objectName,
+		Arguments:             arguments,
+		Description:           info.Description,
+		UniquenessConstraints: schema.CollectionInfoUniquenessConstraints{},
+	}
+
+	return nil
+}
+
 func (scb *connectorSchemaBuilder) buildMetricsItem(
 	name string,
 	info MetricInfo,
@@ -188,58 +274,31 @@ func (scb *connectorSchemaBuilder) buildMetricsItem(
 	objectType := createMetricObjectType(scb.Configuration.Runtime.PromptQL)
 	labelEnums := make([]string, 0, len(labels))
 
+	objectName := xstrings.ToPascalCase(strings.ReplaceAll(name, ":", " "))
+
 	for key, label := range labels {
+		fieldType, err := scb.buildLabelFieldType(objectName, key, label)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
 		labelEnums = append(labelEnums, key)
 		objectType.Fields[key] = schema.ObjectField{
 			Description: label.Description,
-			Type:        schema.NewNamedType(string(ScalarString)).Encode(),
+			Type:        fieldType,
 		}
 	}
 
-	objectName := xstrings.ToPascalCase(strings.ReplaceAll(name, ":", " "))
 	scb.ObjectTypes[objectName] = objectType
 	arguments := createCollectionArguments(scb.Configuration.Runtime.PromptQL)
 
 	if !scb.Configuration.Runtime.PromptQL {
-		slices.Sort(labelEnums)
-
-		labelEnumScalarName := objectName + "Label"
-		scalarType := schema.NewScalarType()
-		scalarType.Representation = schema.NewTypeRepresentationEnum(labelEnums).Encode()
-		scb.ScalarTypes[labelEnumScalarName] = *scalarType
-		scb.ObjectTypes[buildLabelJoinObjectTypeName(objectName)] = createLabelJoinObjectType(
-			labelEnumScalarName,
-		)
-		scb.ObjectTypes[buildLabelReplaceObjectTypeName(objectName)] = createLabelReplaceObjectType(
-			labelEnumScalarName,
-		)
-
-		// build promQL functions argument
-		promQLFnsObjectName := objectName + "Functions"
-		promQLFnsObject := schema.NewObjectType(
-			createPromQLFunctionObjectFields(objectName, labelEnumScalarName),
-			schema.ObjectTypeForeignKeys{},
-			nil,
+		arguments[ArgumentKeyTimestampBucket] = defaultArgumentInfos[ArgumentKeyTimestampBucket]
+		arguments[ArgumentKeyFunctions] = scb.buildPromQLFunctionsArgument(
+			name,
+			objectName,
+			labelEnums,
 		)
-
-		for _, fnName := range []PromQLFunctionName{Sum, Min, Max, Avg, Stddev, Stdvar, Count, Group} {
-			promQLFnsObject.Fields[string(fnName)] = schema.ObjectField{
-				Type: schema.NewNullableType(schema.NewArrayType(schema.NewNamedType(labelEnumScalarName))).
-					Encode(),
-			}
-		}
-
-		promQLFnsObject.Fields[string(CountValues)] = schema.ObjectField{
-			Type: schema.NewNullableType(schema.NewNamedType(labelEnumScalarName)).Encode(),
-		}
-
-		scb.ObjectTypes[promQLFnsObjectName] = promQLFnsObject
-
-		arguments[ArgumentKeyFunctions] = schema.ArgumentInfo{
-			Description: utils.ToPtr("PromQL aggregation operators and functions for " + name),
-			Type: schema.NewNullableType(schema.NewArrayType(schema.NewNamedType(promQLFnsObjectName))).
-				Encode(),
-		}
 	}
 
 	collection := schema.CollectionInfo{
@@ -254,6 +313,109 @@ func (scb *connectorSchemaBuilder) buildMetricsItem(
 	return &collection, nil
 }
 
+// buildPromQLFunctionsArgument creates object types of PromQL functions of the collection
+// and returns the fn argument.
+func (scb *connectorSchemaBuilder) buildPromQLFunctionsArgument(
+	name string,
+	objectName string,
+	labelEnums []string,
+) schema.ArgumentInfo {
+	slices.Sort(labelEnums)
+
+	labelEnumScalarName := objectName + "Label"
+	scalarType := schema.NewScalarType()
+	scalarType.Representation = schema.NewTypeRepresentationEnum(labelEnums).Encode()
+	scb.ScalarTypes[labelEnumScalarName] = *scalarType
+	scb.ObjectTypes[buildLabelJoinObjectTypeName(objectName)] = createLabelJoinObjectType(
+		labelEnumScalarName,
+	)
+	scb.ObjectTypes[buildLabelReplaceObjectTypeName(objectName)] = createLabelReplaceObjectType(
+		labelEnumScalarName,
+	)
+
+	promQLFnsObjectName := objectName + "Functions"
+	promQLFnsObject := schema.NewObjectType(
+		createPromQLFunctionObjectFields(objectName, labelEnumScalarName),
+		schema.ObjectTypeForeignKeys{},
+		nil,
+	)
+
+	for _, fnName := range []PromQLFunctionName{Sum, Min, Max, Avg, Stddev, Stdvar, Count, Group} {
+		promQLFnsObject.Fields[string(fnName)] = schema.ObjectField{
+			Type: schema.NewNullableType(schema.NewArrayType(schema.NewNamedType(labelEnumScalarName))).
+				Encode(),
+		}
+	}
+
+	promQLFnsObject.Fields[string(CountValues)] = schema.ObjectField{
+		Type: schema.NewNullableType(schema.NewNamedType(labelEnumScalarName)).Encode(),
+	}
+
+	scb.ObjectTypes[promQLFnsObjectName] = promQLFnsObject
+
+	return schema.ArgumentInfo{
+		Description: utils.ToPtr("PromQL aggregation operators and functions for " + name),
+		Type: schema.NewNullableType(schema.NewArrayType(schema.NewNamedType(promQLFnsObjectName))).
+			Encode(),
+	}
+}
+
+// buildLabelFieldType validates the label and returns the type of the label column.
+// Typed label columns are nullable because the value may be missing or unparsable.
+func (scb *connectorSchemaBuilder) buildLabelFieldType(
+	objectName string,
+	labelName string,
+	label LabelInfo,
+) (schema.Type, error) {
+	if err := label.Validate(); err != nil {
+		return nil, fmt.Errorf("label %s: %w", labelName, err)
+	}
+
+	var scalarName string
+
+	switch label.GetType() {
+	case LabelTypeInt64:
+		scalarName = string(ScalarInt64)
+	case LabelTypeFloat64:
+		scalarName = string(ScalarFloat64)
+	case LabelTypeBoolean:
+		scalarName = string(ScalarBoolean)
+	case LabelTypeEnum:
+		scalarName = objectName + xstrings.ToPascalCase(labelName) + "Enum"
+		scb.ScalarTypes[scalarName] = createEnumLabelScalarType(scalarName, label.Values)
+	default:
+		return schema.NewNamedType(string(ScalarString)).Encode(), nil
+	}
+
+	return schema.NewNullableNamedType(scalarName).Encode(), nil
+}
+
+// HistogramBucketLabels returns labels of histogram bucket series,
+// including the le label whose values are float64 upper bounds.
+func HistogramBucketLabels(labels map[string]LabelInfo) map[string]LabelInfo {
+	return withFloat64Label(labels, HistogramBucketLabel)
+}
+
+// SummaryQuantileLabels returns labels of summary quantile series,
+// including the quantile label whose values are float64 numbers.
+func SummaryQuantileLabels(labels map[string]LabelInfo) map[string]LabelInfo {
+	return withFloat64Label(labels, SummaryQuantileLabel)
+}
+
+func withFloat64Label(labels map[string]LabelInfo, name string) map[string]LabelInfo {
+	results := make(map[string]LabelInfo, len(labels)+1)
+	maps.Copy(results, labels)
+
+	label := results[name]
+	if label.Type == "" {
+		label.Type = LabelTypeFloat64
+	}
+
+	results[name] = label
+
+	return results
+}
+
 func (scb *connectorSchemaBuilder) checkDuplicatedOperation(name string) error {
 	err := fmt.Errorf("duplicated operation name: %s", name)
 
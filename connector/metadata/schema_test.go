@@ -0,0 +1,257 @@
+package metadata
+
+import (
+	"testing"
+
+	"github.com/hasura/ndc-sdk-go/schema"
+	"github.com/hasura/ndc-sdk-go/utils"
+	"github.com/prometheus/common/model"
+	"gotest.tools/v3/assert"
+)
+
+func TestBuildConnectorSchemaTypedLabels(t *testing.T) {
+	config := &Configuration{
+		Metadata: Metadata{
+			Metrics: map[string]MetricInfo{
+				"http_request_duration_seconds": {
+					Type: model.MetricTypeHistogram,
+					Labels: map[string]LabelInfo{
+						"code":    {Type: LabelTypeInt64},
+						"replica": {Type: LabelTypeBoolean},
+						"method":  {Type: LabelTypeEnum, Values: []string{"GET", "POST"}},
+						"job":     {},
+					},
+				},
+			},
+		},
+	}
+
+	result, err := BuildConnectorSchema(config)
+	assert.NilError(t, err)
+
+	objectType := result.ObjectTypes["HttpRequestDurationSecondsBucket"]
+	expectedFields := map[string]schema.Type{
+		"code":    schema.NewNullableNamedType(string(ScalarInt64)).Encode(),
+		"replica": schema.NewNullableNamedType(string(ScalarBoolean)).Encode(),
+		"method": schema.NewNullableNamedType("HttpRequestDurationSecondsBucketMethodEnum").
+			Encode(),
+		"job": schema.NewNamedType(string(ScalarString)).Encode(),
+		"le":  schema.NewNullableNamedType(string(ScalarFloat64)).Encode(),
+	}
+
+	for key, expected := range expectedFields {
+		assert.DeepEqual(t, expected, objectType.Fields[key].Type)
+	}
+
+	_, ok := result.ObjectTypes["HttpRequestDurationSecondsSum"].Fields[HistogramBucketLabel]
+	assert.Assert(t, !ok)
+
+	enumScalar := result.ScalarTypes["HttpRequestDurationSecondsBucketMethodEnum"]
+	assert.DeepEqual(
+		t,
+		schema.NewTypeRepresentationEnum([]string{"GET", "POST"}).Encode(),
+		enumScalar.Representation,
+	)
+}
+
+func TestBuildConnectorSchemaInvalidLabelType(t *testing.T) {
+	config := &Configuration{
+		Metadata: Metadata{
+			Metrics: map[string]MetricInfo{
+				"http_requests_total": {
+					Type: model.MetricTypeCounter,
+					Labels: map[string]LabelInfo{
+						"method": {Type: LabelTypeEnum},
+					},
+				},
+			},
+		},
+	}
+
+	_, err := BuildConnectorSchema(config)
+	assert.ErrorContains(
+		t,
+		err,
+		"http_requests_total: label method: values of the enum label must not be empty",
+	)
+}
+
+func TestBuildConnectorSchemaMetricTypes(t *testing.T) {
+	config := &Configuration{
+		Metadata: Metadata{
+			Metrics: map[string]MetricInfo{
+				"rpc_duration_seconds": {
+					Type:   model.MetricTypeSummary,
+					Labels: map[string]LabelInfo{"service": {}},
+				},
+				"build_info": {
+					Type:   model.MetricTypeInfo,
+					Labels: map[string]LabelInfo{"version": {}},
+				},
+				"node_systemd_unit_state": {
+					Type: model.MetricTypeStateset,
+					Labels: map[string]LabelInfo{
+						"node_systemd_unit_state": {
+							Type:   LabelTypeEnum,
+							Values: []string{"active", "failed"},
+						},
+					},
+				},
+				"job:up:sum": {
+					Type:   model.MetricTypeUnknown,
+					Labels: map[string]LabelInfo{"job": {}},
+				},
+			},
+		},
+	}
+
+	result, err := BuildConnectorSchema(config)
+	assert.NilError(t, err)
+
+	collections := map[string]schema.CollectionInfo{}
+	for _, collection := range result.Collections {
+		collections[collection.Name] = collection
+	}
+
+	for _, name := range []string{
+		"rpc_duration_seconds",
+		"rpc_duration_seconds_sum",
+		"rpc_duration_seconds_count",
+		"build_info",
+		"node_systemd_unit_state",
+		"job:up:sum",
+	} {
+		_, ok := collections[name]
+		assert.Assert(t, ok, name)
+	}
+
+	assert.DeepEqual(
+		t,
+		schema.NewNullableNamedType(string(ScalarFloat64)).Encode(),
+		result.ObjectTypes["RpcDurationSeconds"].Fields[SummaryQuantileLabel].Type,
+	)
+
+	_, ok := result.ObjectTypes["RpcDurationSecondsSum"].Fields[SummaryQuantileLabel]
+	assert.Assert(t, !ok)
+
+	infoObject := result.ObjectTypes["BuildInfo"]
+	assert.DeepEqual(t, []string{LabelsKey, "version"}, utils.GetSortedKeys(infoObject.Fields))
+	assert.DeepEqual(
+		t,
+		[]string{ArgumentKeyOffset, ArgumentKeyTimeout},
+		utils.GetSortedKeys(collections["build_info"].Arguments),
+	)
+
+	assert.DeepEqual(
+		t,
+		schema.NewNullableNamedType("NodeSystemdUnitStateNodeSystemdUnitStateEnum").Encode(),
+		result.ObjectTypes["NodeSystemdUnitState"].Fields["node_systemd_unit_state"].Type,
+	)
+}
+
+func TestBuildConnectorSchemaNativeQueryArguments(t *testing.T) {
+	config := &Configuration{
+		Metadata: Metadata{
+			NativeOperations: NativeOperations{
+				Queries: map[string]NativeQuery{
+					"service_up": {
+						Query: `up{job=~"${jobs}", env="${env}"[[, namespace="${namespace}"]]}`,
+						Arguments: map[string]NativeQueryArgumentInfo{
+							"jobs":      {Type: string(ScalarString), Array: true},
+							"env":       {Enum: []string{"dev", "prod"}, Default: "prod"},
+							"namespace": {Type: string(ScalarString), Nullable: true},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	result, err := BuildConnectorSchema(config)
+	assert.NilError(t, err)
+
+	var arguments schema.CollectionInfoArguments
+
+	for _, collection := range result.Collections {
+		if collection.Name == "service_up" {
+			arguments = collection.Arguments
+		}
+	}
+
+	assert.DeepEqual(
+		t,
+		schema.NewArrayType(schema.NewNamedType(string(ScalarString))).Encode(),
+		arguments["jobs"].Type,
+	)
+	assert.DeepEqual(t, schema.NewNullableNamedType("ServiceUpEnvArgumentEnum").Encode(), arguments["env"].Type)
+	assert.DeepEqual(
+		t,
+		schema.NewNullableNamedType(string(ScalarString)).Encode(),
+		arguments["namespace"].Type,
+	)
+	assert.DeepEqual(
+		t,
+		schema.NewTypeRepresentationEnum([]string{"dev", "prod"}).Encode(),
+		result.ScalarTypes["ServiceUpEnvArgumentEnum"].Representation,
+	)
+	assert.DeepEqual(
+		t,
+		schema.NewNullableType(schema.NewArrayType(schema.NewNamedType("ServiceUpFunctions"))).
+			Encode(),
+		arguments[ArgumentKeyFunctions].Type,
+	)
+	assert.Assert(t, result.ObjectTypes["ServiceUpFunctions"].Fields["sum"].Type != nil)
+}
+
+func TestBuildConnectorSchemaNativeQueryResultTypes(t *testing.T) {
+	config := &Configuration{
+		Metadata: Metadata{
+			NativeOperations: NativeOperations{
+				Queries: map[string]NativeQuery{
+					"up_count": {
+						Query:      `scalar(count(up))`,
+						ResultType: NativeQueryResultScalar,
+						Labels:     map[string]LabelInfo{"job": {}},
+					},
+					"build_version": {
+						Query:      `"v1.0.0"`,
+						ResultType: NativeQueryResultString,
+					},
+				},
+			},
+		},
+	}
+
+	result, err := BuildConnectorSchema(config)
+	assert.NilError(t, err)
+
+	for _, collection := range result.Collections {
+		if collection.Name != "up_count" && collection.Name != "build_version" {
+			continue
+		}
+
+		_, ok := collection.Arguments[ArgumentKeyFunctions]
+		assert.Assert(t, !ok)
+	}
+
+	upCount := result.ObjectTypes["UpCount"]
+	assert.Equal(t, 2, len(upCount.Fields))
+	assert.DeepEqual(
+		t,
+		schema.NewNamedType(string(ScalarTimestamp)).Encode(),
+		upCount.Fields[TimestampKey].Type,
+	)
+	assert.DeepEqual(
+		t,
+		schema.NewNamedType(string(ScalarDecimal)).Encode(),
+		upCount.Fields[ValueKey].Type,
+	)
+
+	buildVersion := result.ObjectTypes["BuildVersion"]
+	assert.Equal(t, 2, len(buildVersion.Fields))
+	assert.DeepEqual(
+		t,
+		schema.NewNamedType(string(ScalarString)).Encode(),
+		buildVersion.Fields[ValueKey].Type,
+	)
+}
@@ -93,14 +93,23 @@ func evalStepFromRange(start time.Time, end time.Time) time.Duration {
 	}
 }
 
+// RangeResolutionAuto is the range value that is resolved from the step and scrape interval.
+const RangeResolutionAuto = "auto"
+
 // RangeResolution represents the given range and resolution with format xx:xx.
 type RangeResolution struct {
 	Range      model.Duration
 	Resolution model.Duration
+	// The range is resolved from the step and scrape interval of the request.
+	Auto bool
 }
 
 // String implements the fmt.Stringer interface.
 func (rr RangeResolution) String() string {
+	if rr.Auto && rr.Range == 0 {
+		return RangeResolutionAuto
+	}
+
 	if rr.Resolution == 0 {
 		return rr.Range.String()
 	}
@@ -143,6 +152,10 @@ func ParseRangeResolution(input any, unixTimeUnit UnixTimeUnit) (*RangeResolutio
 		return &RangeResolution{Range: model.Duration(rng)}, nil
 	}
 
+	if reflectValue.String() == RangeResolutionAuto {
+		return &RangeResolution{Auto: true}, nil
+	}
+
 	parts := strings.Split(reflectValue.String(), ":")
 	if parts[0] == "" {
 		return nil, fmt.Errorf("invalid range resolution %v", input)
@@ -9,6 +9,7 @@ import (
 	"github.com/hasura/ndc-prometheus/connector/metadata"
 	"github.com/hasura/ndc-sdk-go/schema"
 	"github.com/hasura/ndc-sdk-go/utils"
+	"github.com/prometheus/common/model"
 )
 
 // QueryExplain explains a query by creating an execution plan.
@@ -62,9 +63,36 @@ func (c *PrometheusConnector) QueryExplain(
 			Request:     request,
 			NativeQuery: &nativeQuery,
 			Arguments:   arguments,
+			Variables:   requestVars[0],
 			Runtime:     c.runtime,
 		}
 
+		if nativeQuery.IsJoinQuery() {
+			_, queries, err := executor.ExplainJoin()
+			if err != nil {
+				return nil, err
+			}
+
+			details := schema.ExplainResponseDetails{}
+
+			for name, queryString := range queries {
+				details[name] = queryString
+			}
+
+			return &schema.ExplainResponse{
+				Details: details,
+			}, nil
+		}
+
+		if executor.IsCollectionQuery() {
+			_, explainResult, err := executor.ExplainCollection()
+			if err != nil {
+				return nil, err
+			}
+
+			return explainResult.ToExplainResponse()
+		}
+
 		_, queryString, err := executor.Explain(ctx)
 		if err != nil {
 			return nil, err
@@ -110,6 +138,13 @@ func (c *PrometheusConnector) explainQueryCollection(
 
 	for _, metricName := range metricNames {
 		if collection, ok := c.metadata.Metrics[metricName]; ok {
+			switch {
+			case metricName != request.Collection && histogramMatches[2] == "bucket":
+				collection.Labels = metadata.HistogramBucketLabels(collection.Labels)
+			case metricName == request.Collection && collection.Type == model.MetricTypeSummary:
+				collection.Labels = metadata.SummaryQuantileLabels(collection.Labels)
+			}
+
 			executor.MetricName = request.Collection
 			executor.Metric = collection
 